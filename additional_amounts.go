@@ -0,0 +1,118 @@
+package iso8583
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AdditionalAmount is one entry of DE 54 (additional amounts). On the wire
+// each entry is a 20-character block: account type (n2), amount type (n2),
+// currency code (n3), C/D sign (a1) and amount in minor units (n12).
+type AdditionalAmount struct {
+	AccountType string // e.g. "00" unspecified, "10" savings, "20" checking, "30" credit
+	AmountType  string // e.g. AmountTypeAvailableBalance
+	Amount      Amount // Signed amount in minor units of Amount.Currency
+}
+
+// Common DE 54 amount types.
+const (
+	AmountTypeLedgerBalance    = "01"
+	AmountTypeAvailableBalance = "02"
+	AmountTypeAmountOwing      = "03"
+	AmountTypeAmountDue        = "04"
+	AmountTypeRemainingCycle   = "20"
+	AmountTypeCashback         = "40"
+	AmountTypeGoodsAndServices = "41"
+)
+
+const (
+	additionalAmountField     = 54
+	additionalAmountBlockSize = 20
+)
+
+// ParseAdditionalAmounts decodes the blocks of a DE 54 value.
+func ParseAdditionalAmounts(data []byte) ([]AdditionalAmount, error) {
+	if len(data)%additionalAmountBlockSize != 0 {
+		return nil, fmt.Errorf("%w: additional amounts length %d is not a multiple of %d", ErrInvalidLength, len(data), additionalAmountBlockSize)
+	}
+
+	entries := make([]AdditionalAmount, 0, len(data)/additionalAmountBlockSize)
+	for offset := 0; offset < len(data); offset += additionalAmountBlockSize {
+		block := string(data[offset : offset+additionalAmountBlockSize])
+		minor, err := parseAmountDigits(block[7:], true)
+		if err != nil {
+			return nil, fmt.Errorf("additional amount %d: %w", offset/additionalAmountBlockSize+1, err)
+		}
+		entries = append(entries, AdditionalAmount{
+			AccountType: block[0:2],
+			AmountType:  block[2:4],
+			Amount:      Amount{Minor: minor, Currency: block[4:7]},
+		})
+	}
+	return entries, nil
+}
+
+// FormatAdditionalAmounts encodes entries as DE 54 blocks.
+func FormatAdditionalAmounts(entries []AdditionalAmount) ([]byte, error) {
+	var sb strings.Builder
+	sb.Grow(len(entries) * additionalAmountBlockSize)
+	for i, entry := range entries {
+		if len(entry.AccountType) != 2 || len(entry.AmountType) != 2 || len(entry.Amount.Currency) != 3 {
+			return nil, fmt.Errorf("%w: additional amount %d needs a 2-digit account type, 2-digit amount type and 3-digit currency", ErrInvalidFormat, i+1)
+		}
+		amount, err := formatAmountDigits(entry.Amount.Minor, 12, true)
+		if err != nil {
+			return nil, fmt.Errorf("additional amount %d: %w", i+1, err)
+		}
+		sb.WriteString(entry.AccountType)
+		sb.WriteString(entry.AmountType)
+		sb.WriteString(entry.Amount.Currency)
+		sb.WriteString(amount)
+	}
+	return []byte(sb.String()), nil
+}
+
+// GetAdditionalAmounts returns the decoded entries of DE 54.
+func (m *Message) GetAdditionalAmounts() ([]AdditionalAmount, error) {
+	data, err := m.GetBytes(additionalAmountField)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAdditionalAmounts(data)
+}
+
+// GetAdditionalAmount returns the first DE 54 entry of the given amount
+// type (e.g. AmountTypeAvailableBalance).
+func (m *Message) GetAdditionalAmount(amountType string) (AdditionalAmount, error) {
+	entries, err := m.GetAdditionalAmounts()
+	if err != nil {
+		return AdditionalAmount{}, err
+	}
+	for _, entry := range entries {
+		if entry.AmountType == amountType {
+			return entry, nil
+		}
+	}
+	return AdditionalAmount{}, fmt.Errorf("%w: amount type %s", ErrAmountNotFound, amountType)
+}
+
+// SetAdditionalAmounts replaces DE 54 with the given entries.
+func (m *Message) SetAdditionalAmounts(entries []AdditionalAmount) error {
+	data, err := FormatAdditionalAmounts(entries)
+	if err != nil {
+		return err
+	}
+	return m.SetField(additionalAmountField, data)
+}
+
+// AddAdditionalAmount appends an entry to DE 54.
+func (m *Message) AddAdditionalAmount(entry AdditionalAmount) error {
+	var entries []AdditionalAmount
+	if m.HasField(additionalAmountField) {
+		var err error
+		if entries, err = m.GetAdditionalAmounts(); err != nil {
+			return err
+		}
+	}
+	return m.SetAdditionalAmounts(append(entries, entry))
+}
@@ -0,0 +1,102 @@
+package iso8583
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAdditionalAmountsRoundTrip(t *testing.T) {
+	entries := []AdditionalAmount{
+		{AccountType: "10", AmountType: AmountTypeLedgerBalance, Amount: Amount{Minor: 123456, Currency: "840"}},
+		{AccountType: "10", AmountType: AmountTypeAvailableBalance, Amount: Amount{Minor: -500, Currency: "840"}},
+		{AccountType: "00", AmountType: AmountTypeCashback, Amount: Amount{Minor: 0, Currency: "978"}},
+	}
+	const raw = "1001840C000000123456" + "1002840D000000000500" + "0040978C000000000000"
+
+	data, err := FormatAdditionalAmounts(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != raw {
+		t.Errorf("FormatAdditionalAmounts = %q, want %q", data, raw)
+	}
+
+	got, err := ParseAdditionalAmounts([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("ParseAdditionalAmounts = %+v, want %+v", got, entries)
+	}
+
+	if got, err := ParseAdditionalAmounts(nil); err != nil || len(got) != 0 {
+		t.Errorf("ParseAdditionalAmounts(nil) = %v, %v", got, err)
+	}
+}
+
+func TestParseAdditionalAmountsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"partial block", "1001840C00000012345", ErrInvalidLength},
+		{"trailing partial block", "1001840C000000123456" + "10", ErrInvalidLength},
+		{"missing sign", "10018400000000123456", ErrInvalidFormat},
+		{"non-numeric amount", "1001840C00000012345X", ErrInvalidFormat},
+		{"bad second block", "1001840C000000123456" + "1002840X000000000500", ErrInvalidFormat},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseAdditionalAmounts([]byte(tt.data)); !errors.Is(err, tt.err) {
+			t.Errorf("%s: ParseAdditionalAmounts(%q) = %v, want %v", tt.name, tt.data, err, tt.err)
+		}
+	}
+}
+
+func TestFormatAdditionalAmountsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry AdditionalAmount
+		err   error
+	}{
+		{"short account type", AdditionalAmount{AccountType: "1", AmountType: "02", Amount: Amount{Currency: "840"}}, ErrInvalidFormat},
+		{"long amount type", AdditionalAmount{AccountType: "10", AmountType: "002", Amount: Amount{Currency: "840"}}, ErrInvalidFormat},
+		{"short currency", AdditionalAmount{AccountType: "10", AmountType: "02", Amount: Amount{Currency: "US"}}, ErrInvalidFormat},
+		{"amount too large", AdditionalAmount{AccountType: "10", AmountType: "02", Amount: Amount{Minor: 1e12, Currency: "840"}}, ErrInvalidLength},
+	}
+
+	for _, tt := range tests {
+		if _, err := FormatAdditionalAmounts([]AdditionalAmount{tt.entry}); !errors.Is(err, tt.err) {
+			t.Errorf("%s: FormatAdditionalAmounts = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestMessageAdditionalAmounts(t *testing.T) {
+	m := NewMessage(WithPackager(NewCompiledPackager(NewPackagerConfig())))
+
+	if _, err := m.GetAdditionalAmount(AmountTypeAvailableBalance); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("GetAdditionalAmount without DE 54 = %v, want ErrFieldNotFound", err)
+	}
+
+	ledger := AdditionalAmount{AccountType: "20", AmountType: AmountTypeLedgerBalance, Amount: Amount{Minor: 1000, Currency: "840"}}
+	available := AdditionalAmount{AccountType: "20", AmountType: AmountTypeAvailableBalance, Amount: Amount{Minor: -250, Currency: "840"}}
+	if err := m.AddAdditionalAmount(ledger); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddAdditionalAmount(available); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := m.GetString(54); err != nil || got != "2001840C000000001000"+"2002840D000000000250" {
+		t.Errorf("DE 54 = %q, %v", got, err)
+	}
+	if got, err := m.GetAdditionalAmount(AmountTypeAvailableBalance); err != nil || got != available {
+		t.Errorf("GetAdditionalAmount = %+v, %v; want %+v", got, err, available)
+	}
+	if _, err := m.GetAdditionalAmount(AmountTypeCashback); !errors.Is(err, ErrAmountNotFound) {
+		t.Errorf("GetAdditionalAmount(cashback) = %v, want ErrAmountNotFound", err)
+	}
+}
@@ -0,0 +1,274 @@
+package iso8583
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mkadit/iso8583/pan"
+)
+
+// Amount is a monetary amount in the minor unit of an ISO 4217 currency,
+// e.g. {Minor: 1234, Currency: "840"} is USD 12.34. Debits are negative.
+type Amount struct {
+	Minor    int64
+	Currency string // ISO 4217 numeric code (e.g. "840"); alphabetic codes only work where no currency field is set
+}
+
+// currencyExponents lists the ISO 4217 currencies whose minor unit is not
+// 1/100, by numeric and alphabetic code.
+var currencyExponents = map[string]int{
+	// No minor unit
+	"108": 0, "BIF": 0,
+	"152": 0, "CLP": 0,
+	"174": 0, "KMF": 0,
+	"262": 0, "DJF": 0,
+	"324": 0, "GNF": 0,
+	"352": 0, "ISK": 0,
+	"392": 0, "JPY": 0,
+	"410": 0, "KRW": 0,
+	"548": 0, "VUV": 0,
+	"600": 0, "PYG": 0,
+	"646": 0, "RWF": 0,
+	"704": 0, "VND": 0,
+	"800": 0, "UGX": 0,
+	"940": 0, "UYI": 0,
+	"950": 0, "XAF": 0,
+	"952": 0, "XOF": 0,
+	"953": 0, "XPF": 0,
+
+	// Three decimals
+	"048": 3, "BHD": 3,
+	"368": 3, "IQD": 3,
+	"400": 3, "JOD": 3,
+	"414": 3, "KWD": 3,
+	"434": 3, "LYD": 3,
+	"512": 3, "OMR": 3,
+	"788": 3, "TND": 3,
+
+	// Four decimals
+	"927": 4, "UYW": 4,
+	"990": 4, "CLF": 4,
+}
+
+// amountCurrencyFields maps the amount fields to the field holding their
+// currency code.
+var amountCurrencyFields = map[int]int{
+	4: 49, // Amount, transaction
+	5: 50, // Amount, settlement
+	6: 51, // Amount, cardholder billing
+}
+
+// feeCurrencyFields maps the fee fields to the field holding their currency
+// code. The default layout does not define them as amounts (DE 29/30 are n3,
+// DE 31 is LLVAR), so they only count when configured with an amount Format.
+var feeCurrencyFields = map[int]int{
+	28: 49, // Amount, transaction fee
+	29: 50, // Amount, settlement fee
+	30: 49, // Amount, transaction processing fee
+	31: 50, // Amount, settlement processing fee
+}
+
+// CurrencyExponent returns the number of minor unit digits of an ISO 4217
+// currency given by numeric or alphabetic code. Unlisted and empty codes use 2.
+func CurrencyExponent(currency string) int {
+	if exp, ok := currencyExponents[strings.ToUpper(strings.TrimSpace(currency))]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Exponent returns the number of minor unit digits of the amount's currency.
+func (a Amount) Exponent() int {
+	return CurrencyExponent(a.Currency)
+}
+
+// String formats the amount with its decimal point, e.g. "-12.34 840".
+func (a Amount) String() string {
+	minor := a.Minor
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	s := strconv.FormatInt(minor, 10)
+	if exp := a.Exponent(); exp > 0 {
+		if len(s) <= exp {
+			s = strings.Repeat("0", exp-len(s)+1) + s
+		}
+		s = s[:len(s)-exp] + "." + s[len(s)-exp:]
+	}
+	if a.Currency != "" {
+		return sign + s + " " + a.Currency
+	}
+	return sign + s
+}
+
+// rescaleMinor converts a value with from decimals to one with to decimals.
+// It fails if digits would be lost or the result overflows.
+func rescaleMinor(value int64, from, to int) (int64, error) {
+	for ; from < to; from++ {
+		if value > math.MaxInt64/10 || value < math.MinInt64/10 {
+			return 0, fmt.Errorf("%w: amount overflows", ErrInvalidFormat)
+		}
+		value *= 10
+	}
+	for ; from > to; from-- {
+		if value%10 != 0 {
+			return 0, fmt.Errorf("%w: amount has more decimals than the currency", ErrInvalidFormat)
+		}
+		value /= 10
+	}
+	return value, nil
+}
+
+// formatAmountDigits formats a value zero-padded to width digits; signed
+// values get a C (credit) or D (debit) prefix on top of width.
+func formatAmountDigits(value int64, width int, signed bool) (string, error) {
+	prefix := ""
+	if signed {
+		prefix = "C"
+		if value < 0 {
+			prefix = "D"
+		}
+	}
+	if value < 0 {
+		if !signed {
+			return "", fmt.Errorf("%w: negative amount in unsigned field", ErrInvalidFormat)
+		}
+		value = -value
+	}
+
+	digits := strconv.FormatInt(value, 10)
+	if width > 0 {
+		if len(digits) > width {
+			return "", fmt.Errorf("%w: amount exceeds %d digits", ErrInvalidLength, width)
+		}
+		digits = strings.Repeat("0", width-len(digits)) + digits
+	}
+	return prefix + digits, nil
+}
+
+// parseAmountDigits parses an amount with an optional C/D sign prefix
+// (required if signed is set).
+func parseAmountDigits(s string, signed bool) (int64, error) {
+	negative := false
+	if len(s) > 0 && (s[0] == 'C' || s[0] == 'D') {
+		negative = s[0] == 'D'
+		s = s[1:]
+	} else if signed {
+		return 0, fmt.Errorf("%w: amount must start with C or D", ErrInvalidFormat)
+	}
+	if s == "" || strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return 0, fmt.Errorf("%w: invalid amount %q", ErrInvalidFormat, s)
+	}
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+	if negative {
+		value = -value
+	}
+	return value, nil
+}
+
+// amountLayout returns the decimals and sign convention of an amount field.
+// Fields without a Format are treated as "amount".
+func (m *Message) amountLayout(fieldNum int) (config FieldConfig, decimals int, fixed, signed bool, err error) {
+	config, _ = m.fieldConfig(fieldNum)
+	if config.Format == "" {
+		return config, 0, false, false, nil
+	}
+	decimals, fixed, signed, ok := amountFormat(config.Format)
+	if !ok {
+		return config, 0, false, false, fmt.Errorf("%w: field %d has format %q", ErrInvalidFormat, fieldNum, config.Format)
+	}
+	return config, decimals, fixed, signed, nil
+}
+
+// amountCurrencyField returns the field holding the currency code of an amount field.
+func amountCurrencyField(fieldNum int, config FieldConfig) (int, bool) {
+	if currencyField, ok := amountCurrencyFields[fieldNum]; ok {
+		return currencyField, true
+	}
+	if _, _, _, ok := amountFormat(config.Format); ok {
+		currencyField, ok := feeCurrencyFields[fieldNum]
+		return currencyField, ok
+	}
+	return 0, false
+}
+
+// SetAmount sets an amount field with the implied decimals of its format
+// and, for DE 4/5/6, the currency code in DE 49/50/51 when a.Currency is
+// set; that code must be numeric, as DE 49/50/51 are n3. Signed formats get
+// a C/D prefix. DE 28-31 are not amounts in the default layout; configured
+// with an amount Format, e.g. "signed_amount" (AN, length 9) for the x+n 8
+// form, their currency goes in DE 49 or DE 50.
+func (m *Message) SetAmount(fieldNum int, a Amount) error {
+	config, decimals, fixed, signed, err := m.amountLayout(fieldNum)
+	if err != nil {
+		return err
+	}
+	currencyField, hasCurrency := amountCurrencyField(fieldNum, config)
+	hasCurrency = hasCurrency && a.Currency != ""
+	if hasCurrency && (len(a.Currency) != 3 || !pan.IsDigits(a.Currency)) {
+		return fmt.Errorf("%w: currency %q for field %d must be a 3-digit ISO 4217 numeric code", ErrInvalidFormat, a.Currency, currencyField)
+	}
+
+	value := a.Minor
+	if fixed {
+		if value, err = rescaleMinor(value, a.Exponent(), decimals); err != nil {
+			return err
+		}
+	}
+
+	width := 0
+	if config.Length == LengthFixed && config.MaxLength > 0 {
+		width = config.MaxLength
+		if signed {
+			width--
+		}
+	}
+	text, err := formatAmountDigits(value, width, signed)
+	if err != nil {
+		return err
+	}
+	if err := m.SetField(fieldNum, text); err != nil {
+		return err
+	}
+
+	if hasCurrency {
+		return m.SetField(currencyField, a.Currency)
+	}
+	return nil
+}
+
+// GetAmount returns an amount field in minor units of its currency, taken
+// from DE 49/50/51 as for SetAmount.
+func (m *Message) GetAmount(fieldNum int) (Amount, error) {
+	config, decimals, fixed, signed, err := m.amountLayout(fieldNum)
+	if err != nil {
+		return Amount{}, err
+	}
+	text, err := m.GetString(fieldNum)
+	if err != nil {
+		return Amount{}, err
+	}
+	value, err := parseAmountDigits(text, signed)
+	if err != nil {
+		return Amount{}, err
+	}
+
+	a := Amount{Minor: value}
+	if currencyField, ok := amountCurrencyField(fieldNum, config); ok {
+		if currency, err := m.GetString(currencyField); err == nil {
+			a.Currency = strings.TrimSpace(currency)
+		}
+	}
+	if fixed {
+		if a.Minor, err = rescaleMinor(value, decimals, a.Exponent()); err != nil {
+			return Amount{}, err
+		}
+	}
+	return a, nil
+}
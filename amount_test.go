@@ -0,0 +1,187 @@
+package iso8583
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRescaleMinor(t *testing.T) {
+	tests := []struct {
+		value    int64
+		from, to int
+		want     int64
+		wantErr  bool
+	}{
+		{1234, 2, 2, 1234, false},
+		{1234, 0, 2, 123400, false},
+		{1234, 2, 3, 12340, false},
+		{12340, 3, 2, 1234, false},
+		{-12340, 3, 2, -1234, false},
+		{1235, 3, 2, 0, true},       // Digits would be lost
+		{1 << 62, 0, 2, 0, true},    // Overflow
+		{-(1 << 62), 0, 2, 0, true}, // Negative overflow
+		{922337203685477580, 0, 1, 9223372036854775800, false},
+	}
+
+	for _, tt := range tests {
+		got, err := rescaleMinor(tt.value, tt.from, tt.to)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidFormat) {
+				t.Errorf("rescaleMinor(%d, %d, %d) = %d, %v; want ErrInvalidFormat", tt.value, tt.from, tt.to, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("rescaleMinor(%d, %d, %d) = %d, %v; want %d", tt.value, tt.from, tt.to, got, err, tt.want)
+		}
+	}
+}
+
+func TestAmountDigits(t *testing.T) {
+	tests := []struct {
+		value  int64
+		width  int
+		signed bool
+		want   string
+		err    error
+	}{
+		{1234, 12, false, "000000001234", nil},
+		{1234, 0, false, "1234", nil},
+		{5, 8, true, "C00000005", nil},
+		{-5, 8, true, "D00000005", nil},
+		{0, 8, true, "C00000000", nil},
+		{-1, 12, false, "", ErrInvalidFormat},
+		{123456789, 8, true, "", ErrInvalidLength},
+	}
+
+	for _, tt := range tests {
+		got, err := formatAmountDigits(tt.value, tt.width, tt.signed)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("formatAmountDigits(%d, %d, %v) = %q, %v; want %v", tt.value, tt.width, tt.signed, got, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("formatAmountDigits(%d, %d, %v) = %q, %v; want %q", tt.value, tt.width, tt.signed, got, err, tt.want)
+			continue
+		}
+
+		back, err := parseAmountDigits(got, tt.signed)
+		if err != nil || back != tt.value {
+			t.Errorf("parseAmountDigits(%q) = %d, %v; want %d", got, back, err, tt.value)
+		}
+	}
+
+	for _, s := range []string{"", "C", "00000005", "12a4", "D-5", "X00000005"} {
+		if _, err := parseAmountDigits(s, true); !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("parseAmountDigits(%q, signed) = %v, want ErrInvalidFormat", s, err)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		want   string
+	}{
+		{Amount{Minor: 1234, Currency: "840"}, "12.34 840"},
+		{Amount{Minor: -1234, Currency: "840"}, "-12.34 840"},
+		{Amount{Minor: 5, Currency: "JPY"}, "5 JPY"},
+		{Amount{Minor: 5, Currency: "414"}, "0.005 414"},
+		{Amount{Minor: 0}, "0.00"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.amount.String(); got != tt.want {
+			t.Errorf("%#v.String() = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestSetGetAmount(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig(
+		WithFieldConfig(5, FieldConfig{Type: FieldTypeN, Length: LengthFixed, MaxLength: 12, Format: "amount:2"}),
+		WithFieldConfig(28, FieldConfig{Type: FieldTypeAN, Length: LengthFixed, MaxLength: 9, Format: "signed_amount"}),
+	))
+
+	tests := []struct {
+		name          string
+		field         int
+		amount        Amount
+		want          string // Raw field value
+		currencyField int
+	}{
+		{"implied decimals", 4, Amount{Minor: 1234, Currency: "840"}, "000000001234", 49},
+		{"zero exponent", 4, Amount{Minor: 1234, Currency: "392"}, "000000001234", 49},
+		{"fixed decimals rescaled up", 5, Amount{Minor: 1234, Currency: "392"}, "000000123400", 50},
+		{"fixed decimals rescaled down", 5, Amount{Minor: 12340, Currency: "414"}, "000000001234", 50},
+		{"signed debit", 28, Amount{Minor: -150, Currency: "840"}, "D00000150", 49},
+		{"signed credit", 28, Amount{Minor: 150, Currency: "840"}, "C00000150", 49},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMessage(WithPackager(pkg))
+			if err := m.SetAmount(tt.field, tt.amount); err != nil {
+				t.Fatalf("SetAmount: %v", err)
+			}
+			if got, _ := m.GetString(tt.field); got != tt.want {
+				t.Errorf("field %d = %q, want %q", tt.field, got, tt.want)
+			}
+			if got, _ := m.GetString(tt.currencyField); got != tt.amount.Currency {
+				t.Errorf("DE %d = %q, want %q", tt.currencyField, got, tt.amount.Currency)
+			}
+			got, err := m.GetAmount(tt.field)
+			if err != nil || got != tt.amount {
+				t.Errorf("GetAmount = %+v, %v; want %+v", got, err, tt.amount)
+			}
+		})
+	}
+}
+
+func TestSetAmountErrors(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig(
+		WithFieldConfig(5, FieldConfig{Type: FieldTypeN, Length: LengthFixed, MaxLength: 12, Format: "amount:2"}),
+	))
+
+	tests := []struct {
+		name   string
+		field  int
+		amount Amount
+	}{
+		{"alphabetic currency", 4, Amount{Minor: 1234, Currency: "USD"}},
+		{"short currency", 4, Amount{Minor: 1234, Currency: "84"}},
+		{"negative unsigned", 4, Amount{Minor: -1, Currency: "840"}},
+		{"decimals lost", 5, Amount{Minor: 12345, Currency: "414"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMessage(WithPackager(pkg))
+			if err := m.SetAmount(tt.field, tt.amount); !errors.Is(err, ErrInvalidFormat) {
+				t.Fatalf("SetAmount = %v, want ErrInvalidFormat", err)
+			}
+			if m.HasField(tt.field) || m.HasField(49) || m.HasField(50) {
+				t.Errorf("fields set after failed SetAmount")
+			}
+		})
+	}
+}
+
+func TestSetAmountFeeFields(t *testing.T) {
+	// DE 28-31 have no amount format in the default layout
+	m := NewMessage(WithPackager(NewCompiledPackager(newTestConfig())))
+	if err := m.SetAmount(28, Amount{Minor: 150, Currency: "840"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m.GetString(28); got != "000000150" {
+		t.Errorf("DE 28 = %q, want %q", got, "000000150")
+	}
+	if m.HasField(49) {
+		t.Error("SetAmount(28) set DE 49 without an amount format")
+	}
+	if got, err := m.GetAmount(28); err != nil || got != (Amount{Minor: 150}) {
+		t.Errorf("GetAmount(28) = %+v, %v", got, err)
+	}
+}
@@ -5,12 +5,12 @@ import (
 	"fmt"
 )
 
-// BitmapManager handles operations for the ISO8583 64-bit primary
-// and 64-bit secondary bitmaps.
+// BitmapManager handles operations for the ISO8583 primary, secondary
+// and (optionally) tertiary 64-bit bitmaps.
 type BitmapManager struct {
-	primary      [BitmapSize]byte          // 8 bytes for 64 bits
-	secondary    [SecondaryBitmapSize]byte // 8 bytes for 64 bits
-	hasSecondary bool                      // True if DE 1 is set
+	bitmaps    [MaxBitmaps][BitmapSize]byte // 8 bytes (64 bits) per bitmap
+	maxBitmaps int                          // Bitmaps allowed by the packager (0 means DefaultBitmaps)
+	extension  BitmapExtension              // How the presence of the next bitmap is signalled
 }
 
 // NewBitmapManager creates a new bitmap manager.
@@ -18,31 +18,62 @@ func NewBitmapManager() *BitmapManager {
 	return &BitmapManager{}
 }
 
-// SetField sets the bit for the given field number (1-128).
-// It automatically sets the secondary bitmap indicator (DE 1) if fieldNum > 64.
+// Configure sets how many bitmaps the message may carry (1-3) and how the
+// presence of each following bitmap is signalled.
+// It does not clear any bits already set.
+func (bm *BitmapManager) Configure(maxBitmaps int, extension BitmapExtension) {
+	bm.maxBitmaps = maxBitmaps
+	bm.extension = extension
+}
+
+// limit returns the configured number of bitmaps, falling back to DefaultBitmaps.
+func (bm *BitmapManager) limit() int {
+	if bm.maxBitmaps < 1 || bm.maxBitmaps > MaxBitmaps {
+		return DefaultBitmaps
+	}
+	return bm.maxBitmaps
+}
+
+// count returns the number of bitmaps currently in use.
+// With BitmapExtensionFixed every configured bitmap is always in use.
+func (bm *BitmapManager) count() int {
+	limit := bm.limit()
+	if bm.extension == BitmapExtensionFixed {
+		return limit
+	}
+	n := 1
+	for n < limit && bm.bitmaps[n-1][0]&0x80 != 0 {
+		n++
+	}
+	return n
+}
+
+// MaxField returns the highest field number the configured bitmaps can address.
+func (bm *BitmapManager) MaxField() int {
+	return bm.limit() * 64
+}
+
+// IsExtensionField reports whether fieldNum is the extension bit of a bitmap
+// (DE 1, and DE 65 when a tertiary bitmap is configured) rather than a data field.
+func (bm *BitmapManager) IsExtensionField(fieldNum int) bool {
+	idx := (fieldNum - 1) / 64
+	return fieldNum >= 1 && (fieldNum-1)%64 == 0 && idx < bm.limit()-1
+}
+
+// SetField sets the bit for the given field number (1-192).
+// It automatically sets the extension bit of every preceding bitmap
+// (DE 1 for fields 65-128, DE 1 and DE 65 for fields 129-192).
 func (bm *BitmapManager) SetField(fieldNum int) error {
-	if fieldNum < 1 || fieldNum > MaxFieldNumber {
+	if fieldNum < 1 || fieldNum > bm.MaxField() {
 		return fmt.Errorf("field number %d out of range", fieldNum)
 	}
 
-	if fieldNum <= 64 {
-		// Primary bitmap
-		byteIndex := (fieldNum - 1) / 8
-		bitIndex := 7 - ((fieldNum - 1) % 8) // Bits are 7 (MSB) to 0 (LSB)
-		bm.primary[byteIndex] |= (1 << bitIndex)
-
-		// Note: Field 1 is the secondary bitmap indicator.
-		// If fieldNum > 64, this logic will be hit by the 'else' block.
-		// If fieldNum == 1, it will be set here, which is correct.
-	} else {
-		// Secondary bitmap
-		bm.hasSecondary = true
-		bm.primary[0] |= 0x80 // Set bit 1 (MSB of first byte)
-
-		adjustedField := fieldNum - 64
-		byteIndex := (adjustedField - 1) / 8
-		bitIndex := 7 - ((adjustedField - 1) % 8)
-		bm.secondary[byteIndex] |= (1 << bitIndex)
+	idx := (fieldNum - 1) / 64
+	bit := (fieldNum - 1) % 64
+	bm.bitmaps[idx][bit/8] |= 1 << (7 - bit%8) // Bits are 7 (MSB) to 0 (LSB)
+
+	for i := 0; i < idx; i++ {
+		bm.bitmaps[i][0] |= 0x80
 	}
 
 	return nil
@@ -54,244 +85,152 @@ func (bm *BitmapManager) IsFieldSet(fieldNum int) bool {
 		return false
 	}
 
-	if fieldNum <= 64 {
-		// Primary bitmap
-		byteIndex := (fieldNum - 1) / 8
-		bitIndex := 7 - ((fieldNum - 1) % 8)
-		return (bm.primary[byteIndex] & (1 << bitIndex)) != 0
-	} else {
-		// Secondary bitmap
-		if !bm.hasSecondary {
-			return false // No secondary bitmap, so field can't be set
-		}
-
-		adjustedField := fieldNum - 64
-		byteIndex := (adjustedField - 1) / 8
-		bitIndex := 7 - ((adjustedField - 1) % 8)
-		return (bm.secondary[byteIndex] & (1 << bitIndex)) != 0
+	idx := (fieldNum - 1) / 64
+	if idx >= bm.count() {
+		return false // Bitmap not present, so field can't be set
 	}
+
+	bit := (fieldNum - 1) % 64
+	return bm.bitmaps[idx][bit/8]&(1<<(7-bit%8)) != 0
 }
 
 // ClearField clears the bit for the given field number.
-// If clearing the last field in the secondary bitmap, it also clears DE 1.
+// If this empties a secondary or tertiary bitmap, the extension bit
+// pointing to it is cleared as well.
 func (bm *BitmapManager) ClearField(fieldNum int) error {
 	if fieldNum < 1 || fieldNum > MaxFieldNumber {
 		return fmt.Errorf("field number %d out of range", fieldNum)
 	}
 
-	if fieldNum <= 64 {
-		// Primary bitmap
-		byteIndex := (fieldNum - 1) / 8
-		bitIndex := 7 - ((fieldNum - 1) % 8)
-		bm.primary[byteIndex] &^= (1 << bitIndex) // &^= is (AND NOT)
-	} else {
-		// Secondary bitmap
-		if !bm.hasSecondary {
-			return nil // Already clear
-		}
+	idx := (fieldNum - 1) / 64
+	bit := (fieldNum - 1) % 64
+	bm.bitmaps[idx][bit/8] &^= 1 << (7 - bit%8) // &^= is (AND NOT)
 
-		adjustedField := fieldNum - 64
-		byteIndex := (adjustedField - 1) / 8
-		bitIndex := 7 - ((adjustedField - 1) % 8)
-		bm.secondary[byteIndex] &^= (1 << bitIndex)
-
-		// Check if secondary bitmap is now empty
-		isEmpty := true
-		for i := 0; i < SecondaryBitmapSize; i++ {
-			if bm.secondary[i] != 0 {
-				isEmpty = false
-				break
-			}
-		}
-
-		// If empty, clear the secondary indicator (DE 1)
-		if isEmpty {
-			bm.hasSecondary = false
-			bm.primary[0] &^= 0x80 // Clear bit 1
+	// Walk back from the last bitmap, dropping extension bits of empty ones
+	for i := bm.limit() - 1; i >= 1; i-- {
+		if !bitmapEmpty(&bm.bitmaps[i]) {
+			break
 		}
+		bm.bitmaps[i-1][0] &^= 0x80
 	}
 
 	return nil
 }
 
+// bitmapEmpty reports whether no bit is set in b.
+func bitmapEmpty(b *[BitmapSize]byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // GetPresentFields returns a slice of field numbers that are set in the bitmap.
+// Extension bits (DE 1, and DE 65 with a tertiary bitmap) are not reported.
 func (bm *BitmapManager) GetPresentFields() []int {
 	fields := make([]int, 0, 64) // Pre-allocate for common case
 
-	// Check primary bitmap (fields 2-64)
-	for i := 1; i < BitmapSize*8; i++ { // Start from i=1 (field 2)
-		fieldNum := i + 1
+	last := bm.count() * 64
+	for fieldNum := 2; fieldNum <= last; fieldNum++ {
+		if bm.IsExtensionField(fieldNum) {
+			continue
+		}
 		if bm.IsFieldSet(fieldNum) {
 			fields = append(fields, fieldNum)
 		}
 	}
 
-	// Check secondary bitmap if present (fields 65-128)
-	if bm.hasSecondary {
-		for i := 0; i < SecondaryBitmapSize*8; i++ {
-			fieldNum := i + 65
-			if bm.IsFieldSet(fieldNum) {
-				fields = append(fields, fieldNum)
-			}
-		}
-	}
-
 	return fields
 }
 
 // PackBitmap packs the bitmap into the buffer, using the specified encoding.
 // Returns the number of bytes written.
 func (bm *BitmapManager) PackBitmap(buf []byte, encoding BitmapEncoding) (int, error) {
+	width := BitmapSize // 8 bytes per bitmap
 	if encoding == BitmapEncodingHex {
-		return bm.packBitmapHex(buf)
+		width = BitmapSize * 2 // 16 hex chars per bitmap
 	}
-	return bm.packBitmapBinary(buf)
-}
 
-// packBitmapBinary packs the bitmap as raw binary bytes (8 or 16 bytes).
-func (bm *BitmapManager) packBitmapBinary(buf []byte) (int, error) {
-	offset := 0
-	totalSize := BitmapSize // 8 bytes
-	if bm.hasSecondary {
-		totalSize += SecondaryBitmapSize // +8 = 16 bytes
-	}
-	if len(buf) < totalSize {
+	n := bm.count()
+	if len(buf) < n*width {
 		return 0, ErrBufferTooSmall
 	}
 
-	// Write primary bitmap
-	copy(buf[offset:], bm.primary[:])
-	offset += BitmapSize
-
-	// Write secondary bitmap if present
-	if bm.hasSecondary {
-		copy(buf[offset:], bm.secondary[:])
-		offset += SecondaryBitmapSize
-	}
-
-	return offset, nil
-}
-
-// packBitmapHex packs the bitmap as a hex-encoded string (16 or 32 chars).
-func (bm *BitmapManager) packBitmapHex(buf []byte) (int, error) {
-	const hexBitmapSize = 16 // 8 bytes * 2 hex chars
 	offset := 0
-
-	// Check space for primary bitmap (16 hex chars)
-	if len(buf) < offset+hexBitmapSize {
-		return 0, ErrBufferTooSmall
-	}
-	// hex.Encode(buf[offset:offset+hexBitmapSize], bm.primary[:])
-	encodeHexUpper(buf[offset:offset+hexBitmapSize], bm.primary[:])
-	offset += hexBitmapSize
-
-	// Check space and write secondary bitmap if present
-	if bm.hasSecondary {
-		if len(buf) < offset+hexBitmapSize {
-			return 0, ErrBufferTooSmall
+	for i := 0; i < n; i++ {
+		block := bm.bitmaps[i]
+		if i < n-1 {
+			block[0] |= 0x80 // Flag the bitmap that follows (always set for fixed layouts)
 		}
-		// hex.Encode(buf[offset:offset+hexBitmapSize], bm.secondary[:])
-		encodeHexUpper(buf[offset:offset+hexBitmapSize], bm.secondary[:])
-		offset += hexBitmapSize
+		if encoding == BitmapEncodingHex {
+			encodeHexUpper(buf[offset:offset+width], block[:])
+		} else {
+			copy(buf[offset:offset+width], block[:])
+		}
+		offset += width
 	}
+
 	return offset, nil
 }
 
 // UnpackBitmap unpacks the bitmap from the data buffer, using the specified encoding.
 // Returns the number of bytes consumed.
 func (bm *BitmapManager) UnpackBitmap(data []byte, encoding BitmapEncoding) (int, error) {
-	if encoding == BitmapEncodingHex {
-		return bm.unpackBitmapHex(data)
-	}
-	return bm.unpackBitmapBinary(data)
-}
+	bm.clear()
 
-// unpackBitmapBinary unpacks the bitmap from raw binary bytes.
-func (bm *BitmapManager) unpackBitmapBinary(data []byte) (int, error) {
-	if len(data) < BitmapSize { // Must have at least primary
-		return 0, ErrInvalidBitmap
+	width := BitmapSize
+	if encoding == BitmapEncodingHex {
+		width = BitmapSize * 2
 	}
 
-	// Read primary bitmap
-	copy(bm.primary[:], data[:BitmapSize])
-	offset := BitmapSize
-
-	// Check DE 1 (MSB of first byte)
-	bm.hasSecondary = (bm.primary[0] & 0x80) != 0
-
-	if bm.hasSecondary {
-		// Must have secondary bitmap
-		if len(data) < offset+SecondaryBitmapSize {
+	offset := 0
+	for i := 0; i < bm.limit(); i++ {
+		// A chained bitmap is only present if the previous one flags it
+		if i > 0 && bm.extension == BitmapExtensionChained && bm.bitmaps[i-1][0]&0x80 == 0 {
+			break
+		}
+		if len(data) < offset+width {
 			return 0, ErrInvalidBitmap
 		}
-		copy(bm.secondary[:], data[offset:offset+SecondaryBitmapSize])
-		offset += SecondaryBitmapSize
-	} else {
-		// Ensure secondary bitmap is zeroed out
-		for i := range bm.secondary {
-			bm.secondary[i] = 0
+
+		if encoding == BitmapEncodingHex {
+			if _, err := hex.Decode(bm.bitmaps[i][:], data[offset:offset+width]); err != nil {
+				return 0, ErrInvalidBitmapHex
+			}
+		} else {
+			copy(bm.bitmaps[i][:], data[offset:offset+width])
 		}
+		offset += width
 	}
+
 	return offset, nil
 }
 
-// unpackBitmapHex unpacks the bitmap from a hex-encoded string.
-func (bm *BitmapManager) unpackBitmapHex(data []byte) (int, error) {
-	const hexBitmapSize = 16 // 16 hex chars
-	if len(data) < hexBitmapSize {
-		return 0, ErrInvalidBitmap
-	}
-
-	// Unpack primary bitmap (16 hex chars -> 8 bytes)
-	_, err := hex.Decode(bm.primary[:], data[:hexBitmapSize])
-	if err != nil {
-		return 0, ErrInvalidBitmapHex
-	}
-	offset := hexBitmapSize
-
-	// Check DE 1
-	bm.hasSecondary = (bm.primary[0] & 0x80) != 0
-
-	if bm.hasSecondary {
-		// Must have secondary bitmap (another 16 hex chars)
-		if len(data) < offset+hexBitmapSize {
-			return 0, ErrInvalidBitmap
-		}
-		_, err := hex.Decode(bm.secondary[:], data[offset:offset+hexBitmapSize])
-		if err != nil {
-			return 0, ErrInvalidBitmapHex
-		}
-		offset += hexBitmapSize
-	} else {
-		// Ensure secondary bitmap is zeroed out
-		for i := range bm.secondary {
-			bm.secondary[i] = 0
-		}
-	}
-	return offset, nil
+// clear zeroes every bitmap without touching the configured layout.
+func (bm *BitmapManager) clear() {
+	bm.bitmaps = [MaxBitmaps][BitmapSize]byte{}
 }
 
-// Reset clears all bits in both bitmaps.
+// Reset clears all bits and restores the default two-bitmap layout.
 func (bm *BitmapManager) Reset() {
-	for i := range bm.primary {
-		bm.primary[i] = 0
-	}
-	for i := range bm.secondary {
-		bm.secondary[i] = 0
-	}
-	bm.hasSecondary = false
+	bm.clear()
+	bm.maxBitmaps = 0
+	bm.extension = BitmapExtensionChained
 }
 
-// HasSecondaryBitmap returns true if the secondary bitmap indicator (DE 1) is set.
+// HasSecondaryBitmap returns true if the secondary bitmap is present.
 func (bm *BitmapManager) HasSecondaryBitmap() bool {
-	return bm.hasSecondary
+	return bm.count() >= 2
+}
+
+// HasTertiaryBitmap returns true if the tertiary bitmap is present.
+func (bm *BitmapManager) HasTertiaryBitmap() bool {
+	return bm.count() >= 3
 }
 
-// BitmapSize returns the total size of the bitmap in bytes (8 or 16).
+// BitmapSize returns the total size of the bitmap in bytes (8, 16 or 24).
 func (bm *BitmapManager) BitmapSize() int {
-	size := BitmapSize
-	if bm.hasSecondary {
-		size += SecondaryBitmapSize
-	}
-	return size
+	return bm.count() * BitmapSize
 }
@@ -0,0 +1,124 @@
+package iso8583
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBitmapExtensionBits(t *testing.T) {
+	bm := NewBitmapManager()
+	bm.Configure(3, BitmapExtensionChained)
+
+	bm.SetField(3)
+	if bm.HasSecondaryBitmap() || bm.BitmapSize() != 8 {
+		t.Errorf("primary-only bitmap size = %d", bm.BitmapSize())
+	}
+	bm.SetField(130)
+	if !bm.IsFieldSet(1) || !bm.IsFieldSet(65) || !bm.HasTertiaryBitmap() || bm.BitmapSize() != 24 {
+		t.Errorf("DE 130 did not flag the secondary and tertiary bitmaps")
+	}
+	if got := bm.GetPresentFields(); !reflect.DeepEqual(got, []int{3, 130}) {
+		t.Errorf("GetPresentFields = %v, want [3 130]", got)
+	}
+
+	bm.ClearField(130)
+	if bm.IsFieldSet(1) || bm.IsFieldSet(65) || bm.BitmapSize() != 8 {
+		t.Errorf("clearing DE 130 left extension bits set")
+	}
+
+	bm.Configure(2, BitmapExtensionChained)
+	if err := bm.SetField(130); err == nil {
+		t.Error("SetField(130) accepted with two bitmaps")
+	}
+	if bm.IsExtensionField(65) {
+		t.Error("DE 65 is a data field with two bitmaps")
+	}
+}
+
+func TestBitmapPackUnpack(t *testing.T) {
+	tests := []struct {
+		name      string
+		bitmaps   int
+		extension BitmapExtension
+		fields    []int
+		size      int
+	}{
+		{"primary", 3, BitmapExtensionChained, []int{2, 64}, 8},
+		{"secondary", 3, BitmapExtensionChained, []int{2, 70}, 16},
+		{"tertiary", 3, BitmapExtensionChained, []int{2, 192}, 24},
+		{"fixed", 3, BitmapExtensionFixed, []int{2}, 24},
+		{"single", 1, BitmapExtensionChained, []int{2, 64}, 8},
+	}
+
+	for _, tt := range tests {
+		for _, encoding := range []BitmapEncoding{BitmapEncodingBinary, BitmapEncodingHex} {
+			bm := NewBitmapManager()
+			bm.Configure(tt.bitmaps, tt.extension)
+			for _, fieldNum := range tt.fields {
+				bm.SetField(fieldNum)
+			}
+
+			buf := make([]byte, 48)
+			n, err := bm.PackBitmap(buf, encoding)
+			want := tt.size
+			if encoding == BitmapEncodingHex {
+				want *= 2
+			}
+			if err != nil || n != want {
+				t.Errorf("%s: PackBitmap = %d, %v, want %d bytes", tt.name, n, err, want)
+				continue
+			}
+
+			got := NewBitmapManager()
+			got.Configure(tt.bitmaps, tt.extension)
+			if consumed, err := got.UnpackBitmap(buf[:n], encoding); err != nil || consumed != n {
+				t.Errorf("%s: UnpackBitmap = %d, %v, want %d", tt.name, consumed, err, n)
+			}
+			if fields := got.GetPresentFields(); !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("%s: fields = %v, want %v", tt.name, fields, tt.fields)
+			}
+		}
+	}
+}
+
+func TestBitmapUnpackErrors(t *testing.T) {
+	bm := NewBitmapManager()
+	bm.Configure(3, BitmapExtensionChained)
+	// The primary bitmap flags a secondary bitmap that is missing
+	if _, err := bm.UnpackBitmap([]byte{0x80, 0, 0, 0, 0, 0, 0, 0}, BitmapEncodingBinary); !errors.Is(err, ErrInvalidBitmap) {
+		t.Errorf("UnpackBitmap = %v, want ErrInvalidBitmap", err)
+	}
+	if _, err := bm.UnpackBitmap([]byte("72000000000000ZZ"), BitmapEncodingHex); !errors.Is(err, ErrInvalidBitmapHex) {
+		t.Errorf("UnpackBitmap = %v, want ErrInvalidBitmapHex", err)
+	}
+}
+
+func TestTertiaryBitmapMessage(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig(
+		WithBitmaps(3, BitmapExtensionChained),
+		WithFieldConfig(65, FieldConfig{Type: FieldTypeB, Length: LengthFixed, MaxLength: 8}),
+		WithFieldConfig(130, FieldConfig{Type: FieldTypeANS, Length: LengthLLVAR, MaxLength: 20}),
+		WithFieldConfig(192, FieldConfig{Type: FieldTypeN, Length: LengthFixed, MaxLength: 4}),
+	))
+	m := newTestMessage(t, pkg, "0800", map[int]string{11: "000001", 70: "301", 130: "extended", 192: "1234"})
+
+	// DE 65 flags the tertiary bitmap, so it cannot carry data
+	if err := m.SetField(65, "data"); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("SetField(65) = %v, want ErrInvalidField", err)
+	}
+
+	got := repack(t, m)
+	if fields := got.GetPresentFields(); !reflect.DeepEqual(fields, []int{11, 70, 130, 192}) {
+		t.Errorf("fields = %v", fields)
+	}
+	if s, _ := got.GetString(130); s != "extended" {
+		t.Errorf("DE 130 = %q", s)
+	}
+
+	// Without a tertiary bitmap DE 129-192 cannot be set
+	m = NewMessage(WithPackager(NewCompiledPackager(newTestConfig())))
+	if err := m.SetField(130, "x"); err == nil {
+		t.Error("SetField(130) accepted with two bitmaps")
+	}
+}
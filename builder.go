@@ -33,11 +33,12 @@ func (b *Builder) Release() {
 }
 
 func (b *Builder) MTI(mti string) *Builder {
-	if len(mti) != 4 {
-		b.errors = append(b.errors, ErrInvalidMTI)
+	parsed, err := ParseMTI(mti)
+	if err != nil {
+		b.errors = append(b.errors, err)
 		return b
 	}
-	copy(b.msg.mti[:], mti)
+	b.msg.SetMTI(parsed[:])
 	return b
 }
 
@@ -60,6 +61,23 @@ func (b *Builder) Amount(amount string) *Builder {
 	return b.Field(4, amount)
 }
 
+// AmountOf sets an amount field and its currency field from a typed Amount
+// (see Message.SetAmount).
+func (b *Builder) AmountOf(fieldNum int, amount Amount) *Builder {
+	if err := b.msg.SetAmount(fieldNum, amount); err != nil {
+		b.errors = append(b.errors, err)
+	}
+	return b
+}
+
+// AdditionalAmount appends an entry to DE 54.
+func (b *Builder) AdditionalAmount(entry AdditionalAmount) *Builder {
+	if err := b.msg.AddAdditionalAmount(entry); err != nil {
+		b.errors = append(b.errors, err)
+	}
+	return b
+}
+
 func (b *Builder) STAN(stan string) *Builder {
 	return b.Field(11, stan)
 }
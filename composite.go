@@ -0,0 +1,453 @@
+package iso8583
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// unpackSubfields splits a composite field's logical value into its subfields.
+// Returns the subfield values keyed by subfield number.
+func unpackSubfields(config FieldConfig, data []byte, bitmapEncoding BitmapEncoding) (map[int][]byte, error) {
+	switch config.SubfieldFormat {
+	case SubfieldFixed:
+		return unpackFixedSubfields(config, data)
+	case SubfieldBitmap:
+		return unpackBitmapSubfields(config, data, bitmapEncoding)
+	case SubfieldTLV:
+		return unpackTLVSubfields(config, data)
+	default:
+		return nil, fmt.Errorf("unsupported subfield format %d", config.SubfieldFormat)
+	}
+}
+
+// packSubfields rebuilds a composite field's logical value from its subfields.
+func packSubfields(config FieldConfig, subfields map[int][]byte, bitmapEncoding BitmapEncoding) ([]byte, error) {
+	switch config.SubfieldFormat {
+	case SubfieldFixed:
+		return packFixedSubfields(config, subfields)
+	case SubfieldBitmap:
+		return packBitmapSubfields(config, subfields, bitmapEncoding)
+	case SubfieldTLV:
+		return packTLVSubfields(config, subfields)
+	default:
+		return nil, fmt.Errorf("unsupported subfield format %d", config.SubfieldFormat)
+	}
+}
+
+// subfieldNumbers returns the configured subfield numbers in ascending order.
+func subfieldNumbers(config FieldConfig) []int {
+	nums := make([]int, 0, len(config.Subfields))
+	for num := range config.Subfields {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// unpackFixedSubfields reads positional subfields in subfield-number order.
+// Trailing subfields beyond the end of data are treated as absent.
+func unpackFixedSubfields(config FieldConfig, data []byte) (map[int][]byte, error) {
+	subfields := make(map[int][]byte, len(config.Subfields))
+	offset := 0
+	for _, num := range subfieldNumbers(config) {
+		if offset >= len(data) {
+			break
+		}
+		value, next, err := readSubfield(config.Subfields[num], data, offset)
+		if err != nil {
+			return nil, &FieldError{Field: num, Err: err}
+		}
+		subfields[num] = value
+		offset = next
+	}
+	if offset < len(data) {
+		return nil, fmt.Errorf("%w: %d bytes after last subfield", ErrTrailingBytes, len(data)-offset)
+	}
+	return subfields, nil
+}
+
+// packFixedSubfields writes every configured subfield in order.
+// Missing fixed-length subfields are padded with zeros (numeric) or spaces.
+func packFixedSubfields(config FieldConfig, subfields map[int][]byte) ([]byte, error) {
+	var out []byte
+	for _, num := range subfieldNumbers(config) {
+		subConfig := config.Subfields[num]
+		value, present := subfields[num]
+		if !present && subConfig.Length == LengthFixed {
+			value = subfieldPadding(subConfig)
+		}
+		var err error
+		out, err = appendSubfield(out, subConfig, value)
+		if err != nil {
+			return nil, &FieldError{Field: num, Err: err}
+		}
+	}
+	return out, nil
+}
+
+// unpackBitmapSubfields reads an 8-byte sub-bitmap followed by the subfields it flags.
+func unpackBitmapSubfields(config FieldConfig, data []byte, bitmapEncoding BitmapEncoding) (map[int][]byte, error) {
+	var bitmap [BitmapSize]byte
+	offset := 0
+	if bitmapEncoding == BitmapEncodingHex {
+		if len(data) < BitmapSize*2 {
+			return nil, ErrInvalidBitmap
+		}
+		for i := 0; i < BitmapSize; i++ {
+			hi, ok1 := hexNibble(data[i*2])
+			lo, ok2 := hexNibble(data[i*2+1])
+			if !ok1 || !ok2 {
+				return nil, ErrInvalidBitmapHex
+			}
+			bitmap[i] = hi<<4 | lo
+		}
+		offset = BitmapSize * 2
+	} else {
+		if len(data) < BitmapSize {
+			return nil, ErrInvalidBitmap
+		}
+		copy(bitmap[:], data[:BitmapSize])
+		offset = BitmapSize
+	}
+
+	subfields := make(map[int][]byte)
+	for num := 1; num <= BitmapSize*8; num++ {
+		if bitmap[(num-1)/8]&(1<<(7-uint((num-1)%8))) == 0 {
+			continue
+		}
+		subConfig, exists := config.Subfields[num]
+		if !exists {
+			return nil, &FieldError{Field: num, Err: ErrFieldNotConfigured}
+		}
+		value, next, err := readSubfield(subConfig, data, offset)
+		if err != nil {
+			return nil, &FieldError{Field: num, Err: err}
+		}
+		subfields[num] = value
+		offset = next
+	}
+	if offset < len(data) {
+		return nil, fmt.Errorf("%w: %d bytes after last subfield", ErrTrailingBytes, len(data)-offset)
+	}
+	return subfields, nil
+}
+
+// packBitmapSubfields writes a sub-bitmap flagging the present subfields, then the subfields.
+func packBitmapSubfields(config FieldConfig, subfields map[int][]byte, bitmapEncoding BitmapEncoding) ([]byte, error) {
+	var bitmap [BitmapSize]byte
+	nums := make([]int, 0, len(subfields))
+	for num := range subfields {
+		if num < 1 || num > BitmapSize*8 {
+			return nil, &FieldError{Field: num, Err: ErrInvalidField}
+		}
+		bitmap[(num-1)/8] |= 1 << (7 - uint((num-1)%8))
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	var out []byte
+	if bitmapEncoding == BitmapEncodingHex {
+		out = make([]byte, BitmapSize*2)
+		encodeHexUpper(out, bitmap[:])
+	} else {
+		out = append(out, bitmap[:]...)
+	}
+
+	for _, num := range nums {
+		subConfig, exists := config.Subfields[num]
+		if !exists {
+			return nil, &FieldError{Field: num, Err: ErrFieldNotConfigured}
+		}
+		var err error
+		out, err = appendSubfield(out, subConfig, subfields[num])
+		if err != nil {
+			return nil, &FieldError{Field: num, Err: err}
+		}
+	}
+	return out, nil
+}
+
+// subfieldTLVParser returns the ASCII TLV parser for TLV-based subfields.
+// Tags are numeric subfield numbers; the default layout is a 2-digit tag
+// followed by a 2-digit decimal length.
+func subfieldTLVParser(config FieldConfig) *TLVParser {
+	tagLen, lenLen, base := 2, 2, 10
+	if config.TLV != nil && config.TLV.Type == TLVASCII {
+		if config.TLV.TagLength > 0 {
+			tagLen = config.TLV.TagLength
+		}
+		if config.TLV.LengthLength > 0 {
+			lenLen = config.TLV.LengthLength
+		}
+		if config.TLV.LengthBase > 0 {
+			base = config.TLV.LengthBase
+		}
+	}
+	return NewASCIITLVParser(tagLen, lenLen, base)
+}
+
+// unpackTLVSubfields reads subfields stored as ASCII TLV with numeric tags.
+func unpackTLVSubfields(config FieldConfig, data []byte) (map[int][]byte, error) {
+	tlvs, err := subfieldTLVParser(config).ParseTLV(data)
+	if err != nil {
+		return nil, err
+	}
+	subfields := make(map[int][]byte, len(tlvs))
+	for _, tlv := range tlvs {
+		num, err := strconv.Atoi(string(tlv.Tag))
+		if err != nil {
+			return nil, &TLVError{Tag: tlv.Tag, Err: fmt.Errorf("non-numeric subfield tag")}
+		}
+		subfields[num] = tlv.Value
+	}
+	return subfields, nil
+}
+
+// packTLVSubfields writes subfields as ASCII TLV in subfield-number order.
+func packTLVSubfields(config FieldConfig, subfields map[int][]byte) ([]byte, error) {
+	parser := subfieldTLVParser(config)
+	nums := make([]int, 0, len(subfields))
+	for num := range subfields {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	tlvs := make([]TLV, 0, len(nums))
+	for _, num := range nums {
+		tag := make([]byte, parser.asciiTagLen)
+		writeIntToASCII(tag, num, parser.asciiTagLen)
+		tlvs = append(tlvs, TLV{Tag: tag, Length: len(subfields[num]), Value: subfields[num]})
+	}
+	return encodeTLVs(parser, tlvs)
+}
+
+// readSubfield decodes one subfield at offset using the same length and
+// encoding rules as top-level fields.
+func readSubfield(config FieldConfig, data []byte, offset int) ([]byte, int, error) {
+	length, newOffset, err := calculateFieldLength(config, data, offset)
+	if err != nil {
+		return nil, offset, err
+	}
+	encoder, err := GetEncoder(config.Encoding)
+	if err != nil {
+		return nil, offset, err
+	}
+	wireLength := encoder.EncodedLen(length)
+	if len(data) < newOffset+wireLength {
+		return nil, offset, ErrInvalidLength
+	}
+	value, err := encoder.Decode(data[newOffset:newOffset+wireLength], length)
+	if err != nil {
+		return nil, offset, err
+	}
+	return value, newOffset + wireLength, nil
+}
+
+// appendSubfield encodes one subfield (length prefix and value) onto out.
+func appendSubfield(out []byte, config FieldConfig, value []byte) ([]byte, error) {
+	encoder, err := GetEncoder(config.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	var prefix [4]byte
+	prefixLen := 0
+	if digits := lengthPrefixDigits(config.Length); digits > 0 {
+		prefixLen, err = writeLengthPrefix(prefix[:], len(value), digits, config.LengthEncoding)
+		if err != nil {
+			return nil, err
+		}
+	} else if len(value) != config.MaxLength {
+		return nil, fmt.Errorf("fixed subfield length mismatch: expected %d, got %d", config.MaxLength, len(value))
+	}
+
+	start := len(out)
+	out = append(out, prefix[:prefixLen]...)
+	out = append(out, make([]byte, encoder.EncodedLen(len(value)))...)
+	n, err := encoder.Encode(out[start+prefixLen:], value)
+	if err != nil {
+		return nil, err
+	}
+	return out[:start+prefixLen+n], nil
+}
+
+// subfieldPadding returns the filler for an absent fixed-length subfield.
+func subfieldPadding(config FieldConfig) []byte {
+	pad := byte(' ')
+	if config.Type == FieldTypeN {
+		pad = '0'
+	}
+	padding := make([]byte, config.MaxLength)
+	for i := range padding {
+		padding[i] = pad
+	}
+	return padding
+}
+
+// --- Message subfield accessors ---
+
+// decodeSubfields splits every present composite field into m.subfields.
+// It's called by Unpack.
+func (m *Message) decodeSubfields() error {
+	if m.packager == nil {
+		return nil
+	}
+	for _, fieldNum := range m.packager.compositeFields {
+		if !m.isFieldPresent(fieldNum) {
+			continue
+		}
+		config := m.packager.fieldConfigs[fieldNum]
+		subfields, err := unpackSubfields(config, m.fields[fieldNum-1].Bytes(), m.packager.bitmapEncoding)
+		if err != nil {
+			if m.validationLevel == ValidationNone {
+				continue
+			}
+			if m.validationMode == ValidationCollectAll {
+				m.report.add(fieldNum, err)
+				continue
+			}
+			m.lastError.Field = fieldNum
+			m.lastError.Err = err
+			return &m.lastError
+		}
+		if m.subfields == nil {
+			m.subfields = make(map[int]map[int][]byte)
+		}
+		m.subfields[fieldNum] = subfields
+	}
+	return nil
+}
+
+// GetSubfield returns a subfield of a composite field as a string,
+// e.g. GetSubfield(90, 2) for the original STAN.
+func (m *Message) GetSubfield(fieldNum, subfieldNum int) (string, error) {
+	value, err := m.GetSubfieldBytes(fieldNum, subfieldNum)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// GetSubfieldBytes returns a subfield of a composite field as a byte slice.
+func (m *Message) GetSubfieldBytes(fieldNum, subfieldNum int) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	subfields, err := m.subfieldsOf(fieldNum)
+	if err != nil {
+		return nil, err
+	}
+	if subfields == nil {
+		return nil, ErrFieldNotFound
+	}
+	value, exists := subfields[subfieldNum]
+	if !exists {
+		return nil, &FieldError{Field: fieldNum, Err: fmt.Errorf("subfield %d: %w", subfieldNum, ErrFieldNotFound)}
+	}
+	return value, nil
+}
+
+// GetSubfields returns all present subfields of a composite field, keyed by subfield number.
+func (m *Message) GetSubfields(fieldNum int) (map[int][]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	subfields, err := m.subfieldsOf(fieldNum)
+	if err != nil {
+		return nil, err
+	}
+	if subfields == nil {
+		return nil, ErrFieldNotFound
+	}
+	result := make(map[int][]byte, len(subfields))
+	for num, value := range subfields {
+		result[num] = value
+	}
+	return result, nil
+}
+
+// subfieldsOf returns the subfields of a composite field, decoding its raw
+// value if it was set with SetField, or nil if the field is absent.
+// Callers must hold m.mu.
+func (m *Message) subfieldsOf(fieldNum int) (map[int][]byte, error) {
+	if subfields, exists := m.subfields[fieldNum]; exists {
+		return subfields, nil
+	}
+	if m.packager == nil || !m.isFieldPresent(fieldNum) {
+		return nil, nil
+	}
+	config := m.packager.fieldConfigs[fieldNum]
+	if len(config.Subfields) == 0 {
+		return nil, nil
+	}
+	subfields, err := unpackSubfields(config, m.fields[fieldNum-1].Bytes(), m.packager.bitmapEncoding)
+	if err != nil {
+		return nil, &FieldError{Field: fieldNum, Err: err}
+	}
+	return subfields, nil
+}
+
+// SetSubfield sets a subfield of a composite field. The parent field is
+// marked present and rebuilt from its subfields on the next Pack; the other
+// subfields of a value set with SetField are kept.
+func (m *Message) SetSubfield(fieldNum, subfieldNum int, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.packager == nil {
+		return ErrNoPackagerConfigured
+	}
+	config, exists := m.packager.fieldConfigs[fieldNum]
+	if !exists || len(config.Subfields) == 0 {
+		return &FieldError{Field: fieldNum, Err: ErrFieldNotComposite}
+	}
+	if _, exists := config.Subfields[subfieldNum]; !exists {
+		return &FieldError{Field: fieldNum, Err: fmt.Errorf("subfield %d: %w", subfieldNum, ErrFieldNotConfigured)}
+	}
+
+	subfields, err := m.subfieldsOf(fieldNum)
+	if err != nil {
+		return err
+	}
+	if subfields == nil {
+		subfields = make(map[int][]byte)
+	}
+	subfields[subfieldNum] = []byte(value)
+
+	if m.subfields == nil {
+		m.subfields = make(map[int]map[int][]byte)
+	}
+	m.subfields[fieldNum] = subfields
+
+	if m.subfieldModified == nil {
+		m.subfieldModified = make(map[int]bool)
+	}
+	m.subfieldModified[fieldNum] = true
+
+	field := &m.fields[fieldNum-1]
+	if !field.parsed {
+		field.fieldType = config.Type
+		field.parsed = true
+	}
+	m.setFieldPresent(fieldNum)
+	m.bitmap.SetField(fieldNum)
+	return nil
+}
+
+// dropSubfields discards decoded and modified subfields for a field.
+func (m *Message) dropSubfields(fieldNum int) {
+	delete(m.subfields, fieldNum)
+	delete(m.subfieldModified, fieldNum)
+}
+
+// encodedCompositeField returns the rebuilt raw value of a modified composite field.
+// ok is false if no subfield has been changed through SetSubfield.
+func (m *Message) encodedCompositeField(packager *CompiledPackager, fieldNum int) ([]byte, bool, error) {
+	if !m.subfieldModified[fieldNum] {
+		return nil, false, nil
+	}
+	config := packager.fieldConfigs[fieldNum]
+	data, err := packSubfields(config, m.subfields[fieldNum], packager.bitmapEncoding)
+	return data, true, err
+}
@@ -0,0 +1,126 @@
+package iso8583
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// newCompositePackager configures DE 90 with fixed subfields, DE 48 with TLV
+// subfields and DE 127 with bitmap subfields.
+func newCompositePackager() *CompiledPackager {
+	return NewCompiledPackager(newTestConfig(
+		WithFieldConfig(90, FieldConfig{Type: FieldTypeN, Length: LengthFixed, MaxLength: 42, SubfieldFormat: SubfieldFixed,
+			Subfields: map[int]FieldConfig{
+				1: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4},  // Original MTI
+				2: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 6},  // Original STAN
+				3: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 10}, // Original transmission date and time
+				4: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 11}, // Original acquirer ID
+				5: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 11}, // Original forwarder ID
+			}}),
+		WithFieldConfig(48, FieldConfig{Type: FieldTypeANS, Length: LengthLLLVAR, MaxLength: 999, SubfieldFormat: SubfieldTLV,
+			Subfields: map[int]FieldConfig{
+				1:  {Type: FieldTypeANS, Length: LengthLLVAR, MaxLength: 99},
+				42: {Type: FieldTypeANS, Length: LengthLLVAR, MaxLength: 99},
+			}}),
+		WithFieldConfig(127, FieldConfig{Type: FieldTypeANS, Length: LengthLLLVAR, MaxLength: 999, SubfieldFormat: SubfieldBitmap,
+			Subfields: map[int]FieldConfig{
+				2:  {Type: FieldTypeANS, Length: LengthLLVAR, MaxLength: 32},
+				12: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4},
+			}}),
+	))
+}
+
+func TestSubfieldRoundTrip(t *testing.T) {
+	pkg := newCompositePackager()
+	m := NewMessage(WithPackager(pkg))
+	m.SetMTI([]byte("0400"))
+
+	sets := []struct {
+		field, subfield int
+		value           string
+	}{
+		{90, 1, "0200"}, {90, 2, "000123"},
+		{48, 42, "ABC"}, {48, 1, "X"},
+		{127, 12, "0042"}, {127, 2, "route"},
+	}
+	for _, s := range sets {
+		if err := m.SetSubfield(s.field, s.subfield, s.value); err != nil {
+			t.Fatalf("SetSubfield(%d, %d): %v", s.field, s.subfield, err)
+		}
+	}
+
+	got := repack(t, m)
+	for _, s := range sets {
+		if v, err := got.GetSubfield(s.field, s.subfield); err != nil || v != s.value {
+			t.Errorf("DE %d.%d = %q, %v, want %q", s.field, s.subfield, v, err, s.value)
+		}
+	}
+	// Absent fixed subfields are padded
+	if v, _ := got.GetSubfield(90, 4); v != "00000000000" {
+		t.Errorf("DE 90.4 = %q, want zero padding", v)
+	}
+	if raw, _ := got.GetString(48); raw != "0101X4203ABC" {
+		t.Errorf("DE 48 = %q", raw)
+	}
+	subfields, _ := got.GetSubfields(127)
+	if want := map[int][]byte{2: []byte("route"), 12: []byte("0042")}; !reflect.DeepEqual(subfields, want) {
+		t.Errorf("DE 127 subfields = %q, want %q", subfields, want)
+	}
+}
+
+func TestSetSubfieldKeepsRawValue(t *testing.T) {
+	pkg := newCompositePackager()
+	m := NewMessage(WithPackager(pkg))
+	m.SetMTI([]byte("0400"))
+	if err := m.SetField(90, "020000012310161200000000000123400000000000"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A field set with SetField is decoded on access
+	if v, err := m.GetSubfield(90, 2); err != nil || v != "000123" {
+		t.Errorf("DE 90.2 = %q, %v", v, err)
+	}
+
+	if err := m.SetSubfield(90, 2, "000999"); err != nil {
+		t.Fatal(err)
+	}
+	got := repack(t, m)
+	if raw, _ := got.GetString(90); raw != "020000099910161200000000000123400000000000" {
+		t.Errorf("DE 90 = %q, other subfields not kept", raw)
+	}
+}
+
+func TestSubfieldErrors(t *testing.T) {
+	pkg := newCompositePackager()
+	m := NewMessage(WithPackager(pkg))
+	m.SetMTI([]byte("0400"))
+
+	if err := m.SetSubfield(4, 1, "1"); !errors.Is(err, ErrFieldNotComposite) {
+		t.Errorf("SetSubfield on a plain field = %v, want ErrFieldNotComposite", err)
+	}
+	if err := m.SetSubfield(90, 9, "1"); !errors.Is(err, ErrFieldNotConfigured) {
+		t.Errorf("SetSubfield on an unknown subfield = %v, want ErrFieldNotConfigured", err)
+	}
+	if _, err := m.GetSubfield(90, 1); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("GetSubfield on an absent field = %v, want ErrFieldNotFound", err)
+	}
+
+	m.SetSubfield(90, 1, "0200")
+	if _, err := m.GetSubfield(90, 2); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("GetSubfield on an unset subfield = %v, want ErrFieldNotFound", err)
+	}
+
+	// An undecodable raw value is reported rather than replaced
+	m.SetField(90, "02000001")
+	if err := m.SetSubfield(90, 2, "000123"); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("SetSubfield over a short raw value = %v, want ErrInvalidLength", err)
+	}
+
+	if _, err := unpackSubfields(pkg.fieldConfigs[127], []byte("0000000000000001"), BitmapEncodingHex); !errors.Is(err, ErrFieldNotConfigured) {
+		t.Errorf("sub-bitmap flagging an unconfigured subfield = %v, want ErrFieldNotConfigured", err)
+	}
+	if _, err := unpackSubfields(pkg.fieldConfigs[48], []byte("0105X"), BitmapEncodingHex); err == nil {
+		t.Error("truncated TLV subfield accepted")
+	}
+}
@@ -8,6 +8,31 @@ const (
 	MTI_NMM_REQUEST       = "0800"
 	MTI_NMM_RESPONSE      = "0810"
 
+	// ISO 8583:1993 MTIs
+	MTI_1993_AUTH_REQUEST       = "1100"
+	MTI_1993_AUTH_RESPONSE      = "1110"
+	MTI_1993_FINANCIAL_REQUEST  = "1200"
+	MTI_1993_FINANCIAL_RESPONSE = "1210"
+	MTI_1993_REVERSAL_ADVICE    = "1420"
+	MTI_1993_REVERSAL_RESPONSE  = "1430"
+	MTI_1993_NMM_REQUEST        = "1804"
+	MTI_1993_NMM_RESPONSE       = "1814"
+
+	// Function code constants (DE 24, ISO 8583:1993 and 2003)
+	FC_AUTH_AMOUNT_ACCURATE      = "100"
+	FC_AUTH_AMOUNT_ESTIMATED     = "101"
+	FC_FINANCIAL_ORIGINAL        = "200"
+	FC_FINANCIAL_PREAUTH         = "201"
+	FC_FINANCIAL_PREAUTH_DIFF    = "202"
+	FC_REVERSAL_FULL             = "400"
+	FC_REVERSAL_PARTIAL          = "401"
+	FC_RECONCILIATION_FINAL      = "500"
+	FC_RECONCILIATION_CHECKPOINT = "501"
+	FC_SIGN_ON                   = "801"
+	FC_SIGN_OFF                  = "802"
+	FC_KEY_CHANGE                = "811"
+	FC_ECHO_TEST                 = "831"
+
 	// Response code constants
 	RC_APPROVED             = "00"
 	RC_REFER_TO_CARD_ISSUER = "01"
@@ -25,20 +50,20 @@ var DefaultConfigField = map[int]FieldConfig{
 
 	2:  {Type: FieldTypeN, Length: LengthLLVAR, MaxLength: 19, Mandatory: false},
 	3:  {Type: FieldTypeN, Length: LengthFixed, MaxLength: 6, Mandatory: true},
-	4:  {Type: FieldTypeN, Length: LengthFixed, MaxLength: 12, Mandatory: true},
-	5:  {Type: FieldTypeN, Length: LengthFixed, MaxLength: 12, Mandatory: false},
-	6:  {Type: FieldTypeN, Length: LengthFixed, MaxLength: 12, Mandatory: false},
-	7:  {Type: FieldTypeN, Length: LengthFixed, MaxLength: 10, Mandatory: true},
+	4:  {Type: FieldTypeN, Length: LengthFixed, MaxLength: 12, Mandatory: true, Format: "amount"},
+	5:  {Type: FieldTypeN, Length: LengthFixed, MaxLength: 12, Mandatory: false, Format: "amount"},
+	6:  {Type: FieldTypeN, Length: LengthFixed, MaxLength: 12, Mandatory: false, Format: "amount"},
+	7:  {Type: FieldTypeN, Length: LengthFixed, MaxLength: 10, Mandatory: true, Format: "MMDDhhmmss"},
 	8:  {Type: FieldTypeN, Length: LengthFixed, MaxLength: 8, Mandatory: false},
 	9:  {Type: FieldTypeN, Length: LengthFixed, MaxLength: 8, Mandatory: false},
 	10: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 8, Mandatory: false},
 	11: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 6, Mandatory: true},
-	12: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 6, Mandatory: true},
-	13: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: true},
-	14: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false},
-	15: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false},
-	16: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false},
-	17: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false},
+	12: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 6, Mandatory: true, Format: "hhmmss"},
+	13: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: true, Format: "MMDD"},
+	14: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false, Format: "YYMM"},
+	15: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false, Format: "MMDD"},
+	16: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false, Format: "MMDD"},
+	17: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false, Format: "MMDD"},
 	18: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false},
 	19: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false},
 	20: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false},
@@ -75,7 +100,7 @@ var DefaultConfigField = map[int]FieldConfig{
 	51: {Type: FieldTypeANS, Length: LengthFixed, MaxLength: 3, Mandatory: false},
 	52: {Type: FieldTypeB, Length: LengthFixed, MaxLength: 16, Mandatory: false},
 	53: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 16, Mandatory: false},
-	54: {Type: FieldTypeANS, Length: LengthLLLVAR, MaxLength: 120, Mandatory: false},
+	54: {Type: FieldTypeANS, Length: LengthLLLVAR, MaxLength: 120, Mandatory: false, Format: "additional_amounts"},
 	55: {Type: FieldTypeB, Length: LengthLLLVAR, MaxLength: 999, Mandatory: false},
 	56: {Type: FieldTypeANS, Length: LengthLLLVAR, MaxLength: 999, Mandatory: false},
 	57: {Type: FieldTypeANS, Length: LengthLLLVAR, MaxLength: 999, Mandatory: false},
@@ -0,0 +1,564 @@
+package iso8583
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CrossFieldRuleConfig declares a rule over several fields of a message in
+// PackagerConfig.CrossFieldRules: whenever When holds, Require must hold too.
+//
+// Conditions are written in a small expression language:
+//
+//	present(DE14)            field is present
+//	DE22[0:2] == "01"        field value (or a slice of it) compared to a literal
+//	pan(DE35) == DE2         PAN extracted from track data
+//	MTI[1] == "2"            MTI digits
+//	not / and / or, ! / && / ||, parentheses
+//
+// Fields are referenced as DEn or by their configured alias. A bare value
+// such as DE14 holds when the field is present and not empty; comparisons
+// involving an absent field never hold. Binary fields compare as upper-case hex.
+type CrossFieldRuleConfig struct {
+	Name    string `json:"name"`
+	MTI     string `json:"mti,omitempty"`     // Only applies to messages matching this MTI or pattern (e.g., "02x0")
+	When    string `json:"when,omitempty"`    // Condition; empty means always
+	Require string `json:"require"`           // Condition that must hold when When does
+	Message string `json:"message,omitempty"` // Error message; derived from the expressions if empty
+}
+
+// crossFieldRule is a compiled CrossFieldRuleConfig.
+type crossFieldRule struct {
+	name    string
+	pattern string
+	when    condExpr // nil means always
+	require condExpr
+	message string
+	fields  []int
+}
+
+// NewCrossFieldRule compiles a declarative cross-field rule. aliases maps
+// field aliases usable in the expressions to field numbers (may be nil).
+func NewCrossFieldRule(config CrossFieldRuleConfig, aliases map[string]int) (MessageRule, error) {
+	rule := &crossFieldRule{
+		name:    config.Name,
+		pattern: config.MTI,
+		message: config.Message,
+	}
+	if rule.name == "" {
+		rule.name = "cross_field"
+	}
+
+	var err error
+	if rule.require, err = parseCondition(config.Require, aliases); err != nil {
+		return nil, fmt.Errorf("rule %s: require: %w", rule.name, err)
+	}
+	rule.require.fields(&rule.fields)
+	if strings.TrimSpace(config.When) != "" {
+		if rule.when, err = parseCondition(config.When, aliases); err != nil {
+			return nil, fmt.Errorf("rule %s: when: %w", rule.name, err)
+		}
+		rule.when.fields(&rule.fields)
+	}
+
+	if rule.message == "" {
+		rule.message = fmt.Sprintf("requires %s", strings.TrimSpace(config.Require))
+		if rule.when != nil {
+			rule.message += fmt.Sprintf(" when %s", strings.TrimSpace(config.When))
+		}
+	}
+	return rule, nil
+}
+
+// Name returns the rule name.
+func (r *crossFieldRule) Name() string {
+	return r.name
+}
+
+// Fields returns the fields referenced by the rule, required fields first.
+func (r *crossFieldRule) Fields() []int {
+	return r.fields
+}
+
+// Validate checks the rule against the message.
+func (r *crossFieldRule) Validate(msg *Message) error {
+	if r.pattern != "" && !msg.GetMTI().Matches(r.pattern) {
+		return nil
+	}
+	if r.when != nil && !r.when.eval(msg) {
+		return nil
+	}
+	if r.require.eval(msg) {
+		return nil
+	}
+	return fmt.Errorf("%s", r.message)
+}
+
+// invalidRule stands in for a configured rule that failed to compile, so
+// the misconfiguration is reported on validation instead of ignored.
+type invalidRule struct {
+	name   string
+	err    error
+	fields []int // Fields the misconfigured rule applies to, if any
+}
+
+// Name returns the rule name.
+func (r *invalidRule) Name() string {
+	return r.name
+}
+
+// Fields returns the fields the misconfigured rule applies to.
+func (r *invalidRule) Fields() []int {
+	return r.fields
+}
+
+// Validate always returns the compile error.
+func (r *invalidRule) Validate(msg *Message) error {
+	return r.err
+}
+
+// compileCrossFieldRules compiles the configured cross-field rules.
+func compileCrossFieldRules(config *PackagerConfig) []MessageRule {
+	if len(config.CrossFieldRules) == 0 {
+		return nil
+	}
+	aliases := fieldAliases(config.Fields)
+	rules := make([]MessageRule, 0, len(config.CrossFieldRules))
+	for _, ruleConfig := range config.CrossFieldRules {
+		rule, err := NewCrossFieldRule(ruleConfig, aliases)
+		if err != nil {
+			rule = &invalidRule{name: ruleConfig.Name, err: err}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// fieldAliases maps the aliases of configured fields to field numbers.
+func fieldAliases(fields map[int]FieldConfig) map[string]int {
+	aliases := make(map[string]int)
+	for fieldNum, fieldConfig := range fields {
+		if fieldConfig.Alias != "" {
+			aliases[fieldConfig.Alias] = fieldNum
+		}
+	}
+	return aliases
+}
+
+// --- Condition expressions ---
+
+// condExpr is a boolean condition over a message.
+type condExpr interface {
+	eval(msg *Message) bool
+	fields(dst *[]int)
+}
+
+// valueExpr is a string value taken from a message; ok is false if the
+// value is absent.
+type valueExpr interface {
+	value(msg *Message) (string, bool)
+	fields(dst *[]int)
+}
+
+type andExpr struct{ left, right condExpr }
+
+func (e *andExpr) eval(msg *Message) bool { return e.left.eval(msg) && e.right.eval(msg) }
+func (e *andExpr) fields(dst *[]int)      { e.left.fields(dst); e.right.fields(dst) }
+
+type orExpr struct{ left, right condExpr }
+
+func (e *orExpr) eval(msg *Message) bool { return e.left.eval(msg) || e.right.eval(msg) }
+func (e *orExpr) fields(dst *[]int)      { e.left.fields(dst); e.right.fields(dst) }
+
+type notExpr struct{ expr condExpr }
+
+func (e *notExpr) eval(msg *Message) bool { return !e.expr.eval(msg) }
+func (e *notExpr) fields(dst *[]int)      { e.expr.fields(dst) }
+
+// presentExpr holds when a field is present.
+type presentExpr struct{ field int }
+
+func (e *presentExpr) eval(msg *Message) bool { return msg.HasField(e.field) }
+func (e *presentExpr) fields(dst *[]int)      { addField(dst, e.field) }
+
+// truthyExpr holds when a value is present and not empty.
+type truthyExpr struct{ value valueExpr }
+
+func (e *truthyExpr) eval(msg *Message) bool {
+	v, ok := e.value.value(msg)
+	return ok && v != ""
+}
+func (e *truthyExpr) fields(dst *[]int) { e.value.fields(dst) }
+
+// compareExpr compares two values; it never holds if either is absent.
+type compareExpr struct {
+	equal       bool
+	left, right valueExpr
+}
+
+func (e *compareExpr) eval(msg *Message) bool {
+	l, ok := e.left.value(msg)
+	if !ok {
+		return false
+	}
+	r, ok := e.right.value(msg)
+	if !ok {
+		return false
+	}
+	return (l == r) == e.equal
+}
+func (e *compareExpr) fields(dst *[]int) { e.left.fields(dst); e.right.fields(dst) }
+
+type literalValue struct{ s string }
+
+func (v *literalValue) value(msg *Message) (string, bool) { return v.s, true }
+func (v *literalValue) fields(dst *[]int)                 {}
+
+// valueSlice bounds a value to [from:to]; to < 0 means the end.
+type valueSlice struct{ from, to int }
+
+func (s valueSlice) apply(v string) (string, bool) {
+	to := s.to
+	if to < 0 {
+		to = len(v)
+	}
+	if s.from > len(v) || to > len(v) || s.from > to {
+		return "", false
+	}
+	return v[s.from:to], true
+}
+
+type fieldValue struct {
+	field int
+	slice valueSlice
+}
+
+func (v *fieldValue) value(msg *Message) (string, bool) {
+	field, err := msg.GetField(v.field)
+	if err != nil {
+		return "", false
+	}
+	config, _ := msg.fieldConfig(v.field)
+	return v.slice.apply(displayValue(config, field.Bytes()))
+}
+func (v *fieldValue) fields(dst *[]int) { addField(dst, v.field) }
+
+type mtiValue struct{ slice valueSlice }
+
+func (v *mtiValue) value(msg *Message) (string, bool) { return v.slice.apply(msg.GetMTI().String()) }
+func (v *mtiValue) fields(dst *[]int)                 {}
+
+// panValue extracts the PAN from track 1 or track 2 data.
+type panValue struct{ field int }
+
+func (v *panValue) value(msg *Message) (string, bool) {
+	field, err := msg.GetField(v.field)
+	if err != nil {
+		return "", false
+	}
+	pan, _, err := parseTrack(field.String())
+	return pan, err == nil
+}
+func (v *panValue) fields(dst *[]int) { addField(dst, v.field) }
+
+// addField appends fieldNum to dst unless it is already there.
+func addField(dst *[]int, fieldNum int) {
+	for _, f := range *dst {
+		if f == fieldNum {
+			return
+		}
+	}
+	*dst = append(*dst, fieldNum)
+}
+
+// --- Condition parser ---
+
+type condTokenKind int
+
+const (
+	condEOF condTokenKind = iota
+	condIdent
+	condString
+	condNumber
+	condOp
+)
+
+type condToken struct {
+	kind condTokenKind
+	text string
+	pos  int
+}
+
+// tokenizeCondition splits a condition into tokens.
+func tokenizeCondition(src string) ([]condToken, error) {
+	var tokens []condToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated string at %d", ErrInvalidRule, i)
+			}
+			tokens = append(tokens, condToken{kind: condString, text: src[i+1 : i+1+end], pos: i})
+			i += end + 2
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+				i++
+			}
+			tokens = append(tokens, condToken{kind: condNumber, text: src[start:i], pos: start})
+		case c == '_' || (c|0x20 >= 'a' && c|0x20 <= 'z'):
+			start := i
+			for i < len(src) && (src[i] == '_' || (src[i]|0x20 >= 'a' && src[i]|0x20 <= 'z') || (src[i] >= '0' && src[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, condToken{kind: condIdent, text: src[start:i], pos: start})
+		default:
+			if i+1 < len(src) {
+				if op := src[i : i+2]; op == "==" || op == "!=" || op == "&&" || op == "||" {
+					tokens = append(tokens, condToken{kind: condOp, text: op, pos: i})
+					i += 2
+					continue
+				}
+			}
+			if strings.IndexByte("!()[]:", c) < 0 {
+				return nil, fmt.Errorf("%w: unexpected %q at %d", ErrInvalidRule, c, i)
+			}
+			tokens = append(tokens, condToken{kind: condOp, text: string(c), pos: i})
+			i++
+		}
+	}
+	return append(tokens, condToken{kind: condEOF, pos: len(src)}), nil
+}
+
+type condParser struct {
+	tokens  []condToken
+	pos     int
+	aliases map[string]int
+}
+
+// parseCondition compiles a condition expression.
+func parseCondition(src string, aliases map[string]int) (condExpr, error) {
+	tokens, err := tokenizeCondition(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &condParser{tokens: tokens, aliases: aliases}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != condEOF {
+		return nil, p.errorf(tok, "unexpected %q", tok.text)
+	}
+	return expr, nil
+}
+
+func (p *condParser) peek() condToken {
+	return p.tokens[p.pos]
+}
+
+func (p *condParser) next() condToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != condEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the given operators or
+// (case-insensitive) keywords.
+func (p *condParser) accept(texts ...string) bool {
+	tok := p.peek()
+	if tok.kind != condOp && tok.kind != condIdent {
+		return false
+	}
+	for _, text := range texts {
+		if strings.EqualFold(tok.text, text) {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *condParser) expect(op string) error {
+	if tok := p.next(); tok.kind != condOp || tok.text != op {
+		return p.errorf(tok, "expected %q", op)
+	}
+	return nil
+}
+
+func (p *condParser) errorf(tok condToken, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s at %d", ErrInvalidRule, fmt.Sprintf(format, args...), tok.pos)
+}
+
+func (p *condParser) parseOr() (condExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||", "or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *condParser) parseAnd() (condExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&", "and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *condParser) parseNot() (condExpr, error) {
+	if p.accept("!", "not") {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *condParser) parsePrimary() (condExpr, error) {
+	if p.accept("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	}
+
+	if tok := p.peek(); tok.kind == condIdent && strings.EqualFold(tok.text, "present") {
+		p.next()
+		field, err := p.parseFieldArg()
+		if err != nil {
+			return nil, err
+		}
+		return &presentExpr{field}, nil
+	}
+
+	left, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind == condOp && (tok.text == "==" || tok.text == "!=") {
+		p.next()
+		right, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return &compareExpr{equal: tok.text == "==", left: left, right: right}, nil
+	}
+	return &truthyExpr{left}, nil
+}
+
+func (p *condParser) parseValue() (valueExpr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case condString, condNumber:
+		return &literalValue{tok.text}, nil
+	case condIdent:
+		switch {
+		case strings.EqualFold(tok.text, "pan") && p.peek().text == "(":
+			field, err := p.parseFieldArg()
+			if err != nil {
+				return nil, err
+			}
+			return &panValue{field}, nil
+		case strings.EqualFold(tok.text, "mti"):
+			slice, err := p.parseSlice()
+			if err != nil {
+				return nil, err
+			}
+			return &mtiValue{slice}, nil
+		}
+		field, err := p.resolveField(tok)
+		if err != nil {
+			return nil, err
+		}
+		slice, err := p.parseSlice()
+		if err != nil {
+			return nil, err
+		}
+		return &fieldValue{field: field, slice: slice}, nil
+	}
+	return nil, p.errorf(tok, "expected a value")
+}
+
+// parseFieldArg parses "(field)".
+func (p *condParser) parseFieldArg() (int, error) {
+	if err := p.expect("("); err != nil {
+		return 0, err
+	}
+	tok := p.next()
+	if tok.kind != condIdent {
+		return 0, p.errorf(tok, "expected a field")
+	}
+	field, err := p.resolveField(tok)
+	if err != nil {
+		return 0, err
+	}
+	return field, p.expect(")")
+}
+
+// resolveField resolves DEn or a field alias.
+func (p *condParser) resolveField(tok condToken) (int, error) {
+	if len(tok.text) > 2 && strings.EqualFold(tok.text[:2], "DE") {
+		if n, err := strconv.Atoi(tok.text[2:]); err == nil && n >= 1 && n <= MaxFieldNumber {
+			return n, nil
+		}
+	}
+	if n, ok := p.aliases[tok.text]; ok {
+		return n, nil
+	}
+	return 0, p.errorf(tok, "unknown field %q", tok.text)
+}
+
+// parseSlice parses an optional [i], [i:j], [i:] or [:j].
+func (p *condParser) parseSlice() (valueSlice, error) {
+	slice := valueSlice{from: 0, to: -1}
+	if !p.accept("[") {
+		return slice, nil
+	}
+
+	bound := func() (int, bool) {
+		if tok := p.peek(); tok.kind == condNumber {
+			p.next()
+			n, _ := strconv.Atoi(tok.text)
+			return n, true
+		}
+		return 0, false
+	}
+
+	from, hasFrom := bound()
+	slice.from = from
+	if p.accept(":") {
+		if to, ok := bound(); ok {
+			slice.to = to
+		}
+	} else if hasFrom {
+		slice.to = from + 1
+	} else {
+		return slice, p.errorf(p.peek(), "expected an index")
+	}
+	return slice, p.expect("]")
+}
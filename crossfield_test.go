@@ -0,0 +1,164 @@
+package iso8583
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newCrossFieldTestMessage returns an 0200 with DE 2, 3, 22, 35 and 41 set
+// and DE 14 absent.
+func newCrossFieldTestMessage(t *testing.T) *Message {
+	t.Helper()
+	m := NewMessage(WithPackager(NewCompiledPackager(NewPackagerConfig())))
+	if err := m.SetMTI([]byte("0200")); err != nil {
+		t.Fatal(err)
+	}
+	for fieldNum, value := range map[int]string{
+		2:  "4111111111111111",
+		3:  "003000",
+		22: "051",
+		35: "4111111111111111=25121010000000000",
+		41: "TERM0001",
+	} {
+		if err := m.SetField(fieldNum, value); err != nil {
+			t.Fatalf("SetField(%d): %v", fieldNum, err)
+		}
+	}
+	return m
+}
+
+func TestConditionEval(t *testing.T) {
+	m := newCrossFieldTestMessage(t)
+	aliases := map[string]int{"terminal": 41}
+
+	tests := []struct {
+		name string
+		cond string
+		want bool
+	}{
+		// Precedence: not binds tighter than and, and tighter than or
+		{"not before and", "not present(DE2) and present(DE14)", false},
+		{"and before or", "present(DE2) or present(DE14) and present(DE14)", true},
+		{"parentheses", "(present(DE2) or present(DE14)) and present(DE14)", false},
+		{"double not", "not not present(DE2)", true},
+		{"symbolic operators", "!present(DE14) && DE22 == '051' || present(DE14)", true},
+		{"keywords ignore case", "NOT present(DE14) AND present(DE2)", true},
+
+		// Slices
+		{"index", `DE22[0] == "0"`, true},
+		{"from", `DE22[1:] == "51"`, true},
+		{"to", `DE22[:2] == "05"`, true},
+		{"from to", `DE22[0:3] == "051"`, true},
+		{"empty tail", `DE22[3:] == ""`, true},
+		{"index out of range", `DE22[3] == ""`, false},
+		{"from out of range", `DE22[5:] != "x"`, false},
+		{"to out of range", `DE22[:4] != "x"`, false},
+		{"inverted bounds", `DE22[2:1] != "x"`, false},
+		{"mti digit", `MTI[1] == "2"`, true},
+		{"mti prefix", `MTI[0:2] == "02"`, true},
+
+		// Absent fields
+		{"absent equal", `DE14 == "2512"`, false},
+		{"absent not equal", `DE14 != "2512"`, false},
+		{"absent both sides", "DE14 == DE14", false},
+		{"absent truthy", "DE14", false},
+		{"absent negated", "not DE14", true},
+		{"present truthy", "DE2", true},
+
+		// Field references
+		{"alias", `terminal == "TERM0001"`, true},
+		{"alias present", "present(terminal)", true},
+		{"field to field", "DE2 != DE3", true},
+		{"number literal", "DE3 == 003000", true},
+		{"pan from track", "pan(DE35) == DE2", true},
+		{"pan from absent track", "pan(DE45) == DE2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := parseCondition(tt.cond, aliases)
+			if err != nil {
+				t.Fatalf("parseCondition(%q): %v", tt.cond, err)
+			}
+			if got := expr.eval(m); got != tt.want {
+				t.Errorf("%q = %v, want %v", tt.cond, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionErrors(t *testing.T) {
+	tests := []struct {
+		cond string
+		want string // Error suffix, including the position
+	}{
+		{`DE22 == `, "expected a value at 8"},
+		{`present(DE2`, `expected ")" at 11`},
+		{`present("DE2")`, "expected a field at 8"},
+		{`DE999 == "1"`, `unknown field "DE999" at 0`},
+		{`terminal == "1"`, `unknown field "terminal" at 0`},
+		{`DE2 = "1"`, `unexpected '=' at 4`},
+		{`DE2 == "abc`, "unterminated string at 7"},
+		{`DE22[]`, "expected an index at 5"},
+		{`DE22[1:2`, `expected "]" at 8`},
+		{`DE2 DE3`, `unexpected "DE3" at 4`},
+		{`(present(DE2)`, `expected ")" at 13`},
+		{``, "expected a value at 0"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseCondition(tt.cond, nil)
+		if !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("parseCondition(%q) = %v, want ErrInvalidRule", tt.cond, err)
+			continue
+		}
+		if !strings.HasSuffix(err.Error(), tt.want) {
+			t.Errorf("parseCondition(%q) = %q, want suffix %q", tt.cond, err, tt.want)
+		}
+	}
+}
+
+func TestCrossFieldRule(t *testing.T) {
+	m := newCrossFieldTestMessage(t)
+
+	rule, err := NewCrossFieldRule(CrossFieldRuleConfig{
+		Name:    "chip_expiry",
+		MTI:     "02x0",
+		When:    `DE22[0:2] == "05"`,
+		Require: "present(DE14)",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rule.Fields(), []int{14, 22}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields() = %v, want %v", got, want)
+	}
+	err = rule.Validate(m)
+	if err == nil || err.Error() != `requires present(DE14) when DE22[0:2] == "05"` {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	if err := m.SetField(14, "2512"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rule.Validate(m); err != nil {
+		t.Errorf("Validate() with DE 14 = %v", err)
+	}
+
+	if err := m.ClearField(14); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetMTI([]byte("0100")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rule.Validate(m); err != nil {
+		t.Errorf("Validate() for other MTI = %v", err)
+	}
+
+	_, err = NewCrossFieldRule(CrossFieldRuleConfig{Name: "broken", Require: "DE2 =="}, nil)
+	if !errors.Is(err, ErrInvalidRule) || !strings.HasPrefix(err.Error(), "rule broken: require:") {
+		t.Errorf("NewCrossFieldRule(broken) = %v", err)
+	}
+}
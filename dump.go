@@ -0,0 +1,238 @@
+package iso8583
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+var fieldTypeNames = [...]string{
+	FieldTypeANS:    "ANS",
+	FieldTypeAN:     "AN",
+	FieldTypeN:      "N",
+	FieldTypeB:      "B",
+	FieldTypeZ:      "Z",
+	FieldTypeCustom: "CUSTOM",
+}
+
+var lengthTypeNames = [...]string{
+	LengthFixed:   "FIXED",
+	LengthLLVAR:   "LLVAR",
+	LengthLLLVAR:  "LLLVAR",
+	LengthLLLLVAR: "LLLLVAR",
+}
+
+var mtiClassNames = map[MTIClass]string{
+	MTIClassAuthorization:  "authorization",
+	MTIClassFinancial:      "financial",
+	MTIClassFileAction:     "file action",
+	MTIClassReversal:       "reversal/chargeback",
+	MTIClassReconciliation: "reconciliation",
+	MTIClassAdministrative: "administrative",
+	MTIClassFeeCollection:  "fee collection",
+	MTIClassNetwork:        "network management",
+}
+
+var mtiFunctionNames = map[MTIFunction]string{
+	MTIFunctionRequest:         "request",
+	MTIFunctionResponse:        "response",
+	MTIFunctionAdvice:          "advice",
+	MTIFunctionAdviceResponse:  "advice response",
+	MTIFunctionNotification:    "notification",
+	MTIFunctionNotificationAck: "notification acknowledgement",
+	MTIFunctionInstruction:     "instruction",
+	MTIFunctionInstructionAck:  "instruction acknowledgement",
+}
+
+var mtiOriginNames = map[MTIOrigin]string{
+	MTIOriginAcquirer:       "acquirer",
+	MTIOriginAcquirerRepeat: "acquirer repeat",
+	MTIOriginIssuer:         "issuer",
+	MTIOriginIssuerRepeat:   "issuer repeat",
+	MTIOriginOther:          "other",
+	MTIOriginOtherRepeat:    "other repeat",
+}
+
+// Dump writes a human-readable description of the message to w: header,
+// MTI, bitmap and every present field with its configured name, type,
+// length prefix, wire bytes and decoded value, including TLV entries and
+// subfields. Values are masked with the packager's masking policy; the
+// wire bytes of masked fields are omitted.
+func (m *Message) Dump(w io.Writer) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var sb strings.Builder
+	masker := m.packager.GetMasker()
+
+	// Header
+	if len(m.header) > 0 {
+		fmt.Fprintf(&sb, "Header: %X\n", m.header)
+		if layout, ok := m.headerCodec().(*HeaderLayout); ok {
+			for _, hf := range layout.Fields {
+				if value, err := layout.Get(m.header, hf.Name); err == nil {
+					fmt.Fprintf(&sb, "  %-20s %s\n", hf.Name, value)
+				}
+			}
+		}
+	}
+
+	// MTI
+	mti := MTI(m.mti)
+	fmt.Fprintf(&sb, "MTI:    %s", mti)
+	if desc := describeMTI(mti); desc != "" {
+		fmt.Fprintf(&sb, " (%s)", desc)
+	}
+	sb.WriteByte('\n')
+
+	// Bitmap
+	var bitmapBuf [MaxBitmaps * BitmapSize * 2]byte
+	n, _ := m.bitmap.PackBitmap(bitmapBuf[:], BitmapEncodingHex)
+	fmt.Fprintf(&sb, "Bitmap: %s\n", bitmapBuf[:n])
+	present := make([]string, 0, 32)
+	for fieldNum := 2; fieldNum <= MaxFieldNumber; fieldNum++ {
+		if m.isFieldPresent(fieldNum) {
+			present = append(present, fmt.Sprintf("%d", fieldNum))
+		}
+	}
+	fmt.Fprintf(&sb, "  fields: %s\n", strings.Join(present, " "))
+
+	// Fields
+	for fieldNum := 2; fieldNum <= MaxFieldNumber; fieldNum++ {
+		if m.isFieldPresent(fieldNum) {
+			m.dumpField(&sb, fieldNum, masker)
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// dumpField writes one field and its TLV entries or subfields.
+func (m *Message) dumpField(sb *strings.Builder, fieldNum int, masker *Masker) {
+	data := m.fields[fieldNum-1].Bytes()
+	mode := masker.Mode(fieldNum)
+
+	var config FieldConfig
+	configured := false
+	if m.packager != nil {
+		config, configured = m.packager.fieldConfigs[fieldNum]
+	}
+
+	fmt.Fprintf(sb, "DE %03d", fieldNum)
+	if config.Name != "" {
+		fmt.Fprintf(sb, " %s", config.Name)
+	}
+	sb.WriteByte('\n')
+	if config.Description != "" {
+		fmt.Fprintf(sb, "       %s\n", config.Description)
+	}
+
+	if configured {
+		fmt.Fprintf(sb, "       type=%s length=%s", enumName(fieldTypeNames[:], int(config.Type)), enumName(lengthTypeNames[:], int(config.Length)))
+		if config.Length != LengthFixed {
+			var prefix [4]byte
+			if n, err := writeLengthPrefix(prefix[:], len(data), lengthPrefixDigits(config.Length), config.LengthEncoding); err == nil {
+				fmt.Fprintf(sb, " prefix=%X", prefix[:n])
+			}
+		}
+		fmt.Fprintf(sb, " len=%d\n", len(data))
+
+		if mode == MaskClear {
+			if encoder, err := GetEncoder(config.Encoding); err == nil {
+				raw := make([]byte, encoder.EncodedLen(len(data)))
+				if n, err := encoder.Encode(raw, data); err == nil {
+					fmt.Fprintf(sb, "       raw=%X\n", raw[:n])
+				}
+			}
+		} else {
+			sb.WriteString("       raw=<masked>\n")
+		}
+	} else {
+		fmt.Fprintf(sb, "       len=%d (not configured)\n", len(data))
+	}
+
+	fmt.Fprintf(sb, "       value=%s\n", masker.MaskWith(mode, []byte(displayValue(config, data))))
+
+	if tlvs, ok := m.tlvData[fieldNum]; ok {
+		emv := false
+		if m.packager != nil {
+			if parser, exists := m.packager.GetTLVParser(fieldNum); exists {
+				emv = parser.tlvType == TLVEMV
+			}
+		}
+		dumpTLVs(sb, tlvs, emv, mode, masker, "       ")
+	}
+
+	if subfields, _ := m.subfieldsOf(fieldNum); subfields != nil {
+		nums := make([]int, 0, len(subfields))
+		for num := range subfields {
+			nums = append(nums, num)
+		}
+		sort.Ints(nums)
+		for _, num := range nums {
+			sub := config.Subfields[num]
+			fmt.Fprintf(sb, "       .%02d", num)
+			if sub.Name != "" {
+				fmt.Fprintf(sb, " %s", sub.Name)
+			}
+			fmt.Fprintf(sb, " = %s\n", masker.MaskWith(mode, []byte(displayValue(sub, subfields[num]))))
+		}
+	}
+}
+
+// dumpTLVs writes TLV entries, recursing into constructed EMV tags.
+func dumpTLVs(sb *strings.Builder, tlvs []TLV, emv bool, mode MaskMode, masker *Masker, indent string) {
+	for _, tlv := range tlvs {
+		tag := string(tlv.Tag)
+		value := string(tlv.Value)
+		if emv {
+			tag = fmt.Sprintf("%X", tlv.Tag)
+			value = tlv.Hex()
+		}
+		if len(tlv.Children) > 0 {
+			fmt.Fprintf(sb, "%stag %s [%d]\n", indent, tag, tlv.Length)
+			dumpTLVs(sb, tlv.Children, emv, mode, masker, indent+"  ")
+			continue
+		}
+		fmt.Fprintf(sb, "%stag %s [%d] %s\n", indent, tag, tlv.Length, masker.MaskWith(mode, []byte(value)))
+	}
+}
+
+// displayValue renders binary fields as hex and everything else as text.
+func displayValue(config FieldConfig, data []byte) string {
+	if config.Type == FieldTypeB {
+		return fmt.Sprintf("%X", data)
+	}
+	return string(data)
+}
+
+// describeMTI returns e.g. "1987 authorization request, acquirer".
+func describeMTI(mti MTI) string {
+	class, ok := mtiClassNames[mti.Class()]
+	if !ok {
+		return ""
+	}
+	parts := make([]string, 0, 3)
+	if v := mti.Version(); v != 0 {
+		parts = append(parts, v.String())
+	}
+	parts = append(parts, class)
+	if function, ok := mtiFunctionNames[mti.Function()]; ok {
+		parts = append(parts, function)
+	}
+	desc := strings.Join(parts, " ")
+	if origin, ok := mtiOriginNames[mti.Origin()]; ok {
+		desc += ", " + origin
+	}
+	return desc
+}
+
+// enumName returns names[v], or the number if v is out of range.
+func enumName(names []string, v int) string {
+	if v >= 0 && v < len(names) && names[v] != "" {
+		return names[v]
+	}
+	return fmt.Sprintf("%d", v)
+}
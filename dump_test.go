@@ -0,0 +1,80 @@
+package iso8583
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig(
+		WithHeaderConfig(HeaderConfig{Type: HeaderBinary, Length: 5, Format: "tpdu"}),
+		WithTLVConfig(TLVConfig{Type: TLVEMV, Enabled: true, MaxDepth: 2}),
+		WithFieldConfig(41, FieldConfig{Type: FieldTypeANS, Length: LengthFixed, MaxLength: 8, Name: "Card acceptor terminal ID"}),
+		WithFieldConfig(55, FieldConfig{Type: FieldTypeB, Length: LengthLLLVAR, MaxLength: 999, TLV: &FieldTLVConfig{Type: TLVEMV}}),
+		WithFieldConfig(90, FieldConfig{Type: FieldTypeN, Length: LengthFixed, MaxLength: 10, Subfields: map[int]FieldConfig{
+			1: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Name: "Original MTI"},
+			2: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 6},
+		}}),
+		WithMasking(map[int]MaskMode{55: MaskClear}),
+	))
+	m := newTestMessage(t, pkg, "0400", map[int]string{
+		2: "4111111111111111", 3: "000000", 41: "TERM0001", 90: "0200000123",
+	})
+	m.SetField(55, mustHex(t, "9F02060000000012347004950200FF"))
+	got := repack(t, m)
+
+	var sb strings.Builder
+	if err := got.Dump(&sb); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+
+	for _, want := range []string{
+		"Header: 6000000000",
+		"MTI:    0400 (1987 reversal/chargeback request, acquirer)",
+		"  fields: 2 3 41 55 90",
+		"DE 002",
+		"prefix=3136 len=16",
+		"raw=<masked>",
+		"value=411111******1111",
+		"DE 041 Card acceptor terminal ID",
+		"type=ANS length=FIXED len=8",
+		"raw=5445524D30303031",
+		"tag 9F02 [6] 000000001234",
+		"tag 70 [4]",
+		"  tag 95 [2] 00FF",
+		".01 Original MTI = 0200",
+		".02 = 000123",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Dump output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "4111111111111111") {
+		t.Errorf("Dump output contains the clear PAN:\n%s", out)
+	}
+
+	// Subfields of a value set with SetField are shown before packing
+	sb.Reset()
+	m.Dump(&sb)
+	if !strings.Contains(sb.String(), ".02 = 000123") {
+		t.Errorf("Dump output lacks the DE 90 subfields:\n%s", sb.String())
+	}
+}
+
+func TestDumpUnconfiguredField(t *testing.T) {
+	m := NewMessage()
+	m.SetMTI([]byte("9800"))
+	m.SetField(11, "000001")
+
+	var sb strings.Builder
+	if err := m.Dump(&sb); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+	for _, want := range []string{"MTI:    9800 (network management request, acquirer)", "len=6 (not configured)", "value=000001"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Dump output lacks %q:\n%s", want, out)
+		}
+	}
+}
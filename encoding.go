@@ -0,0 +1,242 @@
+package iso8583
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// FieldEncoder converts a field's logical value to and from its wire representation.
+// Lengths are always expressed in logical units (characters, digits or bytes),
+// which is what LLVAR prefixes and FieldConfig.MaxLength count.
+type FieldEncoder interface {
+	// EncodedLen returns the number of wire bytes used by a value of the given logical length.
+	EncodedLen(length int) int
+	// Encode writes the wire form of src into dst and returns the number of bytes written.
+	Encode(dst, src []byte) (int, error)
+	// Decode converts the wire bytes in src, holding length logical units, into the logical value.
+	Decode(src []byte, length int) ([]byte, error)
+}
+
+// builtinEncoders maps each built-in Encoding to its implementation.
+var builtinEncoders = [...]FieldEncoder{
+	EncodingASCII:   asciiEncoder{},
+	EncodingEBCDIC:  ebcdicEncoder{},
+	EncodingBCD:     bcdEncoder{leftJustified: false},
+	EncodingBCDLeft: bcdEncoder{leftJustified: true},
+	EncodingBinary:  asciiEncoder{},
+	EncodingHex:     hexEncoder{},
+}
+
+// customEncoders holds encoders registered with RegisterEncoding.
+var (
+	customEncoders   = make(map[Encoding]FieldEncoder)
+	customEncodersMu sync.RWMutex
+)
+
+// RegisterEncoding registers a FieldEncoder for a custom Encoding value.
+// Custom encodings must use values at or above EncodingCustom.
+func RegisterEncoding(enc Encoding, encoder FieldEncoder) error {
+	if enc < EncodingCustom {
+		return fmt.Errorf("encoding %d is reserved for built-in encodings", enc)
+	}
+	customEncodersMu.Lock()
+	defer customEncodersMu.Unlock()
+	customEncoders[enc] = encoder
+	return nil
+}
+
+// GetEncoder returns the FieldEncoder registered for the given Encoding.
+func GetEncoder(enc Encoding) (FieldEncoder, error) {
+	if enc >= 0 && int(enc) < len(builtinEncoders) {
+		return builtinEncoders[enc], nil
+	}
+	customEncodersMu.RLock()
+	defer customEncodersMu.RUnlock()
+	if encoder, ok := customEncoders[enc]; ok {
+		return encoder, nil
+	}
+	return nil, ErrUnsupportedEncoding
+}
+
+// asciiEncoder passes bytes through unchanged. It is used for both ASCII
+// text and raw binary fields, so decoding is zero-copy.
+type asciiEncoder struct{}
+
+func (asciiEncoder) EncodedLen(length int) int { return length }
+
+func (asciiEncoder) Encode(dst, src []byte) (int, error) {
+	if len(dst) < len(src) {
+		return 0, ErrBufferTooSmall
+	}
+	return copy(dst, src), nil
+}
+
+func (asciiEncoder) Decode(src []byte, length int) ([]byte, error) {
+	if len(src) < length {
+		return nil, ErrInvalidLength
+	}
+	return src[:length], nil
+}
+
+// ebcdicEncoder converts between ASCII (Latin-1) and EBCDIC code page 037.
+type ebcdicEncoder struct{}
+
+func (ebcdicEncoder) EncodedLen(length int) int { return length }
+
+func (ebcdicEncoder) Encode(dst, src []byte) (int, error) {
+	if len(dst) < len(src) {
+		return 0, ErrBufferTooSmall
+	}
+	for i, b := range src {
+		dst[i] = asciiToEBCDIC[b]
+	}
+	return len(src), nil
+}
+
+func (ebcdicEncoder) Decode(src []byte, length int) ([]byte, error) {
+	if len(src) < length {
+		return nil, ErrInvalidLength
+	}
+	out := make([]byte, length)
+	for i := 0; i < length; i++ {
+		out[i] = ebcdicToASCII[src[i]]
+	}
+	return out, nil
+}
+
+// bcdEncoder packs two digits per byte. Odd-length values are padded with a
+// leading 0 nibble (right-justified) or a trailing F nibble (left-justified).
+// Nibbles A-F are carried as uppercase hex letters so track data separators
+// ('D', or '=' on input) survive the round trip.
+type bcdEncoder struct {
+	leftJustified bool
+}
+
+func (bcdEncoder) EncodedLen(length int) int { return (length + 1) / 2 }
+
+func (e bcdEncoder) Encode(dst, src []byte) (int, error) {
+	n := (len(src) + 1) / 2
+	if len(dst) < n {
+		return 0, ErrBufferTooSmall
+	}
+
+	pad := len(src) % 2
+	for i := 0; i < n; i++ {
+		dst[i] = 0
+	}
+	for i, ch := range src {
+		nibble, ok := bcdNibble(ch)
+		if !ok {
+			return 0, fmt.Errorf("%w: invalid BCD character '%c' at position %d", ErrInvalidEncoding, ch, i)
+		}
+		pos := i
+		if !e.leftJustified {
+			pos += pad // Shift right past the leading pad nibble
+		}
+		if pos%2 == 0 {
+			dst[pos/2] |= nibble << 4
+		} else {
+			dst[pos/2] |= nibble
+		}
+	}
+	if pad == 1 && e.leftJustified {
+		dst[n-1] |= 0x0F
+	}
+	return n, nil
+}
+
+func (e bcdEncoder) Decode(src []byte, length int) ([]byte, error) {
+	n := (length + 1) / 2
+	if len(src) < n {
+		return nil, ErrInvalidLength
+	}
+
+	start := 0
+	if !e.leftJustified && length%2 == 1 {
+		start = 1 // Skip the leading pad nibble
+	}
+	out := make([]byte, length)
+	for i := 0; i < length; i++ {
+		pos := start + i
+		var nibble byte
+		if pos%2 == 0 {
+			nibble = src[pos/2] >> 4
+		} else {
+			nibble = src[pos/2] & 0x0F
+		}
+		out[i] = hexTableUpper[nibble]
+	}
+	return out, nil
+}
+
+// bcdNibble returns the 4-bit value for a BCD character.
+func bcdNibble(ch byte) (byte, bool) {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return ch - '0', true
+	case ch >= 'A' && ch <= 'F':
+		return ch - 'A' + 10, true
+	case ch >= 'a' && ch <= 'f':
+		return ch - 'a' + 10, true
+	case ch == '=':
+		return 0x0D, true // Track 2 field separator
+	default:
+		return 0, false
+	}
+}
+
+// hexEncoder carries raw binary values as uppercase ASCII hex on the wire.
+// The logical length is the number of binary bytes.
+type hexEncoder struct{}
+
+func (hexEncoder) EncodedLen(length int) int { return length * 2 }
+
+func (hexEncoder) Encode(dst, src []byte) (int, error) {
+	n := len(src) * 2
+	if len(dst) < n {
+		return 0, ErrBufferTooSmall
+	}
+	encodeHexUpper(dst[:n], src)
+	return n, nil
+}
+
+func (hexEncoder) Decode(src []byte, length int) ([]byte, error) {
+	if len(src) < length*2 {
+		return nil, ErrInvalidLength
+	}
+	out := make([]byte, length)
+	if _, err := hex.Decode(out, src[:length*2]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEncoding, err)
+	}
+	return out, nil
+}
+
+// ebcdicToASCII maps EBCDIC code page 037 to ISO-8859-1.
+var ebcdicToASCII = [256]byte{
+	0x00, 0x01, 0x02, 0x03, 0x9C, 0x09, 0x86, 0x7F, 0x97, 0x8D, 0x8E, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F,
+	0x10, 0x11, 0x12, 0x13, 0x9D, 0x85, 0x08, 0x87, 0x18, 0x19, 0x92, 0x8F, 0x1C, 0x1D, 0x1E, 0x1F,
+	0x80, 0x81, 0x82, 0x83, 0x84, 0x0A, 0x17, 0x1B, 0x88, 0x89, 0x8A, 0x8B, 0x8C, 0x05, 0x06, 0x07,
+	0x90, 0x91, 0x16, 0x93, 0x94, 0x95, 0x96, 0x04, 0x98, 0x99, 0x9A, 0x9B, 0x14, 0x15, 0x9E, 0x1A,
+	0x20, 0xA0, 0xE2, 0xE4, 0xE0, 0xE1, 0xE3, 0xE5, 0xE7, 0xF1, 0xA2, 0x2E, 0x3C, 0x28, 0x2B, 0x7C,
+	0x26, 0xE9, 0xEA, 0xEB, 0xE8, 0xED, 0xEE, 0xEF, 0xEC, 0xDF, 0x21, 0x24, 0x2A, 0x29, 0x3B, 0xAC,
+	0x2D, 0x2F, 0xC2, 0xC4, 0xC0, 0xC1, 0xC3, 0xC5, 0xC7, 0xD1, 0xA6, 0x2C, 0x25, 0x5F, 0x3E, 0x3F,
+	0xF8, 0xC9, 0xCA, 0xCB, 0xC8, 0xCD, 0xCE, 0xCF, 0xCC, 0x60, 0x3A, 0x23, 0x40, 0x27, 0x3D, 0x22,
+	0xD8, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69, 0xAB, 0xBB, 0xF0, 0xFD, 0xFE, 0xB1,
+	0xB0, 0x6A, 0x6B, 0x6C, 0x6D, 0x6E, 0x6F, 0x70, 0x71, 0x72, 0xAA, 0xBA, 0xE6, 0xB8, 0xC6, 0xA4,
+	0xB5, 0x7E, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79, 0x7A, 0xA1, 0xBF, 0xD0, 0xDD, 0xDE, 0xAE,
+	0x5E, 0xA3, 0xA5, 0xB7, 0xA9, 0xA7, 0xB6, 0xBC, 0xBD, 0xBE, 0x5B, 0x5D, 0xAF, 0xA8, 0xB4, 0xD7,
+	0x7B, 0x41, 0x42, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49, 0xAD, 0xF4, 0xF6, 0xF2, 0xF3, 0xF5,
+	0x7D, 0x4A, 0x4B, 0x4C, 0x4D, 0x4E, 0x4F, 0x50, 0x51, 0x52, 0xB9, 0xFB, 0xFC, 0xF9, 0xFA, 0xFF,
+	0x5C, 0xF7, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59, 0x5A, 0xB2, 0xD4, 0xD6, 0xD2, 0xD3, 0xD5,
+	0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39, 0xB3, 0xDB, 0xDC, 0xD9, 0xDA, 0x9F,
+}
+
+// asciiToEBCDIC is the inverse of ebcdicToASCII.
+var asciiToEBCDIC [256]byte
+
+func init() {
+	for e, a := range ebcdicToASCII {
+		asciiToEBCDIC[a] = byte(e)
+	}
+}
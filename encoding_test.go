@@ -0,0 +1,104 @@
+package iso8583
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncoderRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		enc   Encoding
+		value string
+		wire  []byte
+	}{
+		{"ASCII", EncodingASCII, "AB 12", []byte("AB 12")},
+		{"EBCDIC", EncodingEBCDIC, "AB 12", []byte{0xC1, 0xC2, 0x40, 0xF1, 0xF2}},
+		{"BCD even", EncodingBCD, "1234", []byte{0x12, 0x34}},
+		{"BCD odd", EncodingBCD, "12345", []byte{0x01, 0x23, 0x45}},
+		{"BCD left odd", EncodingBCDLeft, "12345", []byte{0x12, 0x34, 0x5F}},
+		{"BCD track separator", EncodingBCD, "41D25", []byte{0x04, 0x1D, 0x25}},
+		{"binary", EncodingBinary, "\x00\xff", []byte{0x00, 0xff}},
+		{"hex", EncodingHex, "\x0a\xbc", []byte("0ABC")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder, err := GetEncoder(tt.enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := encoder.EncodedLen(len(tt.value)); got != len(tt.wire) {
+				t.Errorf("EncodedLen(%d) = %d, want %d", len(tt.value), got, len(tt.wire))
+			}
+			buf := make([]byte, len(tt.wire))
+			n, err := encoder.Encode(buf, []byte(tt.value))
+			if err != nil || !bytes.Equal(buf[:n], tt.wire) {
+				t.Errorf("Encode = %X, %v, want %X", buf[:n], err, tt.wire)
+			}
+			value, err := encoder.Decode(tt.wire, len(tt.value))
+			if err != nil || string(value) != tt.value {
+				t.Errorf("Decode = %q, %v, want %q", value, err, tt.value)
+			}
+		})
+	}
+}
+
+func TestEncoderErrors(t *testing.T) {
+	bcd, _ := GetEncoder(EncodingBCD)
+	if _, err := bcd.Encode(make([]byte, 2), []byte("12x4")); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("BCD Encode of a letter = %v, want ErrInvalidEncoding", err)
+	}
+	if _, err := bcd.Encode(make([]byte, 1), []byte("1234")); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("BCD Encode into a short buffer = %v, want ErrBufferTooSmall", err)
+	}
+	if _, err := bcd.Decode([]byte{0x12}, 4); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("BCD Decode of short data = %v, want ErrInvalidLength", err)
+	}
+
+	hexEnc, _ := GetEncoder(EncodingHex)
+	if _, err := hexEnc.Decode([]byte("0G"), 1); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("hex Decode of a non-hex digit = %v, want ErrInvalidEncoding", err)
+	}
+
+	if _, err := GetEncoder(EncodingCustom + 100); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("GetEncoder of an unregistered encoding = %v, want ErrUnsupportedEncoding", err)
+	}
+	if err := RegisterEncoding(EncodingBCD, asciiEncoder{}); err == nil {
+		t.Error("RegisterEncoding replaced a built-in encoding")
+	}
+}
+
+func TestMessageEncodingRoundTrip(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig(
+		WithFieldConfig(3, FieldConfig{Type: FieldTypeN, Length: LengthFixed, MaxLength: 6, Encoding: EncodingBCD}),
+		WithFieldConfig(4, FieldConfig{Type: FieldTypeN, Length: LengthFixed, MaxLength: 12, Encoding: EncodingBCD}),
+		WithFieldConfig(41, FieldConfig{Type: FieldTypeANS, Length: LengthFixed, MaxLength: 8, Encoding: EncodingEBCDIC}),
+		WithFieldConfig(52, FieldConfig{Type: FieldTypeB, Length: LengthFixed, MaxLength: 8, Encoding: EncodingHex}),
+	))
+	m := newTestMessage(t, pkg, "0200", map[int]string{
+		3: "000000", 4: "000000001234", 41: "TERM0001", 52: "\x01\x23\x45\x67\x89\xab\xcd\xef",
+	})
+
+	buf := make([]byte, 256)
+	n, err := m.Pack(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// MTI, hex bitmap, 3 + 6 BCD bytes, 8 EBCDIC bytes, 16 hex characters
+	if want := 4 + 16 + 3 + 6 + 8 + 16; n != want {
+		t.Errorf("packed %d bytes, want %d", n, want)
+	}
+	if !bytes.Contains(buf[:n], []byte{0xE3, 0xC5, 0xD9, 0xD4}) {
+		t.Errorf("DE 41 not EBCDIC encoded: %X", buf[:n])
+	}
+
+	got := repack(t, m)
+	for fieldNum := range map[int]bool{3: true, 4: true, 41: true, 52: true} {
+		want, _ := m.GetString(fieldNum)
+		if s, err := got.GetString(fieldNum); err != nil || s != want {
+			t.Errorf("DE %d = %q, %v, want %q", fieldNum, s, err, want)
+		}
+	}
+}
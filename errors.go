@@ -1,6 +1,10 @@
 package iso8583
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
 
 var (
 	ErrInvalidMTI       = fmt.Errorf("invalid MTI")
@@ -17,6 +21,23 @@ var (
 	ErrFieldNotConfigured    = fmt.Errorf("field not configured")
 	ErrUnsupportedLengthType = fmt.Errorf("unsupported length type")
 	ErrInvalidBitmapHex      = fmt.Errorf("invalid bitmap hex")
+	ErrInvalidEncoding       = fmt.Errorf("invalid encoded data")
+	ErrUnsupportedEncoding   = fmt.Errorf("unsupported encoding")
+	ErrTruncatedMessage      = fmt.Errorf("truncated message")
+	ErrTrailingBytes         = fmt.Errorf("trailing bytes after message")
+	ErrMessageTooLarge       = fmt.Errorf("message exceeds maximum size")
+	ErrNoLengthIndicator     = fmt.Errorf("no length indicator configured")
+	ErrTagNotFound           = fmt.Errorf("TLV tag not found")
+	ErrFieldNotTLV           = fmt.Errorf("field not configured for TLV")
+	ErrFieldNotComposite     = fmt.Errorf("field not configured with subfields")
+	ErrUnsupportedVersion    = fmt.Errorf("unsupported ISO 8583 version")
+	ErrNoResponseMTI         = fmt.Errorf("MTI has no response")
+	ErrResponseFieldMissing  = fmt.Errorf("required response field missing")
+	ErrInvalidFormat         = fmt.Errorf("value does not match field format")
+	ErrUnsupportedType       = fmt.Errorf("unsupported Go type")
+	ErrInvalidRule           = fmt.Errorf("invalid rule expression")
+	ErrAmountNotFound        = fmt.Errorf("additional amount not found")
+	ErrInvalidTrackData      = fmt.Errorf("invalid track data")
 )
 
 type FieldError struct {
@@ -28,16 +49,117 @@ func (fe *FieldError) Error() string {
 	return fmt.Sprintf("field %d: %v", fe.Field, fe.Err)
 }
 
+// Unwrap returns the underlying error.
+func (fe *FieldError) Unwrap() error {
+	return fe.Err
+}
+
 type ValidationError struct {
 	Field   int
+	Fields  []int // All fields involved in a cross-field rule failure; Field is the first
 	Rule    string
 	Message string
+	Err     error // Underlying cause, e.g. a decode error during Unpack
 }
 
 func (ve *ValidationError) Error() string {
+	if len(ve.Fields) > 1 {
+		nums := make([]string, len(ve.Fields))
+		for i, fieldNum := range ve.Fields {
+			nums[i] = fmt.Sprintf("%d", fieldNum)
+		}
+		return fmt.Sprintf("validation failed for fields %s (%s): %s", strings.Join(nums, ", "), ve.Rule, ve.Message)
+	}
 	return fmt.Sprintf("validation failed for field %d (%s): %s", ve.Field, ve.Rule, ve.Message)
 }
 
+// involves reports whether the error concerns a field.
+func (ve *ValidationError) involves(fieldNum int) bool {
+	if ve.Field == fieldNum {
+		return true
+	}
+	for _, f := range ve.Fields {
+		if f == fieldNum {
+			return true
+		}
+	}
+	return false
+}
+
+// Is reports every ValidationError as ErrValidationFailed.
+func (ve *ValidationError) Is(target error) bool {
+	return target == ErrValidationFailed
+}
+
+func (ve *ValidationError) Unwrap() error {
+	return ve.Err
+}
+
+// ValidationReport lists every validation failure of a message. It is
+// returned in ValidationCollectAll mode and works with errors.Is and
+// errors.As like an errors.Join of its entries.
+type ValidationReport struct {
+	Errors []*ValidationError
+}
+
+func (vr *ValidationReport) Error() string {
+	if len(vr.Errors) == 1 {
+		return vr.Errors[0].Error()
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d validation errors", len(vr.Errors))
+	for _, ve := range vr.Errors {
+		sb.WriteString("; ")
+		sb.WriteString(ve.Error())
+	}
+	return sb.String()
+}
+
+func (vr *ValidationReport) Unwrap() []error {
+	errs := make([]error, len(vr.Errors))
+	for i, ve := range vr.Errors {
+		errs[i] = ve
+	}
+	return errs
+}
+
+// Fields returns the numbers of the failing fields in ascending order.
+func (vr *ValidationReport) Fields() []int {
+	seen := make(map[int]bool, len(vr.Errors))
+	fields := make([]int, 0, len(vr.Errors))
+	for _, ve := range vr.Errors {
+		for _, fieldNum := range append([]int{ve.Field}, ve.Fields...) {
+			if !seen[fieldNum] {
+				seen[fieldNum] = true
+				fields = append(fields, fieldNum)
+			}
+		}
+	}
+	sort.Ints(fields)
+	return fields
+}
+
+// FieldErrors returns the failures of a single field.
+func (vr *ValidationReport) FieldErrors(fieldNum int) []*ValidationError {
+	var errs []*ValidationError
+	for _, ve := range vr.Errors {
+		if ve.involves(fieldNum) {
+			errs = append(errs, ve)
+		}
+	}
+	return errs
+}
+
+// add records a decode failure of a field.
+func (vr *ValidationReport) add(fieldNum int, err error) {
+	vr.Errors = append(vr.Errors, &ValidationError{
+		Field:   fieldNum,
+		Rule:    "decode",
+		Message: err.Error(),
+		Err:     err,
+	})
+}
+
 type TLVError struct {
 	Tag []byte
 	Err error
@@ -46,3 +168,8 @@ type TLVError struct {
 func (te *TLVError) Error() string {
 	return fmt.Sprintf("TLV tag %x: %v", te.Tag, te.Err)
 }
+
+// Unwrap returns the underlying error.
+func (te *TLVError) Unwrap() error {
+	return te.Err
+}
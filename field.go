@@ -2,6 +2,7 @@ package iso8583
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"sync"
 	"unsafe"
@@ -171,8 +172,9 @@ func (f *Field) SetFloat(value float64, fieldType FieldType, precision int) {
 		value = -value
 	}
 
-	// Convert to integer by moving decimal point
-	intValue := int64(value * pow10(precision))
+	// Convert to integer by moving decimal point, rounding to the nearest
+	// unit so e.g. 0.29 gives 29 rather than 28
+	intValue := int64(math.Round(value * pow10(precision)))
 
 	// Calculate required digits
 	integerDigits := 0
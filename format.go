@@ -0,0 +1,171 @@
+package iso8583
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeKind tells how much of a date a date/time format carries.
+type timeKind int
+
+const (
+	timeDated    timeKind = iota // Includes the year
+	timeYearless                 // Month and day without a year
+	timeOnly                     // Time of day without a date
+)
+
+// isoTimeLayout is the Go time layout of a date/time format and its kind.
+type isoTimeLayout struct {
+	layout string
+	kind   timeKind
+}
+
+// isoTimeLayouts maps the date/time names used in FieldConfig.Format to Go time layouts.
+var isoTimeLayouts = map[string]isoTimeLayout{
+	"MMDDhhmmss":     {"0102150405", timeYearless},
+	"YYMMDDhhmmss":   {"060102150405", timeDated},
+	"CCYYMMDDhhmmss": {"20060102150405", timeDated},
+	"hhmmss":         {"150405", timeOnly},
+	"MMDD":           {"0102", timeYearless},
+	"YYMM":           {"0601", timeDated},
+	"YYMMDD":         {"060102", timeDated},
+	"CCYYMMDD":       {"20060102", timeDated},
+}
+
+// defaultTimeFormats gives the 1987 date/time format of fields whose
+// configuration does not set one.
+var defaultTimeFormats = map[int]string{
+	7:  "MMDDhhmmss", // Transmission date & time
+	12: "hhmmss",     // Local transaction time
+	13: "MMDD",       // Local transaction date
+	14: "YYMM",       // Expiration date
+	15: "MMDD",       // Settlement date
+	16: "MMDD",       // Conversion date
+	17: "MMDD",       // Capture date
+}
+
+// timeLayout returns the Go time layout for a FieldConfig.Format name.
+func timeLayout(format string) (isoTimeLayout, bool) {
+	layout, ok := isoTimeLayouts[format]
+	return layout, ok
+}
+
+// amountFormat parses an amount format: "amount" takes its implied decimals
+// from the currency, "amount:N" always has N decimals. The "signed_amount"
+// variants carry a C (credit) or D (debit) prefix.
+func amountFormat(format string) (decimals int, fixed bool, signed bool, ok bool) {
+	if rest, found := strings.CutPrefix(format, "signed_"); found {
+		format, signed = rest, true
+	}
+	if format == "amount" {
+		return 0, false, signed, true
+	}
+	if rest, found := strings.CutPrefix(format, "amount:"); found {
+		n, err := strconv.Atoi(rest)
+		if err == nil && n >= 0 && n <= 18 {
+			return n, true, signed, true
+		}
+	}
+	return 0, false, false, false
+}
+
+// trackFormats maps the track data format names to the track number.
+var trackFormats = map[string]int{
+	"track1": 1,
+	"track2": 2,
+}
+
+// knownFormat reports whether a FieldConfig.Format is a date/time, amount,
+// DE 54 additional amounts, track data or PAN format.
+func knownFormat(format string) bool {
+	if _, ok := timeLayout(format); ok {
+		return true
+	}
+	_, _, _, ok := amountFormat(format)
+	if _, isTrack := trackFormats[format]; isTrack {
+		return true
+	}
+	return ok || format == "additional_amounts" || format == "pan"
+}
+
+// parseISOTime parses value with a date/time layout in the location of ref.
+// Yearless layouts take the year that puts the result nearest to ref
+// (within six months), so "MMDD" and "MMDDhhmmss" values read around New
+// Year resolve to the right year; a Feb 29 that is not within six months
+// of a leap day is rejected. Time-only layouts take the date of ref.
+func parseISOTime(value string, layout isoTimeLayout, ref time.Time) (time.Time, error) {
+	loc := ref.Location()
+	t, err := time.ParseInLocation(layout.layout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+
+	hour, min, sec := t.Clock()
+	switch layout.kind {
+	case timeDated:
+		return t, nil
+	case timeOnly:
+		year, month, day := ref.Date()
+		return time.Date(year, month, day, hour, min, sec, t.Nanosecond(), loc), nil
+	}
+
+	var best time.Time
+	var bestDiff time.Duration
+	for year := ref.Year() - 1; year <= ref.Year()+1; year++ {
+		candidate := time.Date(year, t.Month(), t.Day(), hour, min, sec, t.Nanosecond(), loc)
+		if candidate.Day() != t.Day() {
+			continue // Feb 29 in a non-leap year
+		}
+		diff := candidate.Sub(ref)
+		if diff < 0 {
+			diff = -diff
+		}
+		if best.IsZero() || diff < bestDiff {
+			best, bestDiff = candidate, diff
+		}
+	}
+	if best.IsZero() || bestDiff > 184*24*time.Hour {
+		return time.Time{}, fmt.Errorf("%w: %s is not a valid date near %s", ErrInvalidFormat, value, ref.Format("2006-01-02"))
+	}
+	return best, nil
+}
+
+// GetTime parses a date/time field with its FieldConfig.Format (or the
+// 1987 format of DE 7 and DE 12-17) in loc. Formats without a year take
+// the year nearest to the current date; see GetTimeNear.
+func (m *Message) GetTime(fieldNum int, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return m.GetTimeNear(fieldNum, time.Now().In(loc))
+}
+
+// GetTimeNear parses a date/time field like GetTime, in the location of
+// ref. Formats without a year take the year that puts the result within six
+// months of ref, and time-only formats take the date of ref.
+func (m *Message) GetTimeNear(fieldNum int, ref time.Time) (time.Time, error) {
+	field, err := m.GetField(fieldNum)
+	if err != nil {
+		return time.Time{}, err
+	}
+	config, _ := m.fieldConfig(fieldNum)
+	layout, err := fieldTimeLayout(fieldNum, config, "")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseISOTime(field.String(), layout, ref)
+}
+
+// SetTime formats t with the field's date/time format and sets the field.
+// t is formatted in its own location; convert it first where the scheme
+// expects a specific zone (e.g. UTC for DE 7).
+func (m *Message) SetTime(fieldNum int, t time.Time) error {
+	config, _ := m.fieldConfig(fieldNum)
+	layout, err := fieldTimeLayout(fieldNum, config, "")
+	if err != nil {
+		return err
+	}
+	return m.SetField(fieldNum, t.Format(layout.layout))
+}
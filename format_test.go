@@ -0,0 +1,77 @@
+package iso8583
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseISOTimeNearestYear(t *testing.T) {
+	at := func(s string) time.Time {
+		t.Helper()
+		ref, err := time.Parse("2006-01-02 15:04:05", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ref
+	}
+
+	tests := []struct {
+		name   string
+		value  string
+		format string
+		ref    string
+		want   string // Empty if an error is expected
+	}{
+		{"same year", "0315", "MMDD", "2026-03-10 00:00:00", "2026-03-15 00:00:00"},
+		{"late in previous year", "1231235959", "MMDDhhmmss", "2026-01-01 00:00:30", "2025-12-31 23:59:59"},
+		{"early in next year", "0101000010", "MMDDhhmmss", "2025-12-31 23:59:00", "2026-01-01 00:00:10"},
+		{"six months back", "0701", "MMDD", "2026-12-30 00:00:00", "2026-07-01 00:00:00"},
+		{"leap day in leap year", "0229", "MMDD", "2028-03-01 00:00:00", "2028-02-29 00:00:00"},
+		{"leap day next year", "0229", "MMDD", "2027-12-31 00:00:00", "2028-02-29 00:00:00"},
+		{"leap day in non-leap year", "0229", "MMDD", "2026-03-01 00:00:00", ""},
+		{"time only", "134501", "hhmmss", "2026-10-16 08:00:00", "2026-10-16 13:45:01"},
+		{"explicit year", "2502", "YYMM", "2026-10-16 00:00:00", "2025-02-01 00:00:00"},
+		{"full date", "20240229101500", "CCYYMMDDhhmmss", "2026-10-16 00:00:00", "2024-02-29 10:15:00"},
+		{"short year date", "011231", "YYMMDD", "2026-10-16 00:00:00", "2001-12-31 00:00:00"},
+		{"invalid date", "1332", "MMDD", "2026-10-16 00:00:00", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, ok := timeLayout(tt.format)
+			if !ok {
+				t.Fatalf("no layout for %q", tt.format)
+			}
+			got, err := parseISOTime(tt.value, layout, at(tt.ref))
+			if tt.want == "" {
+				if !errors.Is(err, ErrInvalidFormat) {
+					t.Fatalf("parseISOTime(%q) = %v, %v; want ErrInvalidFormat", tt.value, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseISOTime(%q): %v", tt.value, err)
+			}
+			if !got.Equal(at(tt.want)) {
+				t.Errorf("parseISOTime(%q) = %v, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTimeNearLocation(t *testing.T) {
+	m := NewMessage(WithPackager(NewCompiledPackager(NewPackagerConfig())))
+	if err := m.SetField(13, "0101"); err != nil {
+		t.Fatal(err)
+	}
+
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	got, err := m.GetTimeNear(13, time.Date(2026, 12, 31, 20, 0, 0, 0, loc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2027, 1, 1, 0, 0, 0, 0, loc); !got.Equal(want) || got.Location() != loc {
+		t.Errorf("GetTimeNear(13) = %v, want %v", got, want)
+	}
+}
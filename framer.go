@@ -0,0 +1,198 @@
+package iso8583
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DefaultMaxMessageSize is the largest payload a MessageReader or MessageWriter
+// accepts unless configured otherwise with WithMaxMessageSize.
+const DefaultMaxMessageSize = 0xFFFF
+
+// framer holds the settings shared by MessageReader and MessageWriter.
+type framer struct {
+	config  LengthIndicatorConfig // How the length prefix is encoded
+	maxSize int                   // Upper bound on payload size
+}
+
+// FramerOption defines a function signature for configuring a MessageReader or MessageWriter.
+type FramerOption func(*framer)
+
+// WithMaxMessageSize sets the largest payload size accepted.
+// Length indicators declaring more than this are rejected with ErrMessageTooLarge,
+// which protects against corrupt or hostile length headers.
+func WithMaxMessageSize(n int) FramerOption {
+	return func(f *framer) {
+		f.maxSize = n
+	}
+}
+
+func newFramer(config LengthIndicatorConfig, opts []FramerOption) (framer, error) {
+	f := framer{
+		config:  config,
+		maxSize: DefaultMaxMessageSize,
+	}
+	for _, opt := range opts {
+		opt(&f)
+	}
+	if config.Type == LengthIndicatorNone || config.Length <= 0 {
+		return framer{}, fmt.Errorf("%w: indicator length %d", ErrNoLengthIndicator, config.Length)
+	}
+	if f.maxSize < 0 {
+		return framer{}, fmt.Errorf("invalid maximum message size %d", f.maxSize)
+	}
+	return f, nil
+}
+
+// MessageReader reads length-prefixed ISO8583 messages from an io.Reader,
+// one whole message at a time. It handles messages that arrive back-to-back
+// in one read as well as messages split across several reads.
+// A MessageReader is not safe for concurrent use.
+type MessageReader struct {
+	framer
+	r      io.Reader
+	prefix []byte // Reused buffer for the length indicator
+}
+
+// NewMessageReader creates a MessageReader using the given length indicator.
+// It returns ErrNoLengthIndicator if config has no type or a non-positive length.
+func NewMessageReader(r io.Reader, config LengthIndicatorConfig, opts ...FramerOption) (*MessageReader, error) {
+	f, err := newFramer(config, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &MessageReader{
+		framer: f,
+		r:      r,
+		prefix: make([]byte, config.Length),
+	}, nil
+}
+
+// ReadMessage reads the next framed message and returns its payload, without
+// the length indicator. A new slice is returned for every message, so it is
+// safe to Unpack into a Message that outlives the next call.
+// It returns io.EOF if the stream ends cleanly between messages and
+// io.ErrUnexpectedEOF if it ends part-way through one.
+func (mr *MessageReader) ReadMessage() ([]byte, error) {
+	if mr.config.Type == LengthIndicatorNone || mr.config.Length <= 0 {
+		return nil, ErrNoLengthIndicator
+	}
+
+	if _, err := io.ReadFull(mr.r, mr.prefix); err != nil {
+		return nil, err
+	}
+
+	msgLen, _, err := ReadLengthIndicator(mr.prefix, mr.config)
+	if err != nil {
+		return nil, err
+	}
+	if msgLen > mr.maxSize {
+		return nil, fmt.Errorf("%w: declared %d bytes, limit %d", ErrMessageTooLarge, msgLen, mr.maxSize)
+	}
+
+	payload := make([]byte, msgLen)
+	if _, err := io.ReadFull(mr.r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return payload, nil
+}
+
+// ReadMessageInto reads the next framed message and unpacks it into msg.
+// Like UnpackFramed, it returns ErrTrailingBytes if the message does not
+// consume the whole payload.
+func (mr *MessageReader) ReadMessageInto(msg *Message) error {
+	payload, err := mr.ReadMessage()
+	if err != nil {
+		return err
+	}
+
+	msg.mu.Lock()
+	defer msg.mu.Unlock()
+	return msg.unpackPayload(payload)
+}
+
+// MessageWriter writes length-prefixed ISO8583 messages to an io.Writer.
+// Each message is written with a single Write call, and a MessageWriter is
+// safe for concurrent use, so messages from different goroutines never interleave.
+type MessageWriter struct {
+	framer
+	w   io.Writer
+	buf []byte // Reused buffer for indicator + payload
+	mu  sync.Mutex
+}
+
+// NewMessageWriter creates a MessageWriter using the given length indicator.
+// It returns ErrNoLengthIndicator if config has no type or a non-positive length.
+func NewMessageWriter(w io.Writer, config LengthIndicatorConfig, opts ...FramerOption) (*MessageWriter, error) {
+	f, err := newFramer(config, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &MessageWriter{
+		framer: f,
+		w:      w,
+	}, nil
+}
+
+// WriteMessage writes the length indicator followed by payload.
+func (mw *MessageWriter) WriteMessage(payload []byte) error {
+	if mw.config.Type == LengthIndicatorNone || mw.config.Length <= 0 {
+		return ErrNoLengthIndicator
+	}
+	if len(payload) > mw.maxSize {
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrMessageTooLarge, len(payload), mw.maxSize)
+	}
+
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	frame := mw.frameBuffer(len(payload))
+	copy(frame[mw.config.Length:], payload)
+	return mw.writeFrame(frame, len(payload))
+}
+
+// WriteMessageFrom packs msg directly into the writer's buffer and writes it
+// as a single framed message. A message that does not fit in the maximum
+// size is rejected with ErrMessageTooLarge.
+func (mw *MessageWriter) WriteMessageFrom(msg *Message) error {
+	if mw.config.Type == LengthIndicatorNone || mw.config.Length <= 0 {
+		return ErrNoLengthIndicator
+	}
+
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	frame := mw.frameBuffer(mw.maxSize)
+	n, err := msg.Pack(frame[mw.config.Length:])
+	if errors.Is(err, ErrBufferTooSmall) {
+		return fmt.Errorf("%w: limit %d bytes", ErrMessageTooLarge, mw.maxSize)
+	}
+	if err != nil {
+		return err
+	}
+	return mw.writeFrame(frame[:mw.config.Length+n], n)
+}
+
+// frameBuffer returns a buffer large enough for the indicator and a payload
+// of payloadLen bytes, growing the reused buffer if needed.
+func (mw *MessageWriter) frameBuffer(payloadLen int) []byte {
+	total := mw.config.Length + payloadLen
+	if cap(mw.buf) < total {
+		mw.buf = make([]byte, total)
+	}
+	return mw.buf[:total]
+}
+
+// writeFrame fills in the length indicator and writes the frame.
+func (mw *MessageWriter) writeFrame(frame []byte, payloadLen int) error {
+	if _, err := WriteLengthIndicator(payloadLen, frame, mw.config); err != nil {
+		return err
+	}
+	_, err := mw.w.Write(frame)
+	return err
+}
@@ -0,0 +1,123 @@
+package iso8583
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+var asciiIndicator = LengthIndicatorConfig{Type: LengthIndicatorASCII, Length: 4}
+
+func TestMessageReaderWriterRoundTrip(t *testing.T) {
+	var stream bytes.Buffer
+	mw, err := NewMessageWriter(&stream, asciiIndicator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloads := [][]byte{[]byte("first"), {}, bytes.Repeat([]byte("x"), 300)}
+	for _, payload := range payloads {
+		if err := mw.WriteMessage(payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Deliver the stream one byte per read to exercise split messages
+	mr, err := NewMessageReader(iotest.OneByteReader(&stream), asciiIndicator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range payloads {
+		got, err := mr.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("ReadMessage = %q, want %q", got, want)
+		}
+	}
+	if _, err := mr.ReadMessage(); err != io.EOF {
+		t.Errorf("ReadMessage at end of stream = %v, want io.EOF", err)
+	}
+}
+
+func TestMessageReaderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		opts   []FramerOption
+		want   error
+	}{
+		{"partial indicator", "00", nil, io.ErrUnexpectedEOF},
+		{"partial payload", "0005abc", nil, io.ErrUnexpectedEOF},
+		{"over limit", "0100" + string(make([]byte, 100)), []FramerOption{WithMaxMessageSize(50)}, ErrMessageTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr, err := NewMessageReader(bytes.NewBufferString(tt.stream), asciiIndicator, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := mr.ReadMessage(); !errors.Is(err, tt.want) {
+				t.Errorf("ReadMessage = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewMessageReader(nil, LengthIndicatorConfig{}); !errors.Is(err, ErrNoLengthIndicator) {
+		t.Errorf("NewMessageReader without an indicator = %v, want ErrNoLengthIndicator", err)
+	}
+}
+
+func TestMessageWriterLimit(t *testing.T) {
+	var stream bytes.Buffer
+	mw, err := NewMessageWriter(&stream, asciiIndicator, WithMaxMessageSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.WriteMessage(make([]byte, 11)); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("WriteMessage = %v, want ErrMessageTooLarge", err)
+	}
+
+	m := newTestMessage(t, NewCompiledPackager(newTestConfig()), "0800", map[int]string{11: "000001", 70: "301"})
+	if err := mw.WriteMessageFrom(m); !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("WriteMessageFrom = %v, want ErrMessageTooLarge", err)
+	}
+	if stream.Len() != 0 {
+		t.Errorf("rejected messages wrote %d bytes", stream.Len())
+	}
+}
+
+func TestReadMessageInto(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig())
+	m := newTestMessage(t, pkg, "0800", map[int]string{11: "000001", 70: "301"})
+	buf := make([]byte, 256)
+	n, err := m.Pack(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stream bytes.Buffer
+	mw, _ := NewMessageWriter(&stream, asciiIndicator)
+	if err := mw.WriteMessageFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	// A payload with a byte the message does not consume
+	if err := mw.WriteMessage(append(buf[:n:n], ' ')); err != nil {
+		t.Fatal(err)
+	}
+
+	mr, _ := NewMessageReader(&stream, asciiIndicator)
+	got := NewMessage(WithPackager(pkg))
+	if err := mr.ReadMessageInto(got); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := got.GetString(70); s != "301" {
+		t.Errorf("DE 70 = %q", s)
+	}
+	if err := mr.ReadMessageInto(NewMessage(WithPackager(pkg))); !errors.Is(err, ErrTrailingBytes) {
+		t.Errorf("ReadMessageInto = %v, want ErrTrailingBytes", err)
+	}
+}
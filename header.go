@@ -0,0 +1,347 @@
+package iso8583
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// HeaderCodec describes the structure of a message header that precedes the MTI.
+// Implementations give access to named header sub-fields and know how to
+// turn a request header into a response header.
+type HeaderCodec interface {
+	// Length returns the header length in bytes found at the start of data.
+	// Headers that carry their own length (e.g., Visa) read it from data.
+	Length(data []byte) (int, error)
+	// Get returns the value of a named header sub-field.
+	Get(header []byte, name string) (string, error)
+	// Set writes a named header sub-field into header in place.
+	Set(header []byte, name, value string) error
+	// Reverse returns a new header for the response to a message with the
+	// given header, with source and destination swapped.
+	Reverse(header []byte) []byte
+	// New returns a new header populated with default values.
+	New() []byte
+}
+
+// MessageLengthSetter is implemented by header codecs whose header may carry
+// the total message length. Pack calls SetMessageLength after the message
+// body is written, if HasMessageLength reports true.
+type MessageLengthSetter interface {
+	HasMessageLength() bool
+	SetMessageLength(header []byte, msgLen int) error
+}
+
+// HeaderField describes one fixed-position sub-field of a HeaderLayout.
+type HeaderField struct {
+	Name   string // Sub-field name used by Get/Set
+	Offset int    // Byte offset from the start of the header
+	Length int    // Length in bytes
+	Binary bool   // If true, values are exposed as uppercase hex strings
+}
+
+// HeaderLayout is a HeaderCodec for headers made of fixed-position sub-fields.
+// The built-in TPDU, Base24 and Visa headers are HeaderLayouts, and custom
+// layouts can be registered with RegisterHeaderCodec.
+type HeaderLayout struct {
+	Size          int  // Header length in bytes (used when LengthByte is false)
+	LengthByte    bool // If true, the first byte holds the header length
+	Fields        []HeaderField
+	Swap          [][2]string // Pairs of sub-fields exchanged by Reverse
+	MessageLength string      // Binary sub-field set to the total message length by Pack (optional)
+	Default       []byte      // Header returned by New
+}
+
+// Length returns the header length found at the start of data.
+func (hl *HeaderLayout) Length(data []byte) (int, error) {
+	if !hl.LengthByte {
+		return hl.Size, nil
+	}
+	if len(data) < 1 {
+		return 0, ErrInvalidHeader
+	}
+	return int(data[0]), nil
+}
+
+// Get returns the value of a named sub-field.
+func (hl *HeaderLayout) Get(header []byte, name string) (string, error) {
+	hf, err := hl.lookup(header, name)
+	if err != nil {
+		return "", err
+	}
+	value := header[hf.Offset : hf.Offset+hf.Length]
+	if hf.Binary {
+		return fmt.Sprintf("%X", value), nil
+	}
+	return string(value), nil
+}
+
+// Set writes a named sub-field. Binary sub-fields take a hex string.
+// The value must exactly fill the sub-field.
+func (hl *HeaderLayout) Set(header []byte, name, value string) error {
+	hf, err := hl.lookup(header, name)
+	if err != nil {
+		return err
+	}
+
+	raw := []byte(value)
+	if hf.Binary {
+		raw, err = hex.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("%w: header field %s: %v", ErrInvalidHeader, name, err)
+		}
+	}
+	if len(raw) != hf.Length {
+		return fmt.Errorf("%w: header field %s expects %d bytes, got %d", ErrInvalidHeader, name, hf.Length, len(raw))
+	}
+	copy(header[hf.Offset:], raw)
+	return nil
+}
+
+// Reverse returns a copy of header with each Swap pair exchanged.
+func (hl *HeaderLayout) Reverse(header []byte) []byte {
+	reversed := make([]byte, len(header))
+	copy(reversed, header)
+
+	for _, pair := range hl.Swap {
+		a, errA := hl.lookup(header, pair[0])
+		b, errB := hl.lookup(header, pair[1])
+		if errA != nil || errB != nil || a.Length != b.Length {
+			continue
+		}
+		copy(reversed[a.Offset:a.Offset+a.Length], header[b.Offset:b.Offset+b.Length])
+		copy(reversed[b.Offset:b.Offset+b.Length], header[a.Offset:a.Offset+a.Length])
+	}
+	return reversed
+}
+
+// New returns a copy of the default header.
+func (hl *HeaderLayout) New() []byte {
+	header := make([]byte, len(hl.Default))
+	copy(header, hl.Default)
+	return header
+}
+
+// HasMessageLength reports whether the layout has a MessageLength sub-field.
+func (hl *HeaderLayout) HasMessageLength() bool {
+	return hl.MessageLength != ""
+}
+
+// SetMessageLength writes msgLen into the MessageLength sub-field as a
+// big-endian binary number, if the layout has one.
+func (hl *HeaderLayout) SetMessageLength(header []byte, msgLen int) error {
+	if hl.MessageLength == "" {
+		return nil
+	}
+	hf, err := hl.lookup(header, hl.MessageLength)
+	if err != nil {
+		return err
+	}
+	if hf.Length < 4 && msgLen >= 1<<(uint(hf.Length)*8) {
+		return fmt.Errorf("%w: message length %d exceeds header field %s", ErrInvalidHeader, msgLen, hf.Name)
+	}
+	for i := hf.Offset + hf.Length - 1; i >= hf.Offset; i-- {
+		header[i] = byte(msgLen)
+		msgLen >>= 8
+	}
+	return nil
+}
+
+// lookup finds a sub-field by name and checks that header is long enough to hold it.
+func (hl *HeaderLayout) lookup(header []byte, name string) (HeaderField, error) {
+	for _, hf := range hl.Fields {
+		if hf.Name != name {
+			continue
+		}
+		if len(header) < hf.Offset+hf.Length {
+			return hf, fmt.Errorf("%w: header too short for field %s", ErrInvalidHeader, name)
+		}
+		return hf, nil
+	}
+	return HeaderField{}, fmt.Errorf("%w: unknown header field %q", ErrInvalidHeader, name)
+}
+
+// TPDUHeader is the 5-byte binary Transport Protocol Data Unit header:
+// a 1-byte ID (usually 0x60) followed by 2-byte destination and origin NIIs.
+var TPDUHeader = &HeaderLayout{
+	Size: 5,
+	Fields: []HeaderField{
+		{Name: "id", Offset: 0, Length: 1, Binary: true},
+		{Name: "destination", Offset: 1, Length: 2, Binary: true},
+		{Name: "origin", Offset: 3, Length: 2, Binary: true},
+	},
+	Swap:    [][2]string{{"destination", "origin"}},
+	Default: []byte{0x60, 0x00, 0x00, 0x00, 0x00},
+}
+
+// Base24Header is the 12-byte ASCII BASE24 "ISO" header.
+var Base24Header = &HeaderLayout{
+	Size: 12,
+	Fields: []HeaderField{
+		{Name: "prefix", Offset: 0, Length: 3},
+		{Name: "product_indicator", Offset: 3, Length: 2},
+		{Name: "release_number", Offset: 5, Length: 2},
+		{Name: "status", Offset: 7, Length: 3},
+		{Name: "originator", Offset: 10, Length: 1},
+		{Name: "responder", Offset: 11, Length: 1},
+	},
+	Swap:    [][2]string{{"originator", "responder"}},
+	Default: []byte("ISO016000000"),
+}
+
+// VisaHeader is the 22-byte binary Visa (BASE I / V.I.P.) header.
+// The first byte holds the header length and bytes 4-5 the total message length.
+var VisaHeader = &HeaderLayout{
+	LengthByte: true,
+	Fields: []HeaderField{
+		{Name: "header_length", Offset: 0, Length: 1, Binary: true},
+		{Name: "header_format", Offset: 1, Length: 1, Binary: true},
+		{Name: "text_format", Offset: 2, Length: 1, Binary: true},
+		{Name: "message_length", Offset: 3, Length: 2, Binary: true},
+		{Name: "destination", Offset: 5, Length: 3, Binary: true},
+		{Name: "source", Offset: 8, Length: 3, Binary: true},
+		{Name: "round_trip_info", Offset: 11, Length: 1, Binary: true},
+		{Name: "base1_flags", Offset: 12, Length: 2, Binary: true},
+		{Name: "message_status_flags", Offset: 14, Length: 3, Binary: true},
+		{Name: "batch_number", Offset: 17, Length: 1, Binary: true},
+		{Name: "reserved", Offset: 18, Length: 3, Binary: true},
+		{Name: "user_info", Offset: 21, Length: 1, Binary: true},
+	},
+	Swap:          [][2]string{{"destination", "source"}},
+	MessageLength: "message_length",
+	Default: []byte{
+		0x16, 0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	},
+}
+
+// headerCodecs holds the codecs selectable through HeaderConfig.Format.
+var (
+	headerCodecs = map[string]HeaderCodec{
+		"tpdu":   TPDUHeader,
+		"base24": Base24Header,
+		"visa":   VisaHeader,
+	}
+	headerCodecsMu sync.RWMutex
+)
+
+// RegisterHeaderCodec makes a HeaderCodec selectable by name through HeaderConfig.Format.
+func RegisterHeaderCodec(name string, codec HeaderCodec) {
+	headerCodecsMu.Lock()
+	defer headerCodecsMu.Unlock()
+	headerCodecs[strings.ToLower(name)] = codec
+}
+
+// lookupHeaderCodec returns the codec registered under name, or nil.
+func lookupHeaderCodec(name string) HeaderCodec {
+	if name == "" {
+		return nil
+	}
+	headerCodecsMu.RLock()
+	defer headerCodecsMu.RUnlock()
+	return headerCodecs[strings.ToLower(name)]
+}
+
+// unpackHeader reads the header at the start of data according to the
+// packager's HeaderConfig and codec.
+// Returns the decoded header and the number of wire bytes consumed.
+func (cp *CompiledPackager) unpackHeader(data []byte) ([]byte, int, error) {
+	if cp.headerConfig.Type == HeaderHex {
+		// Header carried as ASCII hex: decode the configured number of bytes
+		wireLen := cp.headerConfig.Length * 2
+		if len(data) < wireLen {
+			return nil, 0, ErrInvalidHeader
+		}
+		header := make([]byte, cp.headerConfig.Length)
+		if _, err := hex.Decode(header, data[:wireLen]); err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+		}
+		return header, wireLen, nil
+	}
+
+	headerLen := cp.headerConfig.Length
+	if cp.headerCodec != nil {
+		n, err := cp.headerCodec.Length(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		headerLen = n
+	} else if cp.headerConfig.Type == HeaderCustom {
+		return nil, 0, fmt.Errorf("%w: no header codec registered for format %q", ErrInvalidHeader, cp.headerConfig.Format)
+	}
+
+	if len(data) < headerLen {
+		return nil, 0, ErrInvalidHeader
+	}
+	return data[:headerLen], headerLen, nil // Zero-copy slice
+}
+
+// packHeader writes header into buf according to the packager's HeaderConfig.
+// Returns the number of bytes written.
+func (cp *CompiledPackager) packHeader(buf, header []byte) (int, error) {
+	if cp != nil && cp.headerConfig.Type == HeaderHex {
+		if len(buf) < len(header)*2 {
+			return 0, ErrBufferTooSmall
+		}
+		encodeHexUpper(buf, header)
+		return len(header) * 2, nil
+	}
+	if len(buf) < len(header) {
+		return 0, ErrBufferTooSmall
+	}
+	return copy(buf, header), nil
+}
+
+// Header returns the message header.
+func (m *Message) Header() []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.header
+}
+
+// HeaderField returns a named header sub-field using the packager's header codec.
+func (m *Message) HeaderField(name string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	codec := m.headerCodec()
+	if codec == nil {
+		return "", fmt.Errorf("%w: no header codec configured", ErrInvalidHeader)
+	}
+	return codec.Get(m.header, name)
+}
+
+// SetHeaderField sets a named header sub-field using the packager's header codec.
+// If the message has no header yet, it starts from the codec's default header.
+func (m *Message) SetHeaderField(name, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	codec := m.headerCodec()
+	if codec == nil {
+		return fmt.Errorf("%w: no header codec configured", ErrInvalidHeader)
+	}
+
+	// Copy before writing: an unpacked header references the caller's buffer
+	var header []byte
+	if len(m.header) == 0 {
+		header = codec.New()
+	} else {
+		header = make([]byte, len(m.header))
+		copy(header, m.header)
+	}
+	if err := codec.Set(header, name, value); err != nil {
+		return err
+	}
+	m.header = header
+	return nil
+}
+
+// headerCodec returns the packager's header codec, or nil.
+func (m *Message) headerCodec() HeaderCodec {
+	if m.packager == nil {
+		return nil
+	}
+	return m.packager.headerCodec
+}
@@ -0,0 +1,160 @@
+package iso8583
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestHeaderLayoutFields(t *testing.T) {
+	header := TPDUHeader.New()
+	if err := TPDUHeader.Set(header, "destination", "0012"); err != nil {
+		t.Fatal(err)
+	}
+	if err := TPDUHeader.Set(header, "origin", "0034"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(header, []byte{0x60, 0x00, 0x12, 0x00, 0x34}) {
+		t.Errorf("header = %X", header)
+	}
+
+	reversed := TPDUHeader.Reverse(header)
+	if got, _ := TPDUHeader.Get(reversed, "destination"); got != "0034" {
+		t.Errorf("reversed destination = %s, want 0034", got)
+	}
+	if got, _ := TPDUHeader.Get(header, "destination"); got != "0012" {
+		t.Error("Reverse modified its input")
+	}
+
+	b24 := Base24Header.New()
+	if err := Base24Header.Set(b24, "status", "123"); err != nil {
+		t.Fatal(err)
+	}
+	if string(b24) != "ISO016012300" {
+		t.Errorf("Base24 header = %q", b24)
+	}
+
+	errs := []error{
+		TPDUHeader.Set(header, "destination", "12"),   // Too short
+		TPDUHeader.Set(header, "destination", "zzzz"), // Not hex
+		TPDUHeader.Set(header, "unknown", "00"),       // Unknown sub-field
+		TPDUHeader.Set(header[:2], "origin", "0000"),  // Header too short
+		Base24Header.Set(b24, "originator", "12"),     // Too long
+	}
+	for i, err := range errs {
+		if !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("case %d: Set = %v, want ErrInvalidHeader", i, err)
+		}
+	}
+}
+
+func TestHeaderRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		config HeaderConfig
+		header []byte
+		wire   []byte
+	}{
+		{"binary", HeaderConfig{Type: HeaderBinary, Length: 5, Format: "tpdu"}, []byte{0x60, 0x00, 0x12, 0x00, 0x34}, []byte{0x60, 0x00, 0x12, 0x00, 0x34}},
+		{"ASCII", HeaderConfig{Type: HeaderASCII, Length: 12, Format: "base24"}, []byte("ISO016000000"), []byte("ISO016000000")},
+		{"hex", HeaderConfig{Type: HeaderHex, Length: 5, Format: "tpdu"}, []byte{0x60, 0x00, 0x12, 0x00, 0x34}, []byte("6000120034")},
+		{"plain", HeaderConfig{Type: HeaderASCII, Length: 4}, []byte("HDR1"), []byte("HDR1")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := NewCompiledPackager(newTestConfig(WithHeaderConfig(tt.config)))
+			m := NewMessage(WithPackager(pkg), WithHeader(tt.header))
+			m.SetMTI([]byte("0800"))
+			m.SetField(70, "301")
+
+			buf := make([]byte, 256)
+			n, err := m.Pack(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(buf[:n], append(tt.wire, "0800"...)) {
+				t.Errorf("packed %q, want header %q", buf[:n], tt.wire)
+			}
+
+			got := NewMessage(WithPackager(pkg))
+			if err := got.Unpack(buf[:n]); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Header(), tt.header) {
+				t.Errorf("Header = %X, want %X", got.Header(), tt.header)
+			}
+		})
+	}
+}
+
+func TestVisaHeaderMessageLength(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig(WithHeaderConfig(HeaderConfig{Type: HeaderBinary, Format: "visa"})))
+	m := NewMessage(WithPackager(pkg))
+	m.SetMTI([]byte("0800"))
+	m.SetField(70, "301")
+	if err := m.SetHeaderField("destination", "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 256)
+	n, err := m.Pack(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := int(buf[3])<<8 | int(buf[4]); got != n {
+		t.Errorf("header message length = %d, want %d", got, n)
+	}
+
+	got := NewMessage(WithPackager(pkg))
+	if err := got.Unpack(buf[:n]); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := got.HeaderField("destination"); v != "123456" {
+		t.Errorf("destination = %s", v)
+	}
+
+	res, err := got.CreateResponse(RC_APPROVED)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := res.HeaderField("source"); v != "123456" {
+		t.Errorf("response source = %s, want the request destination", v)
+	}
+}
+
+func TestHeaderErrors(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig(WithHeaderConfig(HeaderConfig{Type: HeaderCustom, Format: "no-such-codec"})))
+	if err := NewMessage(WithPackager(pkg)).Unpack([]byte("HDR0800")); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("Unpack with an unregistered codec = %v, want ErrInvalidHeader", err)
+	}
+
+	pkg = NewCompiledPackager(newTestConfig(WithHeaderConfig(HeaderConfig{Type: HeaderHex, Length: 5})))
+	if err := NewMessage(WithPackager(pkg)).Unpack([]byte("60001Z00340800")); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("Unpack of a non-hex header = %v, want ErrInvalidHeader", err)
+	}
+
+	if _, err := NewMessage().HeaderField("id"); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("HeaderField without a codec = %v, want ErrInvalidHeader", err)
+	}
+}
+
+func TestRegisterHeaderCodec(t *testing.T) {
+	layout := &HeaderLayout{
+		Size:    4,
+		Fields:  []HeaderField{{Name: "src", Offset: 0, Length: 2}, {Name: "dst", Offset: 2, Length: 2}},
+		Swap:    [][2]string{{"src", "dst"}},
+		Default: []byte("AABB"),
+	}
+	RegisterHeaderCodec("Test-Swap", layout)
+
+	pkg := NewCompiledPackager(newTestConfig(WithHeaderConfig(HeaderConfig{Type: HeaderCustom, Format: "test-swap"})))
+	m := NewMessage(WithPackager(pkg))
+	m.SetMTI([]byte("0800"))
+	m.SetField(70, "301")
+
+	got := repack(t, m)
+	if !bytes.Equal(got.Header(), []byte("AABB")) {
+		t.Errorf("Header = %q, want the codec default", got.Header())
+	}
+}
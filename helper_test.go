@@ -0,0 +1,60 @@
+package iso8583
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// newTestConfig returns a packager config with its own copy of the default
+// field layout, so options such as WithFieldConfig leave DefaultConfigField
+// untouched for other tests.
+func newTestConfig(opts ...PackagerOption) *PackagerConfig {
+	config := DefaultPackagerConfig()
+	config.Fields = make(map[int]FieldConfig, len(DefaultConfigField))
+	for fieldNum, fieldConfig := range DefaultConfigField {
+		config.Fields[fieldNum] = fieldConfig
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// newTestMessage returns a message with the given MTI and string fields.
+func newTestMessage(t *testing.T, pkg *CompiledPackager, mti string, fields map[int]string) *Message {
+	t.Helper()
+	m := NewMessage(WithPackager(pkg))
+	if err := m.SetMTI([]byte(mti)); err != nil {
+		t.Fatal(err)
+	}
+	for fieldNum, value := range fields {
+		if err := m.SetField(fieldNum, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// repack packs m and unpacks the result into a new message.
+func repack(t *testing.T, m *Message) *Message {
+	t.Helper()
+	buf := make([]byte, 2048)
+	n, err := m.Pack(buf)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	out := NewMessage(WithPackager(m.packager))
+	if err := out.Unpack(buf[:n]); err != nil {
+		t.Fatalf("Unpack: %v", err)
+	}
+	return out
+}
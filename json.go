@@ -0,0 +1,438 @@
+package iso8583
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// messageJSON is the top-level JSON document of a Message.
+type messageJSON struct {
+	MTI    string          `json:"mti"`
+	Header string          `json:"header,omitempty"` // Hex
+	Fields json.RawMessage `json:"fields"`
+}
+
+// jsonMember is one key/value pair of a JSON object, kept in document order.
+type jsonMember struct {
+	key   string
+	value json.RawMessage
+}
+
+// MarshalJSON implements json.Marshaler.
+// Fields are keyed by their configured alias, or by number if none is set.
+// Binary fields are hex strings, TLV fields are objects keyed by tag
+// (nested for constructed EMV tags) and composite fields are objects keyed
+// by subfield alias or number.
+func (m *Message) MarshalJSON() ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var fields bytes.Buffer
+	fields.WriteByte('{')
+	first := true
+	for fieldNum := 2; fieldNum <= MaxFieldNumber; fieldNum++ {
+		if !m.isFieldPresent(fieldNum) {
+			continue
+		}
+
+		value, err := m.marshalFieldJSON(fieldNum)
+		if err != nil {
+			return nil, &FieldError{Field: fieldNum, Err: err}
+		}
+
+		key := strconv.Itoa(fieldNum)
+		if config, exists := m.fieldConfig(fieldNum); exists && config.Alias != "" {
+			key = config.Alias
+		}
+		writeJSONMember(&fields, &first, key, value)
+	}
+	fields.WriteByte('}')
+
+	doc := messageJSON{
+		MTI:    string(m.mti[:]),
+		Fields: fields.Bytes(),
+	}
+	if len(m.header) > 0 {
+		doc.Header = fmt.Sprintf("%X", m.header)
+	}
+	return json.Marshal(doc)
+}
+
+// marshalFieldJSON returns the JSON value of a single field.
+func (m *Message) marshalFieldJSON(fieldNum int) ([]byte, error) {
+	config, _ := m.fieldConfig(fieldNum)
+
+	if tlvs, ok := m.tlvData[fieldNum]; ok {
+		tlvType := TLVStandard
+		if m.packager != nil {
+			if parser, exists := m.packager.GetTLVParser(fieldNum); exists {
+				tlvType = parser.tlvType
+			}
+		}
+		var buf bytes.Buffer
+		if err := marshalTLVsJSON(&buf, tlvs, tlvType); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	if subfields, _ := m.subfieldsOf(fieldNum); subfields != nil {
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		first := true
+		for _, num := range subfieldNumbers(config) {
+			value, present := subfields[num]
+			if !present {
+				continue
+			}
+			sub := config.Subfields[num]
+			encoded, err := json.Marshal(displayValue(sub, value))
+			if err != nil {
+				return nil, err
+			}
+			key := strconv.Itoa(num)
+			if sub.Alias != "" {
+				key = sub.Alias
+			}
+			writeJSONMember(&buf, &first, key, encoded)
+		}
+		buf.WriteByte('}')
+		return buf.Bytes(), nil
+	}
+
+	return json.Marshal(displayValue(config, m.fields[fieldNum-1].Bytes()))
+}
+
+// marshalTLVsJSON writes tlvs as a JSON object in their original order.
+// EMV and standard tags and values are hex; ASCII TLV uses the raw text.
+func marshalTLVsJSON(buf *bytes.Buffer, tlvs []TLV, tlvType TLVType) error {
+	buf.WriteByte('{')
+	first := true
+	for _, tlv := range tlvs {
+		key := string(tlv.Tag)
+		if tlvType != TLVASCII {
+			key = fmt.Sprintf("%X", tlv.Tag)
+		}
+
+		var value []byte
+		if len(tlv.Children) > 0 {
+			var children bytes.Buffer
+			if err := marshalTLVsJSON(&children, tlv.Children, tlvType); err != nil {
+				return err
+			}
+			value = children.Bytes()
+		} else {
+			text := string(tlv.Value)
+			if tlvType != TLVASCII {
+				text = fmt.Sprintf("%X", tlv.Value)
+			}
+			encoded, err := json.Marshal(text)
+			if err != nil {
+				return err
+			}
+			value = encoded
+		}
+		writeJSONMember(buf, &first, key, value)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It replaces the message's
+// MTI, header and fields, keeping its packager and validation level.
+// Field keys may be numbers or configured aliases.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var doc messageJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	mti, err := ParseMTI(doc.MTI)
+	if err != nil {
+		return err
+	}
+	var header []byte
+	if doc.Header != "" {
+		if header, err = hex.DecodeString(doc.Header); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+		}
+	}
+
+	m.mu.Lock()
+	packager, level, mode := m.packager, m.validationLevel, m.validationMode
+	m.reset()
+	m.setPackager(packager)
+	m.validationLevel = level
+	m.validationMode = mode
+	m.mti = mti
+	m.selectVersionProfile()
+	m.header = header
+	m.mu.Unlock()
+
+	if len(doc.Fields) == 0 || string(doc.Fields) == "null" {
+		return nil
+	}
+	members, err := decodeJSONObject(doc.Fields)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		fieldNum, err := m.resolveFieldKey(member.key)
+		if err != nil {
+			return err
+		}
+		if err := m.unmarshalFieldJSON(fieldNum, member.value); err != nil {
+			return &FieldError{Field: fieldNum, Err: err}
+		}
+	}
+	return nil
+}
+
+// resolveFieldKey maps a JSON field key (number or alias) to a field number.
+func (m *Message) resolveFieldKey(key string) (int, error) {
+	if fieldNum, err := strconv.Atoi(key); err == nil {
+		return fieldNum, nil
+	}
+	if m.packager != nil {
+		if fieldNum, exists := m.packager.FieldByAlias(key); exists {
+			return fieldNum, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown field %q", ErrInvalidField, key)
+}
+
+// unmarshalFieldJSON sets a field from its JSON value.
+func (m *Message) unmarshalFieldJSON(fieldNum int, raw json.RawMessage) error {
+	config, _ := m.fieldConfig(fieldNum)
+
+	// TLV and composite fields may be given as objects
+	if len(raw) > 0 && raw[0] == '{' {
+		if m.packager != nil {
+			if parser, exists := m.packager.GetTLVParser(fieldNum); exists {
+				return m.unmarshalTLVFieldJSON(fieldNum, parser, raw)
+			}
+		}
+		if len(config.Subfields) > 0 {
+			return m.unmarshalCompositeFieldJSON(fieldNum, config, raw)
+		}
+		return fmt.Errorf("object given for a field without TLV or subfields")
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return err
+	}
+	if config.Type == FieldTypeB {
+		value, err := hex.DecodeString(text)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidEncoding, err)
+		}
+		if err := m.SetField(fieldNum, value); err != nil {
+			return err
+		}
+	} else if err := m.SetField(fieldNum, text); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.decodeFieldStructure(fieldNum)
+}
+
+// unmarshalTLVFieldJSON encodes a TLV object into the field's raw value.
+func (m *Message) unmarshalTLVFieldJSON(fieldNum int, parser *TLVParser, raw json.RawMessage) error {
+	tlvs, err := unmarshalTLVsJSON(raw, parser.tlvType)
+	if err != nil {
+		return err
+	}
+	data, err := encodeTLVs(parser, tlvs)
+	if err != nil {
+		return err
+	}
+	if err := m.SetField(fieldNum, data); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.decodeFieldStructure(fieldNum)
+}
+
+// unmarshalTLVsJSON decodes a JSON object into TLV entries, in document order.
+func unmarshalTLVsJSON(raw json.RawMessage, tlvType TLVType) ([]TLV, error) {
+	members, err := decodeJSONObject(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	tlvs := make([]TLV, 0, len(members))
+	for _, member := range members {
+		tag := []byte(member.key)
+		if tlvType != TLVASCII {
+			if tag, err = hex.DecodeString(member.key); err != nil {
+				return nil, fmt.Errorf("%w: tag %q", ErrInvalidTLV, member.key)
+			}
+		}
+
+		if len(member.value) > 0 && member.value[0] == '{' {
+			if tlvType != TLVEMV {
+				return nil, fmt.Errorf("%w: nested tag %q requires EMV TLV", ErrInvalidTLV, member.key)
+			}
+			children, err := unmarshalTLVsJSON(member.value, tlvType)
+			if err != nil {
+				return nil, err
+			}
+			tlvs = append(tlvs, TLV{Tag: tag, Children: children})
+			continue
+		}
+
+		var text string
+		if err := json.Unmarshal(member.value, &text); err != nil {
+			return nil, err
+		}
+		value := []byte(text)
+		if tlvType != TLVASCII {
+			if value, err = hex.DecodeString(text); err != nil {
+				return nil, &TLVError{Tag: tag, Err: ErrInvalidEncoding}
+			}
+		}
+		tlvs = append(tlvs, TLV{Tag: tag, Length: len(value), Value: value})
+	}
+	return tlvs, nil
+}
+
+// unmarshalCompositeFieldJSON builds a composite field from its subfields object.
+func (m *Message) unmarshalCompositeFieldJSON(fieldNum int, config FieldConfig, raw json.RawMessage) error {
+	members, err := decodeJSONObject(raw)
+	if err != nil {
+		return err
+	}
+
+	subfields := make(map[int][]byte, len(members))
+	for _, member := range members {
+		num, err := strconv.Atoi(member.key)
+		if err != nil {
+			num = 0
+			for n, sub := range config.Subfields {
+				if sub.Alias == member.key {
+					num = n
+					break
+				}
+			}
+		}
+		sub, exists := config.Subfields[num]
+		if !exists {
+			return fmt.Errorf("subfield %q: %w", member.key, ErrFieldNotConfigured)
+		}
+
+		var text string
+		if err := json.Unmarshal(member.value, &text); err != nil {
+			return err
+		}
+		value := []byte(text)
+		if sub.Type == FieldTypeB {
+			if value, err = hex.DecodeString(text); err != nil {
+				return fmt.Errorf("subfield %d: %w", num, ErrInvalidEncoding)
+			}
+		}
+		subfields[num] = value
+	}
+
+	data, err := packSubfields(config, subfields, m.packager.bitmapEncoding)
+	if err != nil {
+		return err
+	}
+	if err := m.SetField(fieldNum, string(data)); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.decodeFieldStructure(fieldNum)
+}
+
+// decodeFieldStructure re-decodes the TLV entries or subfields of a field
+// from its raw value, as Unpack would.
+func (m *Message) decodeFieldStructure(fieldNum int) error {
+	if m.packager == nil {
+		return nil
+	}
+	data := m.fields[fieldNum-1].Bytes()
+
+	if parser, exists := m.packager.tlvParsers[fieldNum]; exists {
+		tlvs, err := parser.ParseTLV(data)
+		if err != nil {
+			return err
+		}
+		if m.tlvData == nil {
+			m.tlvData = make(map[int][]TLV)
+		}
+		m.tlvData[fieldNum] = tlvs
+	}
+
+	if config := m.packager.fieldConfigs[fieldNum]; len(config.Subfields) > 0 {
+		subfields, err := unpackSubfields(config, data, m.packager.bitmapEncoding)
+		if err != nil {
+			return err
+		}
+		if m.subfields == nil {
+			m.subfields = make(map[int]map[int][]byte)
+		}
+		m.subfields[fieldNum] = subfields
+	}
+	return nil
+}
+
+// fieldConfig returns the packager's configuration for a field, if any.
+func (m *Message) fieldConfig(fieldNum int) (FieldConfig, bool) {
+	if m.packager == nil {
+		return FieldConfig{}, false
+	}
+	return m.packager.GetFieldConfig(fieldNum)
+}
+
+// decodeJSONObject splits a JSON object into its members in document order.
+func decodeJSONObject(data []byte) ([]jsonMember, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("expected JSON object")
+	}
+
+	var members []jsonMember
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		members = append(members, jsonMember{key: key, value: value})
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// writeJSONMember appends "key":value to an object being built in buf.
+func writeJSONMember(buf *bytes.Buffer, first *bool, key string, value []byte) {
+	if !*first {
+		buf.WriteByte(',')
+	}
+	*first = false
+	encodedKey, _ := json.Marshal(key)
+	buf.Write(encodedKey)
+	buf.WriteByte(':')
+	buf.Write(value)
+}
@@ -0,0 +1,100 @@
+package iso8583
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func newJSONPackager() *CompiledPackager {
+	return NewCompiledPackager(newTestConfig(
+		WithHeaderConfig(HeaderConfig{Type: HeaderBinary, Length: 5, Format: "tpdu"}),
+		WithTLVConfig(TLVConfig{Type: TLVEMV, Enabled: true, MaxDepth: 2}),
+		WithFieldConfig(11, FieldConfig{Type: FieldTypeN, Length: LengthFixed, MaxLength: 6, Alias: "stan"}),
+		WithFieldConfig(52, FieldConfig{Type: FieldTypeB, Length: LengthFixed, MaxLength: 8}),
+		WithFieldConfig(55, FieldConfig{Type: FieldTypeB, Length: LengthLLLVAR, MaxLength: 999, TLV: &FieldTLVConfig{Type: TLVEMV}}),
+		WithFieldConfig(90, FieldConfig{Type: FieldTypeN, Length: LengthFixed, MaxLength: 10, Subfields: map[int]FieldConfig{
+			1: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Alias: "mti"},
+			2: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 6},
+		}}),
+	))
+}
+
+func TestMessageJSONRoundTrip(t *testing.T) {
+	pkg := newJSONPackager()
+	m := newTestMessage(t, pkg, "0400", map[int]string{3: "000000", 11: "000123", 90: "0200000123"})
+	m.SetField(52, mustHex(t, "0123456789ABCDEF"))
+	m.SetField(55, mustHex(t, "9F0206000000001234700495020001"))
+	m.SetHeaderField("destination", "0012")
+
+	// DE 55 is decoded when the message is unpacked
+	m = repack(t, m)
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"mti":"0400","header":"6000120000","fields":{"3":"000000","stan":"000123","52":"0123456789ABCDEF",` +
+		`"55":{"9F02":"000000001234","70":{"95":"0001"}},"90":{"mti":"0200","2":"000123"}}}`
+	if string(data) != want {
+		t.Errorf("MarshalJSON =\n%s\nwant\n%s", data, want)
+	}
+
+	got := NewMessage(WithPackager(pkg))
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := json.Marshal(got)
+	if string(again) != want {
+		t.Errorf("JSON round trip =\n%s\nwant\n%s", again, want)
+	}
+	if v, _ := got.GetSubfield(90, 2); v != "000123" {
+		t.Errorf("DE 90.2 = %q", v)
+	}
+	if tlv, err := got.GetTagPath(55, "70/95"); err != nil || tlv.Hex() != "0001" {
+		t.Errorf("DE 55 70/95 = %v, %v", tlv, err)
+	}
+	buf := make([]byte, 256)
+	if _, err := got.Pack(buf); err != nil {
+		t.Errorf("Pack after UnmarshalJSON: %v", err)
+	}
+}
+
+func TestMarshalJSONRawComposite(t *testing.T) {
+	m := newTestMessage(t, newJSONPackager(), "0400", map[int]string{90: "0200000123"})
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"mti":"0400","fields":{"90":{"mti":"0200","2":"000123"}}}`; string(data) != want {
+		t.Errorf("MarshalJSON = %s, want %s", data, want)
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"bad MTI", `{"mti":"01","fields":{}}`, ErrInvalidMTI},
+		{"bad header", `{"mti":"0100","header":"zz","fields":{}}`, ErrInvalidHeader},
+		{"unknown alias", `{"mti":"0100","fields":{"nope":"1"}}`, ErrInvalidField},
+		{"bad hex", `{"mti":"0100","fields":{"52":"xyz"}}`, ErrInvalidEncoding},
+		{"bad tag", `{"mti":"0100","fields":{"55":{"zz":"00"}}}`, ErrInvalidTLV},
+		{"unknown subfield", `{"mti":"0100","fields":{"90":{"9":"1"}}}`, ErrFieldNotConfigured},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := json.Unmarshal([]byte(tt.data), NewMessage(WithPackager(newJSONPackager())))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("UnmarshalJSON = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := json.Unmarshal([]byte(`{"mti":"0100","fields":{"3":{"1":"x"}}}`), NewMessage(WithPackager(newJSONPackager()))); err == nil {
+		t.Error("object accepted for a plain field")
+	}
+}
@@ -0,0 +1,152 @@
+package iso8583
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestLengthIndicatorRoundTrip(t *testing.T) {
+	tests := []struct {
+		config LengthIndicatorConfig
+		msgLen int
+		want   []byte
+	}{
+		{LengthIndicatorConfig{Type: LengthIndicatorBinary, Length: 2}, 300, []byte{0x01, 0x2c}},
+		{LengthIndicatorConfig{Type: LengthIndicatorBinary, Length: 4}, 70000, []byte{0x00, 0x01, 0x11, 0x70}},
+		{LengthIndicatorConfig{Type: LengthIndicatorASCII, Length: 4}, 200, []byte("0200")},
+		{LengthIndicatorConfig{Type: LengthIndicatorHex, Length: 4}, 200, []byte("00C8")},
+	}
+
+	for _, tt := range tests {
+		buf := make([]byte, tt.config.Length)
+		n, err := WriteLengthIndicator(tt.msgLen, buf, tt.config)
+		if err != nil || !bytes.Equal(buf[:n], tt.want) {
+			t.Errorf("WriteLengthIndicator(%d, %+v) = %q, %v, want %q", tt.msgLen, tt.config, buf[:n], err, tt.want)
+			continue
+		}
+		msgLen, consumed, err := ReadLengthIndicator(buf, tt.config)
+		if err != nil || msgLen != tt.msgLen || consumed != tt.config.Length {
+			t.Errorf("ReadLengthIndicator(%q, %+v) = %d, %d, %v", buf, tt.config, msgLen, consumed, err)
+		}
+	}
+}
+
+func TestLengthIndicatorErrors(t *testing.T) {
+	ascii := LengthIndicatorConfig{Type: LengthIndicatorASCII, Length: 4}
+	binary := LengthIndicatorConfig{Type: LengthIndicatorBinary, Length: 2}
+
+	if _, err := WriteLengthIndicator(10000, make([]byte, 4), ascii); err == nil {
+		t.Error("WriteLengthIndicator accepted 10000 in 4 ASCII digits")
+	}
+	if _, err := WriteLengthIndicator(0x10000, make([]byte, 2), binary); err == nil {
+		t.Error("WriteLengthIndicator accepted 65536 in 2 bytes")
+	}
+	if _, err := WriteLengthIndicator(10, make([]byte, 1), binary); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("WriteLengthIndicator into a short buffer = %v, want ErrBufferTooSmall", err)
+	}
+	if _, _, err := ReadLengthIndicator([]byte("02"), ascii); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("ReadLengthIndicator of a short prefix = %v, want ErrInvalidLength", err)
+	}
+	if _, _, err := ReadLengthIndicator([]byte("02x0"), ascii); err == nil {
+		t.Error("ReadLengthIndicator accepted a non-digit prefix")
+	}
+}
+
+func newFramedPackager(config LengthIndicatorConfig) *CompiledPackager {
+	pc := newTestConfig()
+	pc.LengthIndicator = config
+	return NewCompiledPackager(pc)
+}
+
+func TestPackFramedRoundTrip(t *testing.T) {
+	for _, config := range []LengthIndicatorConfig{
+		{Type: LengthIndicatorNone},
+		{Type: LengthIndicatorBinary, Length: 2},
+		{Type: LengthIndicatorASCII, Length: 4},
+		{Type: LengthIndicatorHex, Length: 4},
+	} {
+		pkg := newFramedPackager(config)
+		m := newTestMessage(t, pkg, "0800", map[int]string{11: "000001", 70: "301"})
+
+		buf := make([]byte, 256)
+		n, err := m.PackFramed(buf)
+		if err != nil {
+			t.Fatalf("%+v: PackFramed: %v", config, err)
+		}
+		payloadLen, prefixLen, _ := ReadLengthIndicator(buf[:n], config)
+		if prefixLen+payloadLen != n {
+			t.Errorf("%+v: indicator declares %d bytes after %d, frame has %d", config, payloadLen, prefixLen, n)
+		}
+
+		got := NewMessage(WithPackager(pkg))
+		if err := got.UnpackFramed(buf[:n]); err != nil {
+			t.Fatalf("%+v: UnpackFramed: %v", config, err)
+		}
+		if s, _ := got.GetString(70); s != "301" {
+			t.Errorf("%+v: DE 70 = %q", config, s)
+		}
+	}
+}
+
+func TestUnpackFramedErrors(t *testing.T) {
+	config := LengthIndicatorConfig{Type: LengthIndicatorASCII, Length: 4}
+	pkg := newFramedPackager(config)
+	m := newTestMessage(t, pkg, "0800", map[int]string{11: "000001", 70: "301"})
+	buf := make([]byte, 256)
+	n, err := m.PackFramed(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := buf[:n]
+
+	// A frame whose indicator covers a padding byte the message does not use
+	padded := append(append([]byte(nil), frame...), ' ')
+	WriteLengthIndicator(n-4+1, padded, config)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"short indicator", frame[:2], ErrTruncatedMessage},
+		{"truncated payload", frame[:n-1], ErrTruncatedMessage},
+		{"trailing bytes", append(append([]byte(nil), frame...), '0'), ErrTrailingBytes},
+		{"unconsumed payload", padded, ErrTrailingBytes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewMessage(WithPackager(pkg)).UnpackFramed(tt.data)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("UnpackFramed = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackFramedConcurrentWithSetMTI(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig(WithVersionDetection(true)))
+	m := newTestMessage(t, pkg, "0800", map[int]string{11: "000001"})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		buf := make([]byte, 256)
+		for i := 0; i < 100; i++ {
+			if _, err := m.PackFramed(buf); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			m.SetMTI([]byte{"01"[i%2], '8', '0', '0'})
+		}
+	}()
+	wg.Wait()
+}
@@ -200,6 +200,13 @@ func validateFormat(value, format string) error {
 		if len(value) != 6 {
 			return fmt.Errorf("invalid HHMMSS format: expected 6 digits, got %d", len(value))
 		}
+	default:
+		// FieldConfig.Format names, e.g. MMDDhhmmss or YYMM
+		if layout, ok := timeLayout(format); ok {
+			if _, err := time.Parse(layout.layout, value); err != nil {
+				return fmt.Errorf("invalid %s date: %w", format, err)
+			}
+		}
 	}
 	return nil
 }
@@ -0,0 +1,162 @@
+package iso8583
+
+import "fmt"
+
+// lengthPrefixDigits returns the number of length digits for a variable-length type
+// (2 for LLVAR, 3 for LLLVAR, 4 for LLLLVAR), or 0 for fixed/unknown types.
+func lengthPrefixDigits(lengthType LengthType) int {
+	switch lengthType {
+	case LengthLLVAR:
+		return 2
+	case LengthLLLVAR:
+		return 3
+	case LengthLLLLVAR:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// lengthPrefixSize returns the number of wire bytes a length prefix of the
+// given digit count occupies in the given encoding.
+// BCD and binary prefixes pack into (digits+1)/2 bytes, e.g. 1 byte for LL
+// and 2 bytes for LLL/LLLL.
+func lengthPrefixSize(digits int, enc Encoding) int {
+	switch enc {
+	case EncodingBCD, EncodingBCDLeft, EncodingBinary:
+		return (digits + 1) / 2
+	default:
+		return digits
+	}
+}
+
+// lengthPrefixMax returns the largest length that fits in the prefix.
+func lengthPrefixMax(digits int, enc Encoding) int {
+	switch enc {
+	case EncodingBinary:
+		return 1<<(uint(lengthPrefixSize(digits, enc))*8) - 1
+	case EncodingHex:
+		return 1<<(uint(digits)*4) - 1
+	default:
+		return int(pow(10, float64(digits))) - 1
+	}
+}
+
+// readLengthPrefix decodes a field length prefix starting at offset.
+// Returns: field data length, new offset (after the prefix), error
+func readLengthPrefix(data []byte, offset, digits int, enc Encoding) (int, int, error) {
+	size := lengthPrefixSize(digits, enc)
+	if len(data) < offset+size {
+		return 0, offset, ErrInvalidLength
+	}
+	prefix := data[offset : offset+size]
+
+	length := 0
+	switch enc {
+	case EncodingASCII:
+		for _, ch := range prefix {
+			if ch < '0' || ch > '9' {
+				return 0, offset, ErrInvalidLength
+			}
+			length = length*10 + int(ch-'0')
+		}
+
+	case EncodingEBCDIC:
+		// EBCDIC digits are 0xF0-0xF9
+		for _, ch := range prefix {
+			if ch < 0xF0 || ch > 0xF9 {
+				return 0, offset, ErrInvalidLength
+			}
+			length = length*10 + int(ch-0xF0)
+		}
+
+	case EncodingBCD, EncodingBCDLeft:
+		for _, b := range prefix {
+			hi, lo := b>>4, b&0x0F
+			if hi > 9 || lo > 9 {
+				return 0, offset, ErrInvalidLength
+			}
+			length = length*100 + int(hi)*10 + int(lo)
+		}
+
+	case EncodingBinary:
+		for _, b := range prefix {
+			length = length<<8 | int(b)
+		}
+
+	case EncodingHex:
+		for _, ch := range prefix {
+			nibble, ok := hexNibble(ch)
+			if !ok {
+				return 0, offset, ErrInvalidLength
+			}
+			length = length<<4 | int(nibble)
+		}
+
+	default:
+		return 0, offset, ErrUnsupportedEncoding
+	}
+
+	return length, offset + size, nil
+}
+
+// writeLengthPrefix encodes a field length prefix into buf.
+// Returns the number of bytes written.
+func writeLengthPrefix(buf []byte, length, digits int, enc Encoding) (int, error) {
+	size := lengthPrefixSize(digits, enc)
+	if len(buf) < size {
+		return 0, ErrBufferTooSmall
+	}
+	if length > lengthPrefixMax(digits, enc) {
+		return 0, fmt.Errorf("%w: length %d does not fit in %d-digit prefix", ErrInvalidLength, length, digits)
+	}
+
+	switch enc {
+	case EncodingASCII:
+		writeIntToASCII(buf[:size], length, digits)
+
+	case EncodingEBCDIC:
+		writeIntToASCII(buf[:size], length, digits)
+		for i := 0; i < size; i++ {
+			buf[i] = asciiToEBCDIC[buf[i]]
+		}
+
+	case EncodingBCD, EncodingBCDLeft:
+		// Right-justified: pad digits up to a whole number of bytes
+		for i := size - 1; i >= 0; i-- {
+			buf[i] = byte(length%10) | byte((length/10)%10)<<4
+			length /= 100
+		}
+
+	case EncodingBinary:
+		for i := size - 1; i >= 0; i-- {
+			buf[i] = byte(length)
+			length >>= 8
+		}
+
+	case EncodingHex:
+		for i := size - 1; i >= 0; i-- {
+			buf[i] = hexTableUpper[length&0x0F]
+			length >>= 4
+		}
+
+	default:
+		return 0, ErrUnsupportedEncoding
+	}
+
+	return size, nil
+}
+
+// hexNibble returns the 4-bit value of an ASCII hex character.
+func hexNibble(ch byte) (byte, bool) {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return ch - '0', true
+	case ch >= 'A' && ch <= 'F':
+		return ch - 'A' + 10, true
+	case ch >= 'a' && ch <= 'f':
+		return ch - 'a' + 10, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,107 @@
+package iso8583
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestLengthPrefixRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int
+		digits int
+		enc    Encoding
+		wire   []byte
+	}{
+		{"ASCII LL", 19, 2, EncodingASCII, []byte("19")},
+		{"ASCII LLL", 7, 3, EncodingASCII, []byte("007")},
+		{"EBCDIC LL", 19, 2, EncodingEBCDIC, []byte{0xF1, 0xF9}},
+		{"BCD LL", 19, 2, EncodingBCD, []byte{0x19}},
+		{"BCD LLL", 123, 3, EncodingBCD, []byte{0x01, 0x23}},
+		{"BCD LLLL", 1234, 4, EncodingBCD, []byte{0x12, 0x34}},
+		{"binary LL", 200, 2, EncodingBinary, []byte{0xC8}},
+		{"binary LLL", 999, 3, EncodingBinary, []byte{0x03, 0xE7}},
+		{"hex LL", 200, 2, EncodingHex, []byte("C8")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := make([]byte, 4)
+			n, err := writeLengthPrefix(buf, tt.length, tt.digits, tt.enc)
+			if err != nil || !bytes.Equal(buf[:n], tt.wire) {
+				t.Fatalf("writeLengthPrefix = %X, %v, want %X", buf[:n], err, tt.wire)
+			}
+			data := append([]byte("xx"), tt.wire...)
+			length, offset, err := readLengthPrefix(data, 2, tt.digits, tt.enc)
+			if err != nil || length != tt.length || offset != 2+len(tt.wire) {
+				t.Errorf("readLengthPrefix = %d, %d, %v, want %d, %d", length, offset, err, tt.length, 2+len(tt.wire))
+			}
+		})
+	}
+}
+
+func TestLengthPrefixErrors(t *testing.T) {
+	writes := []struct {
+		name   string
+		length int
+		digits int
+		enc    Encoding
+	}{
+		{"ASCII LL overflow", 100, 2, EncodingASCII},
+		{"BCD LL overflow", 100, 2, EncodingBCD},
+		{"binary LL overflow", 256, 2, EncodingBinary},
+		{"hex LL overflow", 256, 2, EncodingHex},
+	}
+	for _, tt := range writes {
+		if _, err := writeLengthPrefix(make([]byte, 4), tt.length, tt.digits, tt.enc); !errors.Is(err, ErrInvalidLength) {
+			t.Errorf("%s: writeLengthPrefix = %v, want ErrInvalidLength", tt.name, err)
+		}
+	}
+
+	reads := []struct {
+		name string
+		data []byte
+		enc  Encoding
+	}{
+		{"ASCII non-digit", []byte("1x"), EncodingASCII},
+		{"EBCDIC non-digit", []byte{0xF1, 0xC1}, EncodingEBCDIC},
+		{"BCD invalid nibble", []byte{0x1A}, EncodingBCD},
+		{"hex non-hex", []byte("G1"), EncodingHex},
+		{"truncated", []byte("1"), EncodingASCII},
+	}
+	for _, tt := range reads {
+		if _, _, err := readLengthPrefix(tt.data, 0, 2, tt.enc); !errors.Is(err, ErrInvalidLength) {
+			t.Errorf("%s: readLengthPrefix = %v, want ErrInvalidLength", tt.name, err)
+		}
+	}
+}
+
+func TestMessageLengthPrefixEncoding(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig(
+		WithFieldConfig(2, FieldConfig{Type: FieldTypeN, Length: LengthLLVAR, MaxLength: 19, Encoding: EncodingBCD, LengthEncoding: EncodingBCD}),
+		WithFieldConfig(44, FieldConfig{Type: FieldTypeANS, Length: LengthLLVAR, MaxLength: 25, LengthEncoding: EncodingBinary}),
+	))
+	m := newTestMessage(t, pkg, "0100", map[int]string{2: "4111111111111111111", 44: "additional"})
+
+	buf := make([]byte, 256)
+	n, err := m.Pack(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// MTI, hex bitmap, 1 + 10 bytes for DE 2, 1 + 10 bytes for DE 44
+	if want := 4 + 16 + 11 + 11; n != want {
+		t.Errorf("packed %d bytes, want %d", n, want)
+	}
+	if buf[20] != 0x19 {
+		t.Errorf("DE 2 prefix = %X, want BCD 19", buf[20])
+	}
+
+	got := repack(t, m)
+	if s, _ := got.GetString(2); s != "4111111111111111111" {
+		t.Errorf("DE 2 = %q", s)
+	}
+	if s, _ := got.GetString(44); s != "additional" {
+		t.Errorf("DE 44 = %q", s)
+	}
+}
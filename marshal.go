@@ -0,0 +1,530 @@
+package iso8583
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// structTag is a parsed `iso8583:"..."` struct tag.
+// The first element is the field number (or "mti"); the remaining elements
+// are options ("omitempty", "format=MMDD") or a descriptive name.
+type structTag struct {
+	field     int
+	mti       bool
+	omitEmpty bool
+	format    string // FieldConfig.Format name for time.Time values
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// parseStructTag parses an iso8583 struct tag. ok is false for "-" or an empty tag.
+func parseStructTag(tag string) (structTag, bool, error) {
+	var st structTag
+	if tag == "" || tag == "-" {
+		return st, false, nil
+	}
+
+	parts := strings.Split(tag, ",")
+	if strings.EqualFold(parts[0], "mti") {
+		st.mti = true
+	} else {
+		n, err := strconv.Atoi(parts[0])
+		if err != nil || n < 1 || n > MaxFieldNumber {
+			return st, false, fmt.Errorf("%w: struct tag %q", ErrInvalidField, tag)
+		}
+		st.field = n
+	}
+
+	for _, opt := range parts[1:] {
+		switch {
+		case opt == "omitempty":
+			st.omitEmpty = true
+		case strings.HasPrefix(opt, "format="):
+			st.format = strings.TrimPrefix(opt, "format=")
+		}
+	}
+	return st, true, nil
+}
+
+// Marshal builds a message from a struct whose fields carry `iso8583` tags,
+// e.g. `iso8583:"4,amount"` or `iso8583:"mti"`.
+// Supported field types are string, integers, time.Time (formatted per the
+// field's Format, or MMDDhhmmss/hhmmss/MMDD for DE 7/12/13), []byte,
+// nested structs for composite fields (tagged with subfield numbers) and
+// map[string]string or map[string][]byte for TLV fields (keyed by tag).
+func Marshal(v any, pkg *CompiledPackager) (*Message, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("%w: nil %s", ErrUnsupportedType, rv.Type())
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, rv.Type())
+	}
+
+	msg := NewMessage(WithPackager(pkg))
+	if err := marshalStruct(msg, rv); err != nil {
+		msg.Release()
+		return nil, err
+	}
+	return msg, nil
+}
+
+// marshalStruct sets message fields from the tagged fields of rv.
+func marshalStruct(msg *Message, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+
+		tag, ok, err := parseStructTag(sf.Tag.Get("iso8583"))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// Untagged embedded structs contribute their own tagged fields
+			if sf.Anonymous && fv.Kind() == reflect.Struct {
+				if err := marshalStruct(msg, fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		for fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if (fv.Kind() == reflect.Pointer && fv.IsNil()) || (tag.omitEmpty && fv.IsZero()) {
+			continue
+		}
+
+		if tag.mti {
+			if err := msg.SetMTI([]byte(fmt.Sprint(fv.Interface()))); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := marshalField(msg, tag, fv); err != nil {
+			return &FieldError{Field: tag.field, Err: err}
+		}
+	}
+	return nil
+}
+
+// marshalField sets one message field from a struct field value.
+func marshalField(msg *Message, tag structTag, fv reflect.Value) error {
+	config, _ := msg.fieldConfig(tag.field)
+
+	switch {
+	case fv.Kind() == reflect.Struct && fv.Type() != timeType:
+		return marshalSubfields(msg, tag.field, config, fv)
+
+	case fv.Kind() == reflect.Map:
+		return marshalTLVMap(msg, tag.field, fv)
+
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Uint8:
+		return msg.SetField(tag.field, fv.Bytes())
+
+	case isIntKind(fv.Kind()):
+		n, err := intValue(fv)
+		if err != nil {
+			return err
+		}
+		width := 0
+		if config.Length == LengthFixed {
+			width = config.MaxLength
+		}
+		return msg.SetFieldWithWidth(tag.field, n, width)
+	}
+
+	value, err := formatScalar(fv, tag.field, config, tag.format)
+	if err != nil {
+		return err
+	}
+	return msg.SetField(tag.field, value)
+}
+
+// marshalSubfields sets the subfields of a composite field from a nested struct.
+func marshalSubfields(msg *Message, fieldNum int, config FieldConfig, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag, ok, err := parseStructTag(sf.Tag.Get("iso8583"))
+		if err != nil {
+			return err
+		}
+		if !ok || tag.mti || !sf.IsExported() {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if tag.omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		sub := config.Subfields[tag.field]
+		var value string
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Uint8 {
+			value = string(fv.Bytes())
+		} else {
+			value, err = formatScalar(fv, 0, sub, tag.format)
+			if err != nil {
+				return fmt.Errorf("subfield %d: %w", tag.field, err)
+			}
+		}
+		if err := msg.SetSubfield(fieldNum, tag.field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marshalTLVMap sets the tags of a TLV field from a map keyed by tag.
+// EMV and standard tags are hex keys; string values are hex for those
+// types and plain text for ASCII TLV.
+func marshalTLVMap(msg *Message, fieldNum int, rv reflect.Value) error {
+	if rv.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, rv.Type())
+	}
+	tlvType, err := msg.fieldTLVType(fieldNum)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, rv.Len())
+	for _, key := range rv.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		elem := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+
+		tag := []byte(key)
+		if tlvType != TLVASCII {
+			if tag, err = hex.DecodeString(key); err != nil {
+				return fmt.Errorf("%w: tag %q", ErrInvalidTLV, key)
+			}
+		}
+
+		var value []byte
+		switch {
+		case elem.Kind() == reflect.String && tlvType != TLVASCII:
+			if value, err = hex.DecodeString(elem.String()); err != nil {
+				return &TLVError{Tag: tag, Err: ErrInvalidEncoding}
+			}
+		case elem.Kind() == reflect.String:
+			value = []byte(elem.String())
+		case elem.Kind() == reflect.Slice && elem.Type().Elem().Kind() == reflect.Uint8:
+			value = elem.Bytes()
+		default:
+			return fmt.Errorf("%w: %s", ErrUnsupportedType, rv.Type())
+		}
+
+		if err := msg.SetTag(fieldNum, tag, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatScalar converts a string, integer or time.Time value to field text.
+func formatScalar(fv reflect.Value, fieldNum int, config FieldConfig, format string) (string, error) {
+	switch {
+	case fv.Kind() == reflect.String:
+		return fv.String(), nil
+
+	case isIntKind(fv.Kind()):
+		n, err := intValue(fv)
+		if err != nil {
+			return "", err
+		}
+		width := 0
+		if config.Length == LengthFixed {
+			width = config.MaxLength
+		}
+		var buf [20]byte
+		return string(buf[:formatIntToBytes(buf[:], n, width)]), nil
+
+	case fv.Type() == timeType:
+		layout, err := fieldTimeLayout(fieldNum, config, format)
+		if err != nil {
+			return "", err
+		}
+		return fv.Interface().(time.Time).Format(layout.layout), nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedType, fv.Type())
+}
+
+// fieldTimeLayout resolves the layout for a time value: the struct tag
+// format, then FieldConfig.Format, then the field's 1987 default.
+func fieldTimeLayout(fieldNum int, config FieldConfig, format string) (isoTimeLayout, error) {
+	if format == "" {
+		format = config.Format
+	}
+	if format == "" {
+		format = defaultTimeFormats[fieldNum]
+	}
+	layout, ok := timeLayout(format)
+	if !ok {
+		return isoTimeLayout{}, fmt.Errorf("%w: no date/time format for field", ErrInvalidFormat)
+	}
+	return layout, nil
+}
+
+// isIntKind reports whether k is a signed or unsigned integer kind.
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// intValue returns a non-negative integer value as an int.
+func intValue(fv reflect.Value) (int, error) {
+	switch fv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n := fv.Uint()
+		if n > math.MaxInt {
+			return 0, fmt.Errorf("%w: value %d overflows int", ErrInvalidFormat, n)
+		}
+		return int(n), nil
+	}
+	n := fv.Int()
+	if n < 0 {
+		return 0, fmt.Errorf("%w: negative value %d", ErrInvalidFormat, n)
+	}
+	if n > math.MaxInt {
+		return 0, fmt.Errorf("%w: value %d overflows int", ErrInvalidFormat, n)
+	}
+	return int(n), nil
+}
+
+// Unmarshal copies message fields into the tagged fields of the struct
+// pointed to by v. Absent fields leave the struct field unchanged.
+// See Marshal for the supported types and tag syntax.
+func Unmarshal(msg *Message, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("%w: Unmarshal needs a non-nil pointer", ErrUnsupportedType)
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, rv.Type())
+	}
+	return unmarshalStruct(msg, rv)
+}
+
+// unmarshalStruct fills the tagged fields of rv from the message.
+func unmarshalStruct(msg *Message, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+
+		tag, ok, err := parseStructTag(sf.Tag.Get("iso8583"))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if sf.Anonymous && fv.Kind() == reflect.Struct {
+				if err := unmarshalStruct(msg, fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		if tag.mti {
+			if err := setScalar(fv, msg.MTI(), 0, FieldConfig{}, ""); err != nil {
+				return err
+			}
+			continue
+		}
+		if !msg.HasField(tag.field) {
+			continue
+		}
+
+		if err := unmarshalField(msg, tag, fv); err != nil {
+			return &FieldError{Field: tag.field, Err: err}
+		}
+	}
+	return nil
+}
+
+// unmarshalField copies one message field into a struct field value.
+func unmarshalField(msg *Message, tag structTag, fv reflect.Value) error {
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		fv = fv.Elem()
+	}
+	config, _ := msg.fieldConfig(tag.field)
+
+	switch {
+	case fv.Kind() == reflect.Struct && fv.Type() != timeType:
+		subfields, err := msg.GetSubfields(tag.field)
+		if err != nil {
+			return err
+		}
+		return unmarshalSubfields(subfields, config, fv)
+
+	case fv.Kind() == reflect.Map:
+		return unmarshalTLVMap(msg, tag.field, fv)
+	}
+
+	data, err := msg.GetBytes(tag.field)
+	if err != nil {
+		return err
+	}
+	return setScalar(fv, data, tag.field, config, tag.format)
+}
+
+// unmarshalSubfields fills a nested struct from a composite field's subfields.
+func unmarshalSubfields(subfields map[int][]byte, config FieldConfig, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag, ok, err := parseStructTag(sf.Tag.Get("iso8583"))
+		if err != nil {
+			return err
+		}
+		if !ok || tag.mti || !sf.IsExported() {
+			continue
+		}
+		value, present := subfields[tag.field]
+		if !present {
+			continue
+		}
+		if err := setScalar(rv.Field(i), value, 0, config.Subfields[tag.field], tag.format); err != nil {
+			return fmt.Errorf("subfield %d: %w", tag.field, err)
+		}
+	}
+	return nil
+}
+
+// unmarshalTLVMap fills a map from the top-level tags of a TLV field.
+func unmarshalTLVMap(msg *Message, fieldNum int, rv reflect.Value) error {
+	mt := rv.Type()
+	if mt.Key().Kind() != reflect.String {
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, mt)
+	}
+	tlvType, err := msg.fieldTLVType(fieldNum)
+	if err != nil {
+		return err
+	}
+	tlvs, err := msg.GetTLVs(fieldNum)
+	if err != nil {
+		return err
+	}
+
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMapWithSize(mt, len(tlvs)))
+	}
+	for _, tlv := range tlvs {
+		key := string(tlv.Tag)
+		if tlvType != TLVASCII {
+			key = fmt.Sprintf("%X", tlv.Tag)
+		}
+
+		elem := reflect.New(mt.Elem()).Elem()
+		switch {
+		case elem.Kind() == reflect.String && tlvType != TLVASCII:
+			elem.SetString(fmt.Sprintf("%X", tlv.Value))
+		case elem.Kind() == reflect.String:
+			elem.SetString(string(tlv.Value))
+		case elem.Kind() == reflect.Slice && elem.Type().Elem().Kind() == reflect.Uint8:
+			elem.SetBytes(append([]byte(nil), tlv.Value...))
+		default:
+			return fmt.Errorf("%w: %s", ErrUnsupportedType, mt)
+		}
+		rv.SetMapIndex(reflect.ValueOf(key).Convert(mt.Key()), elem)
+	}
+	return nil
+}
+
+// setScalar parses field data into a string, integer, []byte or time.Time value.
+func setScalar(fv reflect.Value, data []byte, fieldNum int, config FieldConfig, format string) error {
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		fv = fv.Elem()
+	}
+
+	switch {
+	case fv.Kind() == reflect.String:
+		fv.SetString(string(data))
+
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Uint8:
+		fv.SetBytes(append([]byte(nil), data...))
+
+	case fv.Kind() == reflect.Array && fv.Type().Elem().Kind() == reflect.Uint8 && fv.Len() == len(data):
+		reflect.Copy(fv, reflect.ValueOf(data)) // e.g. the MTI type
+
+	case isIntKind(fv.Kind()):
+		text := strings.TrimSpace(string(data))
+		switch fv.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n, err := strconv.ParseUint(text, 10, fv.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+			}
+			fv.SetUint(n)
+		default:
+			n, err := strconv.ParseInt(text, 10, fv.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+			}
+			fv.SetInt(n)
+		}
+
+	case fv.Type() == timeType:
+		layout, err := fieldTimeLayout(fieldNum, config, format)
+		if err != nil {
+			return err
+		}
+		t, err := parseISOTime(string(data), layout, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, fv.Type())
+	}
+	return nil
+}
+
+// fieldTLVType returns the TLV type of a field marked as TLV.
+func (m *Message) fieldTLVType(fieldNum int) (TLVType, error) {
+	if m.packager == nil {
+		return 0, ErrNoPackagerConfigured
+	}
+	parser, exists := m.packager.GetTLVParser(fieldNum)
+	if !exists {
+		return 0, &FieldError{Field: fieldNum, Err: ErrFieldNotTLV}
+	}
+	return parser.tlvType, nil
+}
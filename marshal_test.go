@@ -0,0 +1,148 @@
+package iso8583
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type marshalOriginal struct {
+	MTI  string `iso8583:"1"`
+	STAN int    `iso8583:"2"`
+}
+
+type marshalReversal struct {
+	MTI        string            `iso8583:"mti"`
+	PAN        string            `iso8583:"2,primary account number"`
+	Amount     int64             `iso8583:"4"`
+	Sent       time.Time         `iso8583:"7"`
+	STAN       uint32            `iso8583:"11"`
+	Terminal   *string           `iso8583:"41,omitempty"`
+	Additional string            `iso8583:"44,omitempty"`
+	PINBlock   []byte            `iso8583:"52"`
+	EMV        map[string]string `iso8583:"55"`
+	Original   marshalOriginal   `iso8583:"90"`
+	Ignored    string            `iso8583:"-"`
+}
+
+func newMarshalPackager() *CompiledPackager {
+	return NewCompiledPackager(newTestConfig(
+		WithTLVConfig(TLVConfig{Type: TLVEMV, Enabled: true, MaxDepth: 1}),
+		WithFieldConfig(52, FieldConfig{Type: FieldTypeB, Length: LengthFixed, MaxLength: 8}),
+		WithFieldConfig(55, FieldConfig{Type: FieldTypeB, Length: LengthLLLVAR, MaxLength: 999, TLV: &FieldTLVConfig{Type: TLVEMV}}),
+		WithFieldConfig(90, FieldConfig{Type: FieldTypeN, Length: LengthFixed, MaxLength: 10, Subfields: map[int]FieldConfig{
+			1: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4},
+			2: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 6},
+		}}),
+	))
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	terminal := "TERM0001"
+	in := marshalReversal{
+		MTI:      "0400",
+		PAN:      "4111111111111111",
+		Amount:   1234,
+		Sent:     time.Now().UTC().Truncate(time.Second),
+		STAN:     42,
+		Terminal: &terminal,
+		PINBlock: []byte{1, 2, 3, 4, 5, 6, 7, 8},
+		EMV:      map[string]string{"9F02": "000000001234", "95": "0000008000"},
+		Original: marshalOriginal{MTI: "0200", STAN: 41},
+		Ignored:  "not sent",
+	}
+
+	msg, err := Marshal(&in, newMarshalPackager())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := msg.GetPresentFields(), []int{2, 4, 7, 11, 41, 52, 55, 90}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+	for fieldNum, want := range map[int]string{4: "000000001234", 11: "000042"} {
+		if got, _ := msg.GetString(fieldNum); got != want {
+			t.Errorf("DE %d = %q, want %q", fieldNum, got, want)
+		}
+	}
+	if got, _ := msg.GetSubfield(90, 2); got != "000041" {
+		t.Errorf("DE 90.2 = %q, want %q", got, "000041")
+	}
+
+	var out marshalReversal
+	if err := Unmarshal(repack(t, msg), &out); err != nil {
+		t.Fatal(err)
+	}
+	in.Ignored = ""
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("Unmarshal =\n%+v\nwant\n%+v", out, in)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	pkg := newMarshalPackager()
+	type badTag struct {
+		X string `iso8583:"abc"`
+	}
+	type badType struct {
+		X float64 `iso8583:"4"`
+	}
+	type badTime struct {
+		X time.Time `iso8583:"41"`
+	}
+	type badTLVKey struct {
+		X map[string]string `iso8583:"55"`
+	}
+
+	tests := []struct {
+		name string
+		v    any
+		want error
+	}{
+		{"not a struct", 42, ErrUnsupportedType},
+		{"nil pointer", (*marshalReversal)(nil), ErrUnsupportedType},
+		{"bad tag", badTag{}, ErrInvalidField},
+		{"float", badType{X: 1.5}, ErrUnsupportedType},
+		{"time without format", badTime{X: time.Now()}, ErrInvalidFormat},
+		{"non-hex tag", badTLVKey{X: map[string]string{"zz": "00"}}, ErrInvalidTLV},
+	}
+	for _, tt := range tests {
+		if _, err := Marshal(tt.v, pkg); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Marshal = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	var out marshalReversal
+	if err := Unmarshal(NewMessage(WithPackager(pkg)), out); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Unmarshal into a non-pointer = %v, want ErrUnsupportedType", err)
+	}
+}
+
+func TestMarshalIntOverflow(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig())
+
+	type unsigned struct {
+		STAN uint64 `iso8583:"11"`
+	}
+	type signed struct {
+		STAN int64 `iso8583:"11"`
+	}
+	for _, v := range []any{
+		unsigned{STAN: math.MaxUint64},
+		unsigned{STAN: math.MaxInt64 + 1},
+		signed{STAN: -1},
+	} {
+		if _, err := Marshal(v, pkg); !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("Marshal(%+v) = %v, want ErrInvalidFormat", v, err)
+		}
+	}
+
+	msg, err := Marshal(unsigned{STAN: 123}, pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := msg.GetString(11); got != "000123" {
+		t.Errorf("DE 11 = %q, want %q", got, "000123")
+	}
+}
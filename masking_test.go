@@ -0,0 +1,113 @@
+package iso8583
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestMaskerModes(t *testing.T) {
+	mk := NewMasker(map[int]MaskMode{2: MaskHash, 41: MaskFull, 52: MaskClear}, WithHashKey([]byte("secret")))
+	pan := []byte("4111111111111111")
+
+	tests := []struct {
+		fieldNum int
+		value    []byte
+		want     string
+	}{
+		{3, []byte("000000"), "000000"},          // Not in any policy
+		{14, []byte("2512"), "****"},             // Default policy
+		{34, pan, "411111******1111"},            // Default PAN truncation
+		{34, []byte("4111111111"), "**********"}, // Too short to truncate
+		{41, []byte("TERM0001"), "********"},     // Configured
+		{52, []byte{0x01, 0x02}, "\x01\x02"},     // Default overridden to clear
+	}
+	for _, tt := range tests {
+		if got := mk.Mask(tt.fieldNum, tt.value); got != tt.want {
+			t.Errorf("Mask(%d, %q) = %q, want %q", tt.fieldNum, tt.value, got, tt.want)
+		}
+	}
+
+	hashed := mk.Mask(2, pan)
+	if !strings.HasPrefix(hashed, "sha256:") || len(hashed) != len("sha256:")+16 {
+		t.Errorf("Mask(2) = %q, want a short sha256 digest", hashed)
+	}
+	if again := mk.Mask(2, pan); again != hashed {
+		t.Errorf("hash is not deterministic: %q, %q", hashed, again)
+	}
+	other := NewMasker(map[int]MaskMode{2: MaskHash}, WithHashKey([]byte("other")))
+	if other.Mask(2, pan) == hashed {
+		t.Error("different keys gave the same digest")
+	}
+	if got := NewMasker(map[int]MaskMode{2: MaskHash}).Mask(2, pan); got != strings.Repeat("*", len(pan)) {
+		t.Errorf("MaskHash without a key = %q, want full masking", got)
+	}
+}
+
+func TestMaskModeJSON(t *testing.T) {
+	var policy map[int]MaskMode
+	if err := json.Unmarshal([]byte(`{"2":"pan","35":"full","41":"clear","48":"hash","52":1,"55":"bogus"}`), &policy); err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]MaskMode{2: MaskPAN, 35: MaskFull, 41: MaskClear, 48: MaskHash, 52: MaskFull, 55: MaskFull}
+	for fieldNum, mode := range want {
+		if policy[fieldNum] != mode {
+			t.Errorf("DE %d mode = %v, want %v", fieldNum, policy[fieldNum], mode)
+		}
+	}
+}
+
+func TestLogValueMasksFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []PackagerOption
+		wantPAN  string
+		wantFull bool
+	}{
+		{"default", nil, "411111******1111", false},
+		{"hash", []PackagerOption{WithMasking(map[int]MaskMode{2: MaskHash}), WithMaskingKey([]byte("k"))}, "sha256:", false},
+		{"raw", []PackagerOption{WithRawMessageLogging(true)}, "411111******1111", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := NewCompiledPackager(newTestConfig(tt.opts...))
+			buf := make([]byte, 256)
+			n, err := newTestMessage(t, pkg, "0100", map[int]string{2: "4111111111111111", 3: "000000"}).Pack(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := NewMessage(WithPackager(pkg))
+			if err := m.Unpack(buf[:n]); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			slog.New(slog.NewJSONHandler(&out, nil)).Info("msg", "iso", m)
+			logged := out.String()
+			if !strings.Contains(logged, tt.wantPAN) {
+				t.Errorf("log %s does not contain %q", logged, tt.wantPAN)
+			}
+			if strings.Contains(logged, "full_message") != tt.wantFull {
+				t.Errorf("log %s: full_message present = %v, want %v", logged, !tt.wantFull, tt.wantFull)
+			}
+			if !tt.wantFull && strings.Contains(logged, "4111111111111111") {
+				t.Errorf("log %s contains the clear PAN", logged)
+			}
+		})
+	}
+}
+
+func TestMaskingKeyEnv(t *testing.T) {
+	t.Setenv("ISO8583_TEST_MASKING_KEY", "secret")
+	pc := newTestConfig(WithMasking(map[int]MaskMode{2: MaskHash}))
+	pc.MaskingKeyEnv = "ISO8583_TEST_MASKING_KEY"
+
+	got := NewCompiledPackager(pc).GetMasker().Mask(2, []byte("4111111111111111"))
+	want := NewMasker(nil, WithHashKey([]byte("secret"))).MaskWith(MaskHash, []byte("4111111111111111"))
+	if got != want {
+		t.Errorf("Mask with the key from the environment = %q, want %q", got, want)
+	}
+}
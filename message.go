@@ -1,6 +1,7 @@
 package iso8583
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -21,18 +22,23 @@ var messagePool = sync.Pool{
 // It contains the MTI, header, bitmap, and all present fields.
 // It is designed to be reused via a sync.Pool.
 type Message struct {
-	mti             [4]byte
-	fields          [128]Field // Array of all possible fields
-	bitmap          BitmapManager
-	packager        *CompiledPackager // The specification used to parse/pack this message
-	header          []byte
-	tlvData         map[int][]TLV // Parsed TLV data, keyed by field number
-	validationLevel ValidationLevel
-	fieldPresence   [2]uint64 // Optimized bitset for field presence (1=present)
-	mu              sync.RWMutex
-	fullMessage     []byte // Reference to the original raw message bytes
-
-	lastError FieldError // Stores the last error encountered during parsing
+	mti              [4]byte
+	fields           [MaxFieldNumber]Field // Array of all possible fields
+	bitmap           BitmapManager
+	packager         *CompiledPackager // The specification used to parse/pack this message
+	header           []byte
+	tlvData          map[int][]TLV          // Parsed TLV data, keyed by field number
+	tlvModified      map[int]bool           // TLV fields changed via SetTag, re-encoded on Pack
+	subfields        map[int]map[int][]byte // Decoded subfields of composite fields
+	subfieldModified map[int]bool           // Composite fields changed via SetSubfield, rebuilt on Pack
+	validationLevel  ValidationLevel
+	validationMode   ValidationMode
+	fieldPresence    [MaxBitmaps]uint64 // Optimized bitset for field presence (1=present)
+	mu               sync.RWMutex
+	fullMessage      []byte // Reference to the original raw message bytes
+
+	lastError FieldError       // Stores the last error encountered during parsing
+	report    ValidationReport // Collects parse errors in ValidationCollectAll mode
 }
 
 // NewMessage retrieves a Message from the pool and initializes it.
@@ -65,8 +71,9 @@ func (m *Message) reset() {
 	m.mti = [4]byte{}
 	m.header = nil
 	m.validationLevel = ValidationNone
+	m.validationMode = ValidationFailFast
 	m.bitmap.Reset()
-	m.fieldPresence = [2]uint64{} // Clear presence bits
+	m.fieldPresence = [MaxBitmaps]uint64{} // Clear presence bits
 	m.fullMessage = nil
 	m.packager = nil // Clear packager reference
 
@@ -82,25 +89,41 @@ func (m *Message) reset() {
 			delete(m.tlvData, k)
 		}
 	}
+	if m.tlvModified != nil {
+		for k := range m.tlvModified {
+			delete(m.tlvModified, k)
+		}
+	}
+	if m.subfields != nil {
+		for k := range m.subfields {
+			delete(m.subfields, k)
+		}
+	}
+	if m.subfieldModified != nil {
+		for k := range m.subfieldModified {
+			delete(m.subfieldModified, k)
+		}
+	}
 
 	m.lastError.Field = 0
 	m.lastError.Err = nil
+	m.report.Errors = nil
 }
 
 // isFieldPresent checks the internal presence bitset for a field.
 // This is faster than checking the main ISO8583 bitmap.
 func (m *Message) isFieldPresent(fieldNum int) bool {
-	if fieldNum < 1 || fieldNum > 128 {
+	if fieldNum < 1 || fieldNum > MaxFieldNumber {
 		return false
 	}
-	idx := (fieldNum - 1) / 64 // 0 for fields 1-64, 1 for 65-128
+	idx := (fieldNum - 1) / 64 // 0 for fields 1-64, 1 for 65-128, 2 for 129-192
 	bit := uint64(1) << ((fieldNum - 1) % 64)
 	return m.fieldPresence[idx]&bit != 0
 }
 
 // setFieldPresent sets the internal presence bit for a field.
 func (m *Message) setFieldPresent(fieldNum int) {
-	if fieldNum < 1 || fieldNum > 128 {
+	if fieldNum < 1 || fieldNum > MaxFieldNumber {
 		return
 	}
 	idx := (fieldNum - 1) / 64
@@ -108,10 +131,44 @@ func (m *Message) setFieldPresent(fieldNum int) {
 	m.fieldPresence[idx] |= bit
 }
 
+// isDataField reports whether fieldNum can carry data under the message's
+// bitmap layout: it must be addressable by the configured bitmaps and must
+// not be the tertiary extension bit (DE 65).
+func (m *Message) isDataField(fieldNum int) bool {
+	if fieldNum > m.bitmap.MaxField() {
+		return false
+	}
+	return fieldNum <= 64 || !m.bitmap.IsExtensionField(fieldNum)
+}
+
+// setPackager attaches the packager, or its profile for the message's MTI
+// version, and applies its bitmap layout.
+func (m *Message) setPackager(packager *CompiledPackager) {
+	if packager == nil {
+		m.packager = nil
+		m.bitmap.Configure(DefaultBitmaps, BitmapExtensionChained)
+		return
+	}
+	m.packager = packager.profileFor(m.mti[:])
+	m.bitmap.Configure(m.packager.bitmaps, m.packager.bitmapExtension)
+}
+
+// selectVersionProfile switches the message to the packager profile that
+// matches its MTI version. It is a no-op unless version detection is enabled.
+// SetMTI and Unpack call it, so SetField, Validate and Pack use the same profile.
+func (m *Message) selectVersionProfile() {
+	if m.packager == nil {
+		return
+	}
+	if profile := m.packager.profileFor(m.mti[:]); profile != m.packager {
+		m.setPackager(profile)
+	}
+}
+
 // ClearField clears (turns off) the bit for the specified field.
 // It removes the field from the bitmap and clears its presence flag.
 func (m *Message) ClearField(fieldNum int) error {
-	if fieldNum < 1 || fieldNum > 128 {
+	if fieldNum < 1 || fieldNum > MaxFieldNumber {
 		return fmt.Errorf("field number %d out of range", fieldNum)
 	}
 
@@ -126,6 +183,9 @@ func (m *Message) ClearField(fieldNum int) error {
 
 	m.fields[fieldNum-1] = Field{}
 
+	m.dropTLV(fieldNum)
+	m.dropSubfields(fieldNum)
+
 	return nil
 }
 
@@ -136,7 +196,15 @@ func (m *Message) MTI() []byte {
 	return m.mti[:]
 }
 
-// SetMTI sets the 4-byte Message Type Indicator.
+// GetMTI returns the Message Type Indicator as a decoded MTI value.
+func (m *Message) GetMTI() MTI {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return MTI(m.mti)
+}
+
+// SetMTI sets the 4-byte Message Type Indicator. With version detection
+// enabled it also selects the packager profile for the MTI version.
 func (m *Message) SetMTI(mti []byte) error {
 	if len(mti) != 4 {
 		return ErrInvalidMTI
@@ -144,13 +212,14 @@ func (m *Message) SetMTI(mti []byte) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	copy(m.mti[:], mti)
+	m.selectVersionProfile()
 	return nil
 }
 
 // GetField returns a pointer to the specified Field struct.
 // Returns ErrFieldNotFound if the field is not present.
 func (m *Message) GetField(fieldNum int) (*Field, error) {
-	if fieldNum < 1 || fieldNum > 128 {
+	if fieldNum < 1 || fieldNum > MaxFieldNumber {
 		return nil, ErrInvalidField
 	}
 	m.mu.RLock()
@@ -167,13 +236,17 @@ func (m *Message) GetField(fieldNum int) (*Field, error) {
 // Float64 values are formatted with 2 decimal places by default.
 // It also sets the corresponding bit in the ISO8583 bitmap.
 func (m *Message) SetField(fieldNum int, value interface{}) error {
-	if fieldNum < 1 || fieldNum > 128 {
+	if fieldNum < 1 || fieldNum > MaxFieldNumber {
 		return &FieldError{Field: fieldNum, Err: ErrInvalidField}
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if !m.isDataField(fieldNum) {
+		return &FieldError{Field: fieldNum, Err: ErrInvalidField}
+	}
+
 	field := &m.fields[fieldNum-1]
 
 	switch v := value.(type) {
@@ -204,6 +277,8 @@ func (m *Message) SetField(fieldNum int, value interface{}) error {
 		return &FieldError{Field: fieldNum, Err: fmt.Errorf("unsupported value type")}
 	}
 
+	m.dropTLV(fieldNum)         // Raw value replaces any decoded TLV entries
+	m.dropSubfields(fieldNum)   // and subfields
 	m.setFieldPresent(fieldNum) // Update presence bitset
 	m.bitmap.SetField(fieldNum) // Update ISO8583 bitmap
 	return nil
@@ -215,13 +290,17 @@ func (m *Message) SetField(fieldNum int, value interface{}) error {
 // It accepts string, []byte, int, or float64.
 // It also sets the corresponding bit in the ISO8583 bitmap.
 func (m *Message) SetFieldWithWidth(fieldNum int, value interface{}, width int) error {
-	if fieldNum < 1 || fieldNum > 128 {
+	if fieldNum < 1 || fieldNum > MaxFieldNumber {
 		return &FieldError{Field: fieldNum, Err: ErrInvalidField}
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if !m.isDataField(fieldNum) {
+		return &FieldError{Field: fieldNum, Err: ErrInvalidField}
+	}
+
 	field := &m.fields[fieldNum-1]
 
 	switch v := value.(type) {
@@ -248,6 +327,8 @@ func (m *Message) SetFieldWithWidth(fieldNum int, value interface{}, width int)
 		return &FieldError{Field: fieldNum, Err: fmt.Errorf("unsupported value type")}
 	}
 
+	m.dropTLV(fieldNum)
+	m.dropSubfields(fieldNum)
 	m.setFieldPresent(fieldNum)
 	m.bitmap.SetField(fieldNum)
 	return nil
@@ -266,14 +347,14 @@ func (m *Message) GetPresentFields() []int {
 	defer m.mu.RUnlock()
 
 	count := 0
-	for i := 1; i <= 128; i++ {
+	for i := 1; i <= MaxFieldNumber; i++ {
 		if m.isFieldPresent(i) {
 			count++
 		}
 	}
 
 	fields := make([]int, 0, count)
-	for i := 1; i <= 128; i++ {
+	for i := 1; i <= MaxFieldNumber; i++ {
 		if m.isFieldPresent(i) {
 			fields = append(fields, i)
 		}
@@ -289,7 +370,7 @@ func (m *Message) GetPresentFieldsInto(fields []int) int {
 	defer m.mu.RUnlock()
 
 	idx := 0
-	for i := 1; i <= 128 && idx < len(fields); i++ {
+	for i := 1; i <= MaxFieldNumber && idx < len(fields); i++ {
 		if m.isFieldPresent(i) {
 			fields[idx] = i
 			idx++
@@ -331,30 +412,41 @@ func (m *Message) Unpack(data []byte) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	_, err := m.unpack(data)
+	return err
+}
+
+// unpack is the internal, non-locking Unpack.
+// Returns the number of bytes consumed by the header, MTI, bitmap and fields.
+func (m *Message) unpack(data []byte) (int, error) {
 	if len(data) < 4 { // At least 4 bytes for MTI
-		return ErrInvalidMTI
+		return 0, ErrInvalidMTI
 	}
 
 	m.fullMessage = data // Store reference to original data
+	m.report.Errors = nil
 	offset := 0
 
 	// 1. Parse Header (if configured)
 	if m.packager != nil && m.packager.headerConfig.Type != HeaderNone {
-		headerLen := m.packager.headerConfig.Length
-		if len(data) < headerLen {
-			return ErrInvalidHeader
+		header, headerLen, err := m.packager.unpackHeader(data)
+		if err != nil {
+			return 0, err
 		}
-		m.header = data[offset : offset+headerLen]
+		m.header = header
 		offset += headerLen
 	}
 
 	// 2. Parse MTI
 	if len(data) < offset+4 {
-		return ErrInvalidMTI
+		return 0, ErrInvalidMTI
 	}
 	copy(m.mti[:], data[offset:offset+4])
 	offset += 4
 
+	// Switch to the field layout of the MTI's version, if detection is enabled
+	m.selectVersionProfile()
+
 	// 3. Parse Bitmap
 	encoding := BitmapEncodingHex // Default
 	if m.packager != nil {
@@ -362,14 +454,14 @@ func (m *Message) Unpack(data []byte) error {
 	}
 	bitmapLen, err := m.bitmap.UnpackBitmap(data[offset:], encoding)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	offset += bitmapLen
 
 	// 4. Parse Fields
-	for fieldNum := 2; fieldNum <= 128; fieldNum++ { // Start from 2 (1 is bitmap)
-		if !m.bitmap.IsFieldSet(fieldNum) {
+	for fieldNum := 2; fieldNum <= MaxFieldNumber; fieldNum++ { // Start from 2 (1 is bitmap)
+		if m.bitmap.IsExtensionField(fieldNum) || !m.bitmap.IsFieldSet(fieldNum) {
 			continue
 		}
 
@@ -378,15 +470,34 @@ func (m *Message) Unpack(data []byte) error {
 			if m.validationLevel == ValidationNone {
 				continue
 			}
+			if m.validationMode == ValidationCollectAll {
+				m.report.add(fieldNum, err)
+				if fieldOffset > offset {
+					offset = fieldOffset // Field boundary is known, carry on with the next field
+					continue
+				}
+				break
+			}
 			m.lastError.Field = fieldNum
 			m.lastError.Err = err
-			return &m.lastError
+			return offset, &m.lastError
 		}
 		offset = fieldOffset
 
 	}
 
-	return nil
+	// 5. Decode fields marked as TLV and split composite fields into subfields
+	if err := m.decodeTLVFields(); err != nil {
+		return offset, err
+	}
+	if err := m.decodeSubfields(); err != nil {
+		return offset, err
+	}
+
+	if len(m.report.Errors) > 0 {
+		return offset, &m.report
+	}
+	return offset, nil
 }
 
 // parseField parses a single field from the data buffer.
@@ -407,92 +518,87 @@ func (m *Message) parseField(fieldNum int, data []byte, offset int) (int, error)
 		return offset, err
 	}
 
-	// 2. Check if we have enough data
-	if len(data) < newOffset+fieldLength {
+	// 2. Check if we have enough data for the encoded value
+	encoder, err := GetEncoder(config.Encoding)
+	if err != nil {
+		return offset, err
+	}
+	wireLength := encoder.EncodedLen(fieldLength)
+	if len(data) < newOffset+wireLength {
 		return offset, ErrInvalidLength
 	}
 
-	// 3. Slice the data and set the field
+	// 3. Decode the data and set the field.
+	// ASCII and binary fields are zero-copy slices of the original data.
+	// On failure the end of the field is still returned, so that callers
+	// collecting every error can continue with the next field.
+	value, err := encoder.Decode(data[newOffset:newOffset+wireLength], fieldLength)
+	if err != nil {
+		return newOffset + wireLength, err
+	}
 	field := &m.fields[fieldNum-1]
-	field.data = data[newOffset : newOffset+fieldLength] // Zero-copy slice
-	field.length = fieldLength
+	field.data = value
+	field.length = len(value)
 	field.fieldType = config.Type
 	field.parsed = true
 
 	m.setFieldPresent(fieldNum) // Update presence bitset
 
-	return newOffset + fieldLength, nil
+	return newOffset + wireLength, nil
 }
 
 // calculateFieldLength reads the length prefix (LLVAR, LLLVAR) or uses
 // the fixed length from config to determine the field's data length.
+// The prefix is decoded according to config.LengthEncoding.
 // Returns: field data length, new offset (after length prefix), error
 func calculateFieldLength(config FieldConfig, data []byte, offset int) (int, int, error) {
-	switch config.Length {
-	case LengthFixed:
+	if config.Length == LengthFixed {
 		// Fixed length, length is in MaxLength
 		return config.MaxLength, offset, nil
+	}
 
-	case LengthLLVAR:
-		// 2-digit ASCII length prefix
-		if len(data) < offset+2 {
-			return 0, offset, ErrInvalidLength
-		}
-		// Fast ASCII-to-int conversion
-		if data[offset] < '0' || data[offset] > '9' || data[offset+1] < '0' || data[offset+1] > '9' {
-			return 0, offset, ErrInvalidLength
-		}
-		length := int(data[offset]-'0')*10 + int(data[offset+1]-'0')
-		return length, offset + 2, nil
-
-	case LengthLLLVAR:
-		// 3-digit ASCII length prefix
-		if len(data) < offset+3 {
-			return 0, offset, ErrInvalidLength
-		}
-		if data[offset] < '0' || data[offset] > '9' ||
-			data[offset+1] < '0' || data[offset+1] > '9' ||
-			data[offset+2] < '0' || data[offset+2] > '9' {
-			return 0, offset, ErrInvalidLength
-		}
-		length := int(data[offset]-'0')*100 + int(data[offset+1]-'0')*10 + int(data[offset+2]-'0')
-		return length, offset + 3, nil
-
-	case LengthLLLLVAR:
-		// 4-digit ASCII length prefix
-		if len(data) < offset+4 {
-			return 0, offset, ErrInvalidLength
-		}
-		if data[offset] < '0' || data[offset] > '9' ||
-			data[offset+1] < '0' || data[offset+1] > '9' ||
-			data[offset+2] < '0' || data[offset+2] > '9' ||
-			data[offset+3] < '0' || data[offset+3] > '9' {
-			return 0, offset, ErrInvalidLength
-		}
-		length := int(data[offset]-'0')*1000 + int(data[offset+1]-'0')*100 +
-			int(data[offset+2]-'0')*10 + int(data[offset+3]-'0')
-		return length, offset + 4, nil
-
-	default:
+	digits := lengthPrefixDigits(config.Length)
+	if digits == 0 {
 		return 0, offset, ErrUnsupportedLengthType
 	}
+	return readLengthPrefix(data, offset, digits, config.LengthEncoding)
 }
 
 // Pack serializes the Message struct into a byte buffer.
 // Returns the total number of bytes written.
+// Pack does not modify the message, so it may be called concurrently.
 func (m *Message) Pack(buf []byte) (int, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	return m.pack(buf)
+}
+
+// pack is the internal, non-locking Pack.
+func (m *Message) pack(buf []byte) (int, error) {
 	offset := 0
 
-	// 1. Pack Header (if present)
-	if len(m.header) > 0 {
-		if len(buf) < offset+len(m.header) {
-			return 0, ErrBufferTooSmall
+	// Resolve the version profile locally instead of switching the message
+	packager := m.packager
+	bitmap := m.bitmap
+	if packager != nil {
+		if profile := packager.profileFor(m.mti[:]); profile != packager {
+			packager = profile
+			bitmap.Configure(packager.bitmaps, packager.bitmapExtension)
+		}
+	}
+
+	// 1. Pack Header (if present, or the codec default if one is expected)
+	header := m.header
+	if len(header) == 0 && packager != nil && packager.headerConfig.Type != HeaderNone && packager.headerCodec != nil {
+		header = packager.headerCodec.New()
+	}
+	if len(header) > 0 {
+		n, err := packager.packHeader(buf[offset:], header)
+		if err != nil {
+			return 0, err
 		}
-		copy(buf[offset:], m.header)
-		offset += len(m.header)
+		offset += n
 	}
 
 	// 2. Pack MTI
@@ -504,73 +610,160 @@ func (m *Message) Pack(buf []byte) (int, error) {
 
 	// 3. Pack Bitmap
 	encoding := BitmapEncodingHex
-	if m.packager != nil {
-		encoding = m.packager.bitmapEncoding
+	if packager != nil {
+		encoding = packager.bitmapEncoding
 	}
-	bitmapLen, err := m.bitmap.PackBitmap(buf[offset:], encoding)
+	bitmapLen, err := bitmap.PackBitmap(buf[offset:], encoding)
 	if err != nil {
 		return 0, err
 	}
 	offset += bitmapLen
 
 	// 4. Pack Fields
-	for fieldNum := 2; fieldNum <= 128; fieldNum++ {
+	for fieldNum := 2; fieldNum <= MaxFieldNumber; fieldNum++ {
 		if !m.isFieldPresent(fieldNum) {
 			continue
 		}
 
-		fieldLen, err := m.packField(fieldNum, buf, offset)
+		fieldLen, err := m.packField(packager, fieldNum, buf, offset)
 		if err != nil {
 			return 0, &FieldError{Field: fieldNum, Err: err}
 		}
 		offset += fieldLen
 	}
 
+	// 5. Fill in the total message length for headers that carry it (e.g., Visa)
+	if packager != nil && len(header) > 0 {
+		if setter, ok := packager.headerCodec.(MessageLengthSetter); ok && setter.HasMessageLength() {
+			header = append([]byte(nil), header...)
+			if err := setter.SetMessageLength(header, offset); err != nil {
+				return 0, err
+			}
+			if _, err := packager.packHeader(buf, header); err != nil {
+				return 0, err
+			}
+		}
+	}
+
 	return offset, nil
 }
 
-// packField packs a single field into the buffer.
+// PackFramed serializes the message like Pack and prepends the packager's
+// configured message length indicator.
+// Returns the total number of bytes written, including the indicator.
+func (m *Message) PackFramed(buf []byte) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	config := m.lengthIndicatorConfig()
+	prefixLen := 0
+	if config.Type != LengthIndicatorNone {
+		prefixLen = config.Length
+	}
+	if len(buf) < prefixLen {
+		return 0, ErrBufferTooSmall
+	}
+
+	n, err := m.pack(buf[prefixLen:])
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := WriteLengthIndicator(n, buf, config); err != nil {
+		return 0, err
+	}
+	return prefixLen + n, nil
+}
+
+// UnpackFramed strips the packager's configured message length indicator
+// and parses the payload it describes.
+// It returns ErrTruncatedMessage if data holds fewer bytes than declared,
+// and ErrTrailingBytes if data, or the declared payload itself, holds more
+// bytes than the message consumes.
+func (m *Message) UnpackFramed(data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	config := m.lengthIndicatorConfig()
+	msgLen, prefixLen, err := ReadLengthIndicator(data, config)
+	if err != nil {
+		if errors.Is(err, ErrInvalidLength) {
+			return fmt.Errorf("%w: incomplete length indicator", ErrTruncatedMessage)
+		}
+		return err
+	}
+
+	payload := data[prefixLen:]
+	if len(payload) < msgLen {
+		return fmt.Errorf("%w: declared %d bytes, got %d", ErrTruncatedMessage, msgLen, len(payload))
+	}
+	if len(payload) > msgLen {
+		return fmt.Errorf("%w: declared %d bytes, got %d", ErrTrailingBytes, msgLen, len(payload))
+	}
+
+	return m.unpackPayload(payload)
+}
+
+// unpackPayload unpacks a framed payload, returning ErrTrailingBytes if the
+// message does not consume all of it. Callers must hold m.mu.
+func (m *Message) unpackPayload(payload []byte) error {
+	consumed, err := m.unpack(payload)
+	if err != nil {
+		return err
+	}
+	if consumed != len(payload) {
+		return fmt.Errorf("%w: %d of %d declared bytes not consumed", ErrTrailingBytes, len(payload)-consumed, len(payload))
+	}
+	return nil
+}
+
+// lengthIndicatorConfig returns the packager's length indicator config,
+// or LengthIndicatorNone if no packager is set. Callers must hold m.mu.
+func (m *Message) lengthIndicatorConfig() LengthIndicatorConfig {
+	if m.packager == nil {
+		return LengthIndicatorConfig{Type: LengthIndicatorNone}
+	}
+	return m.packager.lengthIndicator
+}
+
+// packField packs a single field into the buffer using the given packager.
 // It's called by Pack.
-func (m *Message) packField(fieldNum int, buf []byte, offset int) (int, error) {
+func (m *Message) packField(packager *CompiledPackager, fieldNum int, buf []byte, offset int) (int, error) {
 	field := &m.fields[fieldNum-1]
 	if !field.parsed {
 		return 0, ErrFieldNotFound
 	}
 
-	if m.packager == nil {
+	if packager == nil {
 		return 0, fmt.Errorf("no packager configured")
 	}
 
-	config, exists := m.packager.fieldConfigs[fieldNum]
+	config, exists := packager.fieldConfigs[fieldNum]
 	if !exists {
 		return 0, fmt.Errorf("field %d not configured", fieldNum)
 	}
 
 	fieldData := field.Bytes()
+	if tlvData, modified, err := m.encodedTLVField(packager, fieldNum); err != nil {
+		return 0, err
+	} else if modified {
+		fieldData = tlvData
+	}
+	if compositeData, modified, err := m.encodedCompositeField(packager, fieldNum); err != nil {
+		return 0, err
+	} else if modified {
+		fieldData = compositeData
+	}
 	totalLen := 0 // Total bytes written for this field (prefix + data)
 
 	// 1. Write length prefix (LLVAR, LLLVAR, etc.)
 	switch config.Length {
-	case LengthLLVAR:
-		if len(buf) < offset+2 {
-			return 0, ErrBufferTooSmall
-		}
-		writeIntToASCII(buf[offset:offset+2], len(fieldData), 2)
-		totalLen += 2
-
-	case LengthLLLVAR:
-		if len(buf) < offset+3 {
-			return 0, ErrBufferTooSmall
-		}
-		writeIntToASCII(buf[offset:offset+3], len(fieldData), 3)
-		totalLen += 3
-
-	case LengthLLLLVAR:
-		if len(buf) < offset+4 {
-			return 0, ErrBufferTooSmall
+	case LengthLLVAR, LengthLLLVAR, LengthLLLLVAR:
+		n, err := writeLengthPrefix(buf[offset:], len(fieldData), lengthPrefixDigits(config.Length), config.LengthEncoding)
+		if err != nil {
+			return 0, err
 		}
-		writeIntToASCII(buf[offset:offset+4], len(fieldData), 4)
-		totalLen += 4
+		totalLen += n
 
 	case LengthFixed:
 		// No length prefix, but check if data length matches
@@ -579,12 +772,19 @@ func (m *Message) packField(fieldNum int, buf []byte, offset int) (int, error) {
 		}
 	}
 
-	// 2. Write field data
-	if len(buf) < offset+totalLen+len(fieldData) {
+	// 2. Write field data in its configured wire encoding
+	encoder, err := GetEncoder(config.Encoding)
+	if err != nil {
+		return 0, err
+	}
+	if len(buf) < offset+totalLen+encoder.EncodedLen(len(fieldData)) {
 		return 0, ErrBufferTooSmall
 	}
-	copy(buf[offset+totalLen:], fieldData)
-	totalLen += len(fieldData)
+	n, err := encoder.Encode(buf[offset+totalLen:], fieldData)
+	if err != nil {
+		return 0, err
+	}
+	totalLen += n
 
 	return totalLen, nil
 }
@@ -607,8 +807,9 @@ func (m *Message) Clone() *Message {
 	clone := NewMessage()
 	clone.mti = m.mti
 	clone.validationLevel = m.validationLevel
+	clone.validationMode = m.validationMode
 	clone.fieldPresence = m.fieldPresence
-	clone.packager = m.packager // Share the immutable packager
+	clone.setPackager(m.packager) // Share the immutable packager
 
 	// Copy header
 	if m.header != nil {
@@ -617,7 +818,7 @@ func (m *Message) Clone() *Message {
 	}
 
 	// Deep copy fields
-	for i := 0; i < 128; i++ {
+	for i := 0; i < MaxFieldNumber; i++ {
 		if m.isFieldPresent(i + 1) {
 			clone.fields[i] = *m.fields[i].Clone() // Use Field.Clone for deep copy
 			clone.bitmap.SetField(i + 1)
@@ -634,53 +835,59 @@ func (m *Message) Clone() *Message {
 			copy(clone.tlvData[fieldNum], tlvs)
 		}
 	}
+	if len(m.subfields) > 0 {
+		clone.subfields = make(map[int]map[int][]byte, len(m.subfields))
+		for fieldNum, subfields := range m.subfields {
+			clone.subfields[fieldNum] = make(map[int][]byte, len(subfields))
+			for num, value := range subfields {
+				clone.subfields[fieldNum][num] = append([]byte(nil), value...)
+			}
+		}
+	}
+	if len(m.subfieldModified) > 0 {
+		clone.subfieldModified = make(map[int]bool, len(m.subfieldModified))
+		for fieldNum, modified := range m.subfieldModified {
+			clone.subfieldModified[fieldNum] = modified
+		}
+	}
+	if len(m.tlvModified) > 0 {
+		clone.tlvModified = make(map[int]bool, len(m.tlvModified))
+		for fieldNum, modified := range m.tlvModified {
+			clone.tlvModified[fieldNum] = modified
+		}
+	}
 
 	return clone
 }
 
 // CreateResponse generates a response message based on the current message.
-// It clones the message, flips the MTI (e.g., 0100 -> 0110),
+// It derives the response MTI (e.g., 0100 -> 0110, 0221 -> 0230), copies
+// the request fields allowed by the packager's response template for the
+// request MTI, reverses the header if a header codec is configured,
 // and sets the response code (Field 39).
 func (m *Message) CreateResponse(responseCode string) (*Message, error) {
-	resMsg := m.Clone()
-
-	mti := resMsg.MTI()
-	if len(mti) != 4 || mti[2] != '0' {
-		return nil, fmt.Errorf("cannot create response from MTI: %s", mti)
-	}
-
-	// Flip MTI (e.g., 0x00 -> 0x10)
-	mtiBytes := make([]byte, 4)
-	copy(mtiBytes, mti)
-	mtiBytes[2] = '1' // e.g., '0' (request) -> '1' (response)
-
-	if err := resMsg.SetMTI(mtiBytes); err != nil {
-		resMsg.Release()
-		return nil, err
-	}
-
-	// Set Response Code
-	err := resMsg.SetField(39, responseCode)
-	if err != nil {
-		return nil, err
-	}
-
-	return resMsg, nil
+	return m.CreateResponseWith(responseCode, nil)
 }
 
 // LogValue implements the slog.LogValuer interface for structured logging.
+// Field values are masked with the packager's masking policy, and the raw
+// message is only included if the packager enables LogRawMessage.
 func (m *Message) LogValue() slog.Value {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	attrs := make([]slog.Attr, 0, 2)
-	attrs = append(attrs, slog.String("full_message", string(m.fullMessage)))
+	masker := m.packager.GetMasker()
+
+	attrs := make([]slog.Attr, 0, 3)
+	if m.packager != nil && m.packager.logRawMessage {
+		attrs = append(attrs, slog.String("full_message", string(m.fullMessage)))
+	}
 	attrs = append(attrs, slog.String("MTI", string(m.mti[:])))
 
 	// Pre-allocate a buffer on the stack to find present fields
-	var fieldsBuf [128]int
+	var fieldsBuf [MaxFieldNumber]int
 	count := 0
-	for i := 1; i <= 128; i++ {
+	for i := 1; i <= MaxFieldNumber; i++ {
 		if m.isFieldPresent(i) {
 			fieldsBuf[count] = i
 			count++
@@ -692,8 +899,7 @@ func (m *Message) LogValue() slog.Value {
 	for i := 0; i < count; i++ {
 		fieldNum := fieldsBuf[i]
 		field := &m.fields[fieldNum-1]
-		// TODO: Add masking for sensitive fields (PAN, etc.)
-		fieldArgs = append(fieldArgs, slog.String(fmt.Sprintf("%d", fieldNum), field.String()))
+		fieldArgs = append(fieldArgs, slog.String(fmt.Sprintf("%d", fieldNum), masker.Mask(fieldNum, field.Bytes())))
 	}
 
 	attrs = append(attrs, slog.Group("Fields", fieldArgs...))
@@ -705,7 +911,7 @@ func (m *Message) Validate() error {
 	if m.packager == nil || m.packager.validator == nil {
 		return nil // No validator configured
 	}
-	return m.packager.validator.ValidateMessage(m, m.validationLevel)
+	return m.packager.validator.ValidateMessageMode(m, m.validationLevel, m.validationMode)
 }
 
 // SetValidationLevel sets the validation level for this message instance.
@@ -722,13 +928,25 @@ func (m *Message) GetValidationLevel() ValidationLevel {
 	return m.validationLevel
 }
 
+// SetValidationMode chooses between fail-fast and collect-all validation
+// for Validate and Unpack.
+func (m *Message) SetValidationMode(mode ValidationMode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.validationMode = mode
+}
+
+// GetValidationMode returns the current validation mode.
+func (m *Message) GetValidationMode() ValidationMode {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.validationMode
+}
+
+// IsNMM reports whether the message is a network management message
+// (class 8, e.g. 0800, 0810, 0820, 1804).
 func (m *Message) IsNMM() bool {
-	switch string(m.MTI()) {
-	case MTI_NMM_REQUEST, MTI_NMM_RESPONSE:
-		return true
-	default:
-		return false
-	}
+	return m.GetMTI().IsNetworkManagement()
 }
 
 // MTI returns the 4-byte Message Type Indicator.
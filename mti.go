@@ -0,0 +1,151 @@
+package iso8583
+
+import "fmt"
+
+// MTI is a 4-digit Message Type Indicator: version, class, function and origin.
+type MTI [4]byte
+
+// MTIClass is the second MTI digit (the overall purpose of the message).
+type MTIClass byte
+
+const (
+	MTIClassAuthorization  MTIClass = '1'
+	MTIClassFinancial      MTIClass = '2'
+	MTIClassFileAction     MTIClass = '3'
+	MTIClassReversal       MTIClass = '4' // Reversals and chargebacks
+	MTIClassReconciliation MTIClass = '5'
+	MTIClassAdministrative MTIClass = '6'
+	MTIClassFeeCollection  MTIClass = '7'
+	MTIClassNetwork        MTIClass = '8' // Network management
+)
+
+// MTIFunction is the third MTI digit (request, response, advice, ...).
+type MTIFunction byte
+
+const (
+	MTIFunctionRequest         MTIFunction = '0'
+	MTIFunctionResponse        MTIFunction = '1'
+	MTIFunctionAdvice          MTIFunction = '2'
+	MTIFunctionAdviceResponse  MTIFunction = '3'
+	MTIFunctionNotification    MTIFunction = '4'
+	MTIFunctionNotificationAck MTIFunction = '5'
+	MTIFunctionInstruction     MTIFunction = '6' // ISO 8583:2003
+	MTIFunctionInstructionAck  MTIFunction = '7' // ISO 8583:2003
+)
+
+// MTIOrigin is the fourth MTI digit (who sent the message, and whether it is a repeat).
+type MTIOrigin byte
+
+const (
+	MTIOriginAcquirer       MTIOrigin = '0'
+	MTIOriginAcquirerRepeat MTIOrigin = '1'
+	MTIOriginIssuer         MTIOrigin = '2'
+	MTIOriginIssuerRepeat   MTIOrigin = '3'
+	MTIOriginOther          MTIOrigin = '4'
+	MTIOriginOtherRepeat    MTIOrigin = '5'
+)
+
+// ParseMTI parses a 4-digit MTI string such as "0100".
+func ParseMTI(s string) (MTI, error) {
+	var mti MTI
+	if len(s) != 4 {
+		return mti, ErrInvalidMTI
+	}
+	for i := 0; i < 4; i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return mti, fmt.Errorf("%w: %q", ErrInvalidMTI, s)
+		}
+	}
+	copy(mti[:], s)
+	return mti, nil
+}
+
+// String returns the MTI as a 4-character string.
+func (m MTI) String() string {
+	return string(m[:])
+}
+
+// Version returns the ISO 8583 edition from the first digit.
+// It returns 0 for private-use versions.
+func (m MTI) Version() Version {
+	v, _ := DetectVersion(m[:])
+	return v
+}
+
+// Class returns the message class (second digit).
+func (m MTI) Class() MTIClass {
+	return MTIClass(m[1])
+}
+
+// Function returns the message function (third digit).
+func (m MTI) Function() MTIFunction {
+	return MTIFunction(m[2])
+}
+
+// Origin returns the message origin (fourth digit).
+func (m MTI) Origin() MTIOrigin {
+	return MTIOrigin(m[3])
+}
+
+// IsRepeat reports whether the origin digit marks a repeated message (e.g., 0401, 0221).
+func (m MTI) IsRepeat() bool {
+	return m[3] >= '0' && m[3] <= '5' && (m[3]-'0')%2 == 1
+}
+
+// IsResponse reports whether the MTI is a response or acknowledgement
+// (function 1, 3, 5 or 7).
+func (m MTI) IsResponse() bool {
+	switch m.Function() {
+	case MTIFunctionResponse, MTIFunctionAdviceResponse, MTIFunctionNotificationAck, MTIFunctionInstructionAck:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsNetworkManagement reports whether the MTI belongs to class 8.
+func (m MTI) IsNetworkManagement() bool {
+	return m.Class() == MTIClassNetwork
+}
+
+// Response returns the MTI that answers m: requests become responses
+// (0100 -> 0110), advices become advice responses (0120 -> 0130),
+// notifications and instructions become acknowledgements, and a repeat
+// origin is answered as the original (0401 -> 0410, 0221 -> 0230).
+func (m MTI) Response() (MTI, error) {
+	res := m
+	switch m.Function() {
+	case MTIFunctionRequest:
+		res[2] = byte(MTIFunctionResponse)
+	case MTIFunctionAdvice:
+		res[2] = byte(MTIFunctionAdviceResponse)
+	case MTIFunctionNotification:
+		res[2] = byte(MTIFunctionNotificationAck)
+	case MTIFunctionInstruction:
+		res[2] = byte(MTIFunctionInstructionAck)
+	default:
+		return m, fmt.Errorf("%w: %s", ErrNoResponseMTI, m)
+	}
+
+	if m.IsRepeat() {
+		res[3]-- // 1 -> 0, 3 -> 2, 5 -> 4
+	}
+	return res, nil
+}
+
+// Matches reports whether the MTI matches a 4-character pattern in which
+// 'x' (or 'X') matches any digit, e.g. "04x0" or "x8xx".
+func (m MTI) Matches(pattern string) bool {
+	if len(pattern) != 4 {
+		return false
+	}
+	for i := 0; i < 4; i++ {
+		if pattern[i] == 'x' || pattern[i] == 'X' {
+			continue
+		}
+		if pattern[i] != m[i] {
+			return false
+		}
+	}
+	return true
+}
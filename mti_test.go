@@ -0,0 +1,89 @@
+package iso8583
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMTI(t *testing.T) {
+	mti, err := ParseMTI("1421")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mti.Version() != Version1993 || mti.Class() != MTIClassReversal ||
+		mti.Function() != MTIFunctionAdvice || mti.Origin() != MTIOriginAcquirerRepeat {
+		t.Errorf("ParseMTI(1421) = version %v, class %c, function %c, origin %c",
+			mti.Version(), mti.Class(), mti.Function(), mti.Origin())
+	}
+	if !mti.IsRepeat() || mti.IsResponse() || mti.IsNetworkManagement() {
+		t.Errorf("1421 flags: repeat %v, response %v, network %v", mti.IsRepeat(), mti.IsResponse(), mti.IsNetworkManagement())
+	}
+
+	for _, s := range []string{"", "010", "01000", "01a0"} {
+		if _, err := ParseMTI(s); !errors.Is(err, ErrInvalidMTI) {
+			t.Errorf("ParseMTI(%q) = %v, want ErrInvalidMTI", s, err)
+		}
+	}
+	if v := MTI([4]byte{'9', '1', '0', '0'}).Version(); v != 0 {
+		t.Errorf("private-use version = %v, want 0", v)
+	}
+}
+
+func TestMTIResponse(t *testing.T) {
+	tests := []struct {
+		mti  string
+		want string
+	}{
+		{"0100", "0110"},
+		{"0120", "0130"},
+		{"0200", "0210"},
+		{"0221", "0230"},
+		{"0401", "0410"},
+		{"0403", "0412"},
+		{"0800", "0810"},
+		{"1644", "1654"},
+		{"2160", "2170"},
+	}
+
+	for _, tt := range tests {
+		mti, _ := ParseMTI(tt.mti)
+		got, err := mti.Response()
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%s.Response() = %s, %v, want %s", tt.mti, got, err, tt.want)
+		}
+		if !got.IsResponse() {
+			t.Errorf("%s.IsResponse() = false", got)
+		}
+	}
+
+	for _, s := range []string{"0110", "0130", "0810"} {
+		mti, _ := ParseMTI(s)
+		if _, err := mti.Response(); !errors.Is(err, ErrNoResponseMTI) {
+			t.Errorf("%s.Response() = %v, want ErrNoResponseMTI", s, err)
+		}
+	}
+}
+
+func TestMTIMatches(t *testing.T) {
+	tests := []struct {
+		mti     string
+		pattern string
+		want    bool
+	}{
+		{"0400", "04x0", true},
+		{"0420", "04X0", true},
+		{"0401", "04x0", false},
+		{"0800", "x8xx", true},
+		{"1814", "x8xx", true},
+		{"0200", "0200", true},
+		{"0200", "020", false},
+		{"0200", "xxxxx", false},
+	}
+
+	for _, tt := range tests {
+		mti, _ := ParseMTI(tt.mti)
+		if got := mti.Matches(tt.pattern); got != tt.want {
+			t.Errorf("%s.Matches(%q) = %v, want %v", tt.mti, tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -6,7 +6,7 @@ type MessageOption func(*Message)
 // WithPackager sets the packager for the message
 func WithPackager(packager *CompiledPackager) MessageOption {
 	return func(m *Message) {
-		m.packager = packager
+		m.setPackager(packager)
 	}
 }
 
@@ -70,6 +70,133 @@ func WithHeaderConfig(config HeaderConfig) PackagerOption {
 	}
 }
 
+// WithBitmaps sets the maximum number of bitmaps (1-3) and how the
+// presence of each following bitmap is signalled
+func WithBitmaps(count int, extension BitmapExtension) PackagerOption {
+	return func(pc *PackagerConfig) {
+		pc.Bitmaps = count
+		pc.BitmapExtension = extension
+	}
+}
+
+// WithVersionDetection enables selecting the 1987, 1993 or 2003 field
+// layout from the MTI version digit during Unpack and Pack
+func WithVersionDetection(enabled bool) PackagerOption {
+	return func(pc *PackagerConfig) {
+		pc.DetectVersion = enabled
+	}
+}
+
+// WithVersionFields overrides the field layout used for a version when
+// version detection is enabled
+func WithVersionFields(v Version, fields map[int]FieldConfig) PackagerOption {
+	return func(pc *PackagerConfig) {
+		if pc.VersionFields == nil {
+			pc.VersionFields = make(map[Version]map[int]FieldConfig)
+		}
+		pc.VersionFields[v] = fields
+	}
+}
+
+// WithResponseTemplate sets the CreateResponse template for requests
+// matching an MTI or MTI pattern (e.g., "0100", "04x0")
+func WithResponseTemplate(pattern string, template ResponseTemplate) PackagerOption {
+	return func(pc *PackagerConfig) {
+		if pc.ResponseTemplates == nil {
+			pc.ResponseTemplates = make(map[string]ResponseTemplate)
+		}
+		pc.ResponseTemplates[pattern] = template
+	}
+}
+
+// WithDefaultPresenceRules applies DefaultPresenceRules, e.g. DE 39 in
+// responses and DE 90 in 1987 reversals, under any configured presence rules.
+func WithDefaultPresenceRules() PackagerOption {
+	return func(pc *PackagerConfig) {
+		pc.DefaultPresence = true
+	}
+}
+
+// WithPresenceRules sets the presence of fields in messages matching an
+// MTI or MTI pattern (e.g., "0800", "04x0")
+func WithPresenceRules(pattern string, rules map[int]Presence) PackagerOption {
+	return func(pc *PackagerConfig) {
+		if pc.PresenceRules == nil {
+			pc.PresenceRules = make(map[string]map[int]Presence)
+		}
+		if pc.PresenceRules[pattern] == nil {
+			pc.PresenceRules[pattern] = make(map[int]Presence)
+		}
+		for fieldNum, presence := range rules {
+			pc.PresenceRules[pattern][fieldNum] = presence
+		}
+	}
+}
+
+// WithCrossFieldRule adds a declarative rule over several fields
+func WithCrossFieldRule(rule CrossFieldRuleConfig) PackagerOption {
+	return func(pc *PackagerConfig) {
+		pc.CrossFieldRules = append(pc.CrossFieldRules, rule)
+	}
+}
+
+// WithPANRule sets the PAN checks of a field (e.g. BIN allow/deny lists for DE 2)
+func WithPANRule(fieldNum int, rule PANRule) PackagerOption {
+	return func(pc *PackagerConfig) {
+		if pc.PANRules == nil {
+			pc.PANRules = make(map[int]*PANRule)
+		}
+		pc.PANRules[fieldNum] = &rule
+	}
+}
+
+// WithTrackRule sets the track data checks of a field (e.g. SkipLuhn for
+// private-label cards in DE 35)
+func WithTrackRule(fieldNum int, rule TrackDataRule) PackagerOption {
+	return func(pc *PackagerConfig) {
+		if pc.TrackRules == nil {
+			pc.TrackRules = make(map[int]*TrackDataRule)
+		}
+		pc.TrackRules[fieldNum] = &rule
+	}
+}
+
+// WithTrackConsistency checks the PAN and expiry date in track data against
+// other fields, e.g. TrackConsistencyRule{PANField: 2, ExpiryField: 14}
+func WithTrackConsistency(rule TrackConsistencyRule) PackagerOption {
+	return func(pc *PackagerConfig) {
+		pc.TrackConsistency = &rule
+	}
+}
+
+// WithMasking overrides the log masking mode of individual fields
+func WithMasking(policy map[int]MaskMode) PackagerOption {
+	return func(pc *PackagerConfig) {
+		if pc.Masking == nil {
+			pc.Masking = make(map[int]MaskMode)
+		}
+		for fieldNum, mode := range policy {
+			pc.Masking[fieldNum] = mode
+		}
+	}
+}
+
+// WithMaskingKey sets the HMAC key used for MaskHash fields.
+// Without a key, MaskHash fields are fully masked.
+func WithMaskingKey(key []byte) PackagerOption {
+	return func(pc *PackagerConfig) {
+		pc.MaskingKey = append([]byte(nil), key...)
+	}
+}
+
+// WithRawMessageLogging includes the unmasked raw message in LogValue.
+// Only enable this where logs may hold cardholder data
+func WithRawMessageLogging(enabled bool) PackagerOption {
+	return func(pc *PackagerConfig) {
+		pc.LogRawMessage = enabled
+	}
+}
+
 // WithTLVConfig sets the TLV configuration
 func WithTLVConfig(config TLVConfig) PackagerOption {
 	return func(pc *PackagerConfig) {
@@ -84,6 +211,19 @@ func WithValidationLevel(level ValidationLevel) MessageOption {
 	}
 }
 
+// WithValidationMode chooses between fail-fast and collect-all validation.
+func WithValidationMode(mode ValidationMode) MessageOption {
+	return func(m *Message) {
+		m.validationMode = mode
+	}
+}
+
+// WithCollectAllErrors makes Validate and Unpack return a *ValidationReport
+// with every failure instead of stopping at the first one.
+func WithCollectAllErrors() MessageOption {
+	return WithValidationMode(ValidationCollectAll)
+}
+
 func WithStrictValidation() MessageOption {
 	return WithValidationLevel(ValidationStrict)
 }
@@ -5,43 +5,141 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"sort"
 )
 
 // CompiledPackager holds the complete specification (schema) for an ISO8583 message.
 // It contains all field configurations, bitmap encoding, length/header settings,
 // and a pre-compiled validator. It is immutable and safe for concurrent use.
 type CompiledPackager struct {
-	fieldConfigs    map[int]FieldConfig   // Configuration for each field (DE 2, DE 3, etc.)
-	bitmapEncoding  BitmapEncoding        // Binary or Hex
-	lengthIndicator LengthIndicatorConfig // Config for the 2/4 byte message length prefix
-	headerConfig    HeaderConfig          // Config for any message header (e.g., TPDU)
-	tlvConfig       TLVConfig             // Config for TLV-encoded fields (e.g., DE 55)
-	validator       *CompiledValidator    // Pre-compiled validator based on field configs
+	fieldConfigs      map[int]FieldConfig           // Configuration for each field (DE 2, DE 3, etc.)
+	bitmapEncoding    BitmapEncoding                // Binary or Hex
+	bitmaps           int                           // Maximum number of bitmaps (1-3)
+	bitmapExtension   BitmapExtension               // Chained (DE 1/DE 65 flags) or fixed bitmaps
+	lengthIndicator   LengthIndicatorConfig         // Config for the 2/4 byte message length prefix
+	headerConfig      HeaderConfig                  // Config for any message header (e.g., TPDU)
+	headerCodec       HeaderCodec                   // Structured header codec selected by HeaderConfig.Format (may be nil)
+	tlvConfig         TLVConfig                     // Config for TLV-encoded fields (e.g., DE 55)
+	tlvParsers        map[int]*TLVParser            // Parsers for fields marked as TLV, keyed by field number
+	compositeFields   []int                         // Fields with subfield definitions, in ascending order
+	fieldAliases      map[string]int                // Field numbers keyed by FieldConfig.Alias
+	validator         *CompiledValidator            // Pre-compiled validator based on field configs
+	version           Version                       // Field layout edition (1987, 1993 or 2003)
+	versionProfiles   map[Version]*CompiledPackager // Profiles selected by MTI version (nil if detection is off)
+	responseTemplates []responseTemplateEntry       // CreateResponse templates, most specific pattern first
+	masker            *Masker                       // Masking policy applied by LogValue and Dump
+	logRawMessage     bool                          // Whether LogValue includes the unmasked raw message
 }
 
 // NewCompiledPackager creates a new CompiledPackager from a PackagerConfig.
 // It also compiles the validation rules from the config.
 func NewCompiledPackager(config *PackagerConfig) *CompiledPackager {
 	cp := &CompiledPackager{
-		fieldConfigs:    config.Fields,
-		bitmapEncoding:  config.BitmapEncoding,
-		lengthIndicator: config.LengthIndicator,
-		headerConfig:    config.Header,
-		tlvConfig:       config.TLV,
+		fieldConfigs:      config.Fields,
+		bitmapEncoding:    config.BitmapEncoding,
+		bitmaps:           config.Bitmaps,
+		bitmapExtension:   config.BitmapExtension,
+		lengthIndicator:   config.LengthIndicator,
+		headerConfig:      config.Header,
+		headerCodec:       lookupHeaderCodec(config.Header.Format),
+		tlvConfig:         config.TLV,
+		version:           config.Version,
+		responseTemplates: compileResponseTemplates(config.ResponseTemplates),
+		masker:            NewMasker(config.Masking, WithHashKey(maskingKey(config))),
+		logRawMessage:     config.LogRawMessage,
+	}
+	if cp.version == 0 {
+		cp.version = Version1987
+	}
+
+	// Build a TLV parser for every field marked as TLV
+	if config.TLV.Enabled {
+		cp.tlvParsers = make(map[int]*TLVParser)
+		for fieldNum, fieldConfig := range config.Fields {
+			if fieldConfig.TLV != nil {
+				cp.tlvParsers[fieldNum] = newFieldTLVParser(fieldConfig.TLV, config.TLV.MaxDepth)
+			}
+		}
+	}
+
+	if cp.bitmaps < 1 || cp.bitmaps > MaxBitmaps {
+		cp.bitmaps = DefaultBitmaps
 	}
 
+	// Record composite fields so Unpack only visits those, and field aliases
+	for fieldNum, fieldConfig := range config.Fields {
+		if len(fieldConfig.Subfields) > 0 {
+			cp.compositeFields = append(cp.compositeFields, fieldNum)
+		}
+		if fieldConfig.Alias != "" {
+			if cp.fieldAliases == nil {
+				cp.fieldAliases = make(map[string]int)
+			}
+			cp.fieldAliases[fieldConfig.Alias] = fieldNum
+		}
+	}
+	sort.Ints(cp.compositeFields)
+
 	// Pre-compile validation rules for efficiency
 	cp.validator = compileValidator(config)
 
+	if config.DetectVersion {
+		cp.compileVersionProfiles(config)
+	}
+
 	return cp
 }
 
+// maskingKey returns the MaskHash key from the config, or from the
+// environment variable named by MaskingKeyEnv.
+func maskingKey(config *PackagerConfig) []byte {
+	if len(config.MaskingKey) > 0 || config.MaskingKeyEnv == "" {
+		return config.MaskingKey
+	}
+	return []byte(os.Getenv(config.MaskingKeyEnv))
+}
+
 // GetFieldConfig retrieves the configuration for a specific field number.
 func (cp *CompiledPackager) GetFieldConfig(fieldNum int) (FieldConfig, bool) {
 	config, exists := cp.fieldConfigs[fieldNum]
 	return config, exists
 }
 
+// FieldByAlias returns the number of the field configured with the given alias.
+func (cp *CompiledPackager) FieldByAlias(alias string) (int, bool) {
+	fieldNum, exists := cp.fieldAliases[alias]
+	return fieldNum, exists
+}
+
+// GetLengthIndicator returns the message length indicator configuration.
+func (cp *CompiledPackager) GetLengthIndicator() LengthIndicatorConfig {
+	return cp.lengthIndicator
+}
+
+// GetTLVParser returns the TLV parser for a field marked as TLV.
+func (cp *CompiledPackager) GetTLVParser(fieldNum int) (*TLVParser, bool) {
+	parser, exists := cp.tlvParsers[fieldNum]
+	return parser, exists
+}
+
+// GetBitmaps returns the maximum number of bitmaps and the extension convention.
+func (cp *CompiledPackager) GetBitmaps() (int, BitmapExtension) {
+	return cp.bitmaps, cp.bitmapExtension
+}
+
+// GetVersion returns the ISO 8583 edition of the packager's field layout.
+func (cp *CompiledPackager) GetVersion() Version {
+	return cp.version
+}
+
+// GetMasker returns the masking policy used for logging.
+func (cp *CompiledPackager) GetMasker() *Masker {
+	if cp == nil {
+		return defaultMasker
+	}
+	return cp.masker
+}
+
 // GetValidator returns the pre-compiled validator for this packager.
 func (cp *CompiledPackager) GetValidator() *CompiledValidator {
 	return cp.validator
@@ -59,6 +157,10 @@ func (cp *CompiledPackager) LogValue() slog.Value {
 
 	// Log simple values. slog.Any will handle the int-based types.
 	attrs = append(attrs, slog.Any("bitmap_encoding", cp.bitmapEncoding))
+	attrs = append(attrs, slog.String("version", cp.version.String()))
+	attrs = append(attrs, slog.Bool("detect_version", cp.versionProfiles != nil))
+	attrs = append(attrs, slog.Int("bitmaps", cp.bitmaps))
+	attrs = append(attrs, slog.Any("bitmap_extension", cp.bitmapExtension))
 
 	// Log sub-structs as groups
 	attrs = append(attrs, slog.Group("length_indicator",
@@ -69,6 +171,7 @@ func (cp *CompiledPackager) LogValue() slog.Value {
 	attrs = append(attrs, slog.Group("header_config",
 		slog.Any("type", cp.headerConfig.Type),
 		slog.Int("length", cp.headerConfig.Length),
+		slog.String("format", cp.headerConfig.Format),
 	))
 
 	attrs = append(attrs, slog.Group("tlv_config",
@@ -111,6 +214,36 @@ func LoadPackagerFromByte(data []byte) (*CompiledPackager, error) {
 		return nil, fmt.Errorf("failed to parse packager config: %w", err)
 	}
 
+	// Report invalid rules now rather than on every validation
+	if err := validatePresenceRules(config.PresenceRules); err != nil {
+		return nil, fmt.Errorf("failed to parse packager config: %w", err)
+	}
+	if err := validateResponseTemplates(config.ResponseTemplates); err != nil {
+		return nil, fmt.Errorf("failed to parse packager config: %w", err)
+	}
+	aliases := fieldAliases(config.Fields)
+	for _, rule := range config.CrossFieldRules {
+		if _, err := NewCrossFieldRule(rule, aliases); err != nil {
+			return nil, fmt.Errorf("failed to parse packager config: %w", err)
+		}
+	}
+	for fieldNum, rule := range config.PANRules {
+		if rule == nil {
+			continue
+		}
+		if err := rule.Check(); err != nil {
+			return nil, fmt.Errorf("failed to parse packager config: pan rule for field %d: %w", fieldNum, err)
+		}
+	}
+	for fieldNum, rule := range config.TrackRules {
+		if rule == nil {
+			continue
+		}
+		if _, err := trackRuleFor(rule, config.Fields[fieldNum]); err != nil {
+			return nil, fmt.Errorf("failed to parse packager config: track rule for field %d: %w", fieldNum, err)
+		}
+	}
+
 	return NewCompiledPackager(&config), nil
 }
 
@@ -119,6 +252,7 @@ func DefaultPackagerConfig() *PackagerConfig {
 	return &PackagerConfig{
 		Fields:         DefaultConfigField, // Assumes this is a map[int]FieldConfig
 		BitmapEncoding: BitmapEncodingHex,
+		Bitmaps:        DefaultBitmaps,
 		LengthIndicator: LengthIndicatorConfig{
 			Type:   LengthIndicatorNone,
 			Length: 0,
@@ -0,0 +1,121 @@
+// Package pan provides helpers for primary account numbers (card numbers):
+// Luhn check digits, BIN extraction and matching, and masking for display.
+package pan
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	MinLength = 12 // Shortest PAN allowed by ISO/IEC 7812
+	MaxLength = 19 // Longest PAN allowed by ISO/IEC 7812
+)
+
+var (
+	ErrInvalidPAN        = fmt.Errorf("invalid PAN")
+	ErrInvalidBINPattern = fmt.Errorf("invalid BIN pattern")
+)
+
+// IsDigits reports whether s is non-empty and contains only ASCII digits.
+func IsDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// luhnSum returns the Luhn sum of number, doubling every second digit
+// from the right starting with the rightmost digit if doubleFirst is set.
+func luhnSum(number string, doubleFirst bool) int {
+	sum := 0
+	double := doubleFirst
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum
+}
+
+// Luhn reports whether the last digit of number is a valid Luhn check digit.
+func Luhn(number string) bool {
+	if len(number) < 2 || !IsDigits(number) {
+		return false
+	}
+	return luhnSum(number, false)%10 == 0
+}
+
+// CheckDigit computes the Luhn check digit to append to partial.
+func CheckDigit(partial string) (byte, error) {
+	if !IsDigits(partial) {
+		return 0, fmt.Errorf("%w: non-numeric digits", ErrInvalidPAN)
+	}
+	return byte('0' + (10-luhnSum(partial, true)%10)%10), nil
+}
+
+// Valid reports whether number is a 12-19 digit PAN with a valid Luhn check digit.
+func Valid(number string) bool {
+	return len(number) >= MinLength && len(number) <= MaxLength && Luhn(number)
+}
+
+// BIN returns the first length digits of number (6 or 8 in practice), or
+// an empty string if number is shorter.
+func BIN(number string, length int) string {
+	if length <= 0 || len(number) < length {
+		return ""
+	}
+	return number[:length]
+}
+
+// CheckBINPattern reports whether pattern is a valid BIN pattern: a prefix
+// of up to MaxLength digits, or a range of two equal-length prefixes with
+// the low end first.
+func CheckBINPattern(pattern string) error {
+	low, high, isRange := strings.Cut(pattern, "-")
+	if !isRange {
+		high = low
+	}
+	if !IsDigits(low) || !IsDigits(high) || len(low) > MaxLength {
+		return fmt.Errorf("%w %q: prefixes must be 1-%d digits", ErrInvalidBINPattern, pattern, MaxLength)
+	}
+	if len(low) != len(high) || low > high {
+		return fmt.Errorf("%w %q: range ends must have equal length, low first", ErrInvalidBINPattern, pattern)
+	}
+	return nil
+}
+
+// MatchBIN reports whether number falls in a BIN pattern: a prefix such as
+// "4" or "510510", or an inclusive range of equal-length prefixes such as
+// "222100-272099". Malformed patterns never match; see CheckBINPattern.
+func MatchBIN(number, pattern string) bool {
+	low, high, isRange := strings.Cut(pattern, "-")
+	if !isRange {
+		return IsDigits(pattern) && strings.HasPrefix(number, pattern)
+	}
+	if len(low) != len(high) || !IsDigits(low) || !IsDigits(high) || len(number) < len(low) {
+		return false
+	}
+	prefix := number[:len(low)]
+	return prefix >= low && prefix <= high
+}
+
+// Mask keeps the first 6 and last 4 characters of a PAN and replaces the
+// rest with '*'. Values of 10 characters or fewer are fully masked.
+func Mask(number string) string {
+	if len(number) <= 10 {
+		return strings.Repeat("*", len(number))
+	}
+	return number[:6] + strings.Repeat("*", len(number)-10) + number[len(number)-4:]
+}
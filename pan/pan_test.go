@@ -0,0 +1,164 @@
+package pan
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsDigits(t *testing.T) {
+	for s, want := range map[string]bool{"0": true, "4111": true, "": false, "41a1": false, " 41": false} {
+		if got := IsDigits(s); got != want {
+			t.Errorf("IsDigits(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"4111111111111111", true},
+		{"4111111111111112", false},
+		{"5500005555555559", true},
+		{"378282246310005", true},
+		{"6011000990139424", true},
+		{"79927398713", true},
+		{"79927398710", false},
+		{"00", true},
+		{"0", false}, // Too short to carry a check digit
+		{"", false},
+		{"4111 1111 1111 1111", false},
+		{"411111111111111a", false},
+	}
+
+	for _, tt := range tests {
+		if got := Luhn(tt.number); got != tt.want {
+			t.Errorf("Luhn(%q) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDigit(t *testing.T) {
+	tests := []struct {
+		partial string
+		want    byte
+	}{
+		{"411111111111111", '1'},
+		{"550000555555555", '9'},
+		{"7992739871", '3'},
+		{"0", '0'},
+	}
+
+	for _, tt := range tests {
+		got, err := CheckDigit(tt.partial)
+		if err != nil || got != tt.want {
+			t.Errorf("CheckDigit(%q) = %q, %v; want %q", tt.partial, got, err, tt.want)
+		}
+		if !Luhn(tt.partial + string(got)) {
+			t.Errorf("Luhn(%q) = false after CheckDigit", tt.partial+string(got))
+		}
+	}
+
+	for _, partial := range []string{"", "41111x"} {
+		if _, err := CheckDigit(partial); !errors.Is(err, ErrInvalidPAN) {
+			t.Errorf("CheckDigit(%q) = %v, want ErrInvalidPAN", partial, err)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"4111111111111111", true},
+		{"79927398713", false},           // Valid Luhn, 11 digits
+		{"411111111111111111111", false}, // 21 digits
+		{"4111111111111112", false},
+	}
+
+	for _, tt := range tests {
+		if got := Valid(tt.number); got != tt.want {
+			t.Errorf("Valid(%q) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestBIN(t *testing.T) {
+	tests := []struct {
+		number string
+		length int
+		want   string
+	}{
+		{"4111111111111111", 6, "411111"},
+		{"4111111111111111", 8, "41111111"},
+		{"41111", 6, ""},
+		{"4111111111111111", 0, ""},
+	}
+
+	for _, tt := range tests {
+		if got := BIN(tt.number, tt.length); got != tt.want {
+			t.Errorf("BIN(%q, %d) = %q, want %q", tt.number, tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestMatchBIN(t *testing.T) {
+	tests := []struct {
+		number  string
+		pattern string
+		want    bool
+	}{
+		{"4111111111111111", "4", true},
+		{"4111111111111111", "411111", true},
+		{"4111111111111111", "411112", false},
+		{"2221001234567890", "222100-272099", true},
+		{"2720991234567890", "222100-272099", true},
+		{"2721001234567890", "222100-272099", false},
+		{"5105105105105100", "51-55", true},
+		{"5605105105105100", "51-55", false},
+		{"22", "222100-272099", false}, // Shorter than the range
+		{"4111111111111111", "41xxxx", false},
+		{"4111111111111111", "4-41", false},
+		{"4111111111111111", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchBIN(tt.number, tt.pattern); got != tt.want {
+			t.Errorf("MatchBIN(%q, %q) = %v, want %v", tt.number, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestCheckBINPattern(t *testing.T) {
+	for _, pattern := range []string{"4", "411111", "41111111", "222100-272099", "51-55", "40-40"} {
+		if err := CheckBINPattern(pattern); err != nil {
+			t.Errorf("CheckBINPattern(%q) = %v", pattern, err)
+		}
+	}
+
+	for _, pattern := range []string{"", "41xxxx", "411111 ", " 4", "4-", "-4", "4-41", "55-51", "4-5-6", "41111111111111111111"} {
+		if err := CheckBINPattern(pattern); !errors.Is(err, ErrInvalidBINPattern) {
+			t.Errorf("CheckBINPattern(%q) = %v, want ErrInvalidBINPattern", pattern, err)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		number string
+		want   string
+	}{
+		{"4111111111111111", "411111******1111"},
+		{"41111111111", "411111*1111"},
+		{"4111111111", "**********"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Mask(tt.number); got != tt.want {
+			t.Errorf("Mask(%q) = %q, want %q", tt.number, got, tt.want)
+		}
+	}
+}
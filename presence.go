@@ -0,0 +1,170 @@
+package iso8583
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Presence is the presence condition of a field for an MTI.
+type Presence int
+
+const (
+	PresenceOptional    Presence = iota // Field may be present
+	PresenceMandatory                   // Field must be present
+	PresenceConditional                 // Presence depends on other fields; not checked by presence rules
+	PresenceForbidden                   // Field must not be present
+)
+
+func (p *Presence) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case float64:
+		if v != float64(int(v)) || v < float64(PresenceOptional) || v > float64(PresenceForbidden) {
+			return fmt.Errorf("invalid presence %v", v)
+		}
+		*p = Presence(v)
+	case string:
+		presence, ok := parsePresenceString(v)
+		if !ok {
+			return fmt.Errorf("invalid presence %q", v)
+		}
+		*p = presence
+	default:
+		return fmt.Errorf("invalid presence %s", data)
+	}
+	return nil
+}
+
+func parsePresenceString(s string) (Presence, bool) {
+	switch strings.ToUpper(s) {
+	case "O", "OPTIONAL":
+		return PresenceOptional, true
+	case "M", "MANDATORY":
+		return PresenceMandatory, true
+	case "C", "CONDITIONAL":
+		return PresenceConditional, true
+	case "X", "F", "FORBIDDEN":
+		return PresenceForbidden, true
+	default:
+		return PresenceOptional, false
+	}
+}
+
+// DefaultPresenceRules adjusts the global FieldConfig.Mandatory flags of the
+// default field layout per message class. They only apply when enabled with
+// WithDefaultPresenceRules; configured rules are layered on top of them,
+// pattern by pattern and field by field.
+var DefaultPresenceRules = map[string]map[int]Presence{
+	// Network management carries no transaction data
+	"x8xx": {
+		3:  PresenceOptional,
+		4:  PresenceOptional,
+		12: PresenceOptional,
+		13: PresenceOptional,
+		22: PresenceOptional,
+		25: PresenceOptional,
+		49: PresenceOptional,
+	},
+	"08xx": {70: PresenceMandatory}, // Network management information code
+	"18xx": {24: PresenceMandatory}, // Function code
+	"28xx": {24: PresenceMandatory},
+	// Responses carry a response code and no cardholder secrets
+	"xx10": {39: PresenceMandatory, 35: PresenceForbidden, 45: PresenceForbidden, 52: PresenceForbidden},
+	"xx30": {39: PresenceMandatory, 35: PresenceForbidden, 45: PresenceForbidden, 52: PresenceForbidden},
+	// Reversals identify the original transaction
+	"04x0": {90: PresenceMandatory}, // Original data elements (1987)
+	"14x0": {56: PresenceMandatory}, // Original data elements (1993)
+	"24x0": {56: PresenceMandatory},
+}
+
+// presenceRuleEntry is a compiled set of presence rules with its MTI pattern.
+type presenceRuleEntry struct {
+	pattern string
+	fields  map[int]Presence
+}
+
+// validatePresenceRules checks that every pattern is an MTI pattern
+// (four digits or 'x') and every field number is in range.
+func validatePresenceRules(rules map[string]map[int]Presence) error {
+	for pattern, fields := range rules {
+		if len(pattern) != 4 || strings.Trim(pattern, "0123456789xX") != "" {
+			return fmt.Errorf("%w: presence pattern %q must be four digits or x", ErrInvalidMTI, pattern)
+		}
+		for fieldNum := range fields {
+			if fieldNum < 1 || fieldNum > MaxFieldNumber {
+				return fmt.Errorf("%w: presence rule for field %d in %s", ErrInvalidField, fieldNum, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// compilePresenceRules merges configured rules over DefaultPresenceRules
+// (if withDefaults is set) and orders them from most to least specific
+// pattern (fewest wildcards first), so the first entry with a rule for a
+// field wins. Invalid patterns are skipped; see validatePresenceRules.
+func compilePresenceRules(rules map[string]map[int]Presence, withDefaults bool) []presenceRuleEntry {
+	sources := []map[string]map[int]Presence{rules}
+	if withDefaults {
+		sources = []map[string]map[int]Presence{DefaultPresenceRules, rules}
+	}
+	merged := make(map[string]map[int]Presence)
+	for _, source := range sources {
+		for pattern, fields := range source {
+			if len(pattern) != 4 {
+				continue
+			}
+			if merged[pattern] == nil {
+				merged[pattern] = make(map[int]Presence, len(fields))
+			}
+			for fieldNum, presence := range fields {
+				merged[pattern][fieldNum] = presence
+			}
+		}
+	}
+
+	entries := make([]presenceRuleEntry, 0, len(merged))
+	for pattern, fields := range merged {
+		entries = append(entries, presenceRuleEntry{pattern: pattern, fields: fields})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		wi, wj := mtiWildcards(entries[i].pattern), mtiWildcards(entries[j].pattern)
+		if wi != wj {
+			return wi < wj
+		}
+		return entries[i].pattern < entries[j].pattern
+	})
+	return entries
+}
+
+// presenceFor returns the presence condition of a field given the rule
+// entries matching the message MTI; fields without a rule fall back to
+// FieldConfig.Mandatory.
+func (cv *CompiledValidator) presenceFor(matched []presenceRuleEntry, fieldNum int) Presence {
+	for _, entry := range matched {
+		if presence, ok := entry.fields[fieldNum]; ok {
+			return presence
+		}
+	}
+	if cv.mandatoryFields[fieldNum] {
+		return PresenceMandatory
+	}
+	return PresenceOptional
+}
+
+// matchPresenceRules returns the rule entries matching an MTI, most specific first.
+func (cv *CompiledValidator) matchPresenceRules(mti MTI) []presenceRuleEntry {
+	var matched []presenceRuleEntry
+	for _, entry := range cv.presenceRules {
+		if mti.Matches(entry.pattern) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
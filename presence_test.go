@@ -0,0 +1,138 @@
+package iso8583
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// transactionFields are the fields DefaultConfigField marks mandatory.
+var transactionFields = map[int]string{
+	3: "000000", 4: "000000001000", 7: "1016120000", 11: "000123",
+	12: "120000", 13: "1016", 22: "051", 25: "00", 49: "360",
+}
+
+// newPresenceMessage returns a message validated at ValidationBasic,
+// collecting every failure.
+func newPresenceMessage(t *testing.T, pkg *CompiledPackager, mti string, fields map[int]string) *Message {
+	t.Helper()
+	m := newTestMessage(t, pkg, mti, fields)
+	m.SetValidationLevel(ValidationBasic)
+	m.SetValidationMode(ValidationCollectAll)
+	return m
+}
+
+// withFields returns transactionFields plus extra, minus the fields in drop.
+func withFields(extra map[int]string, drop ...int) map[int]string {
+	fields := make(map[int]string, len(transactionFields)+len(extra))
+	for fieldNum, value := range transactionFields {
+		fields[fieldNum] = value
+	}
+	for fieldNum, value := range extra {
+		fields[fieldNum] = value
+	}
+	for _, fieldNum := range drop {
+		delete(fields, fieldNum)
+	}
+	return fields
+}
+
+func TestPresenceRules(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig(
+		WithDefaultPresenceRules(),
+		WithPresenceRules("02xx", map[int]Presence{41: PresenceMandatory, 25: PresenceOptional}),
+		WithPresenceRules("0200", map[int]Presence{41: PresenceForbidden}),
+		WithPresenceRules("0100", map[int]Presence{4: PresenceConditional}),
+	))
+	track2 := "4111111111111111=2512101"
+
+	tests := []struct {
+		name   string
+		mti    string
+		fields map[int]string
+		want   []string
+	}{
+		{"most specific pattern wins", "0200", withFields(map[int]string{41: "TERM0001"}), []string{"41:forbidden"}},
+		{"pattern", "0220", withFields(nil, 25), []string{"41:mandatory"}},
+		{"conditional not checked", "0100", withFields(nil, 4), nil},
+		{"global mandatory", "0100", withFields(nil, 11), []string{"11:mandatory"}},
+		{"default response rules", "0210", withFields(map[int]string{35: track2, 41: "TERM0001"}), []string{"35:forbidden", "39:mandatory"}},
+		{"default network rules", "0800", map[int]string{7: "1016120000", 11: "000123", 70: "301"}, nil},
+		{"network information code", "0800", map[int]string{7: "1016120000", 11: "000123"}, []string{"70:mandatory"}},
+		{"default reversal rules", "0400", withFields(nil), []string{"90:mandatory"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := failedRules(t, newPresenceMessage(t, pkg, tt.mti, tt.fields).Validate())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("failures = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPresenceRulesOptIn(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig())
+
+	if err := newPresenceMessage(t, pkg, "0210", withFields(nil)).Validate(); err != nil {
+		t.Errorf("0210 without DE 39 = %v, want default rules off", err)
+	}
+	got := failedRules(t, newPresenceMessage(t, pkg, "0800", map[int]string{7: "1016120000", 11: "000123", 70: "301"}).Validate())
+	want := []string{"3:mandatory", "4:mandatory", "12:mandatory", "13:mandatory", "22:mandatory", "25:mandatory", "49:mandatory"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("0800 failures = %v, want %v", got, want)
+	}
+}
+
+func TestPresenceUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    Presence
+		wantErr bool
+	}{
+		{`"M"`, PresenceMandatory, false},
+		{`"optional"`, PresenceOptional, false},
+		{`"C"`, PresenceConditional, false},
+		{`"X"`, PresenceForbidden, false},
+		{`"Forbidden"`, PresenceForbidden, false},
+		{`1`, PresenceMandatory, false},
+		{`"Z"`, 0, true},
+		{`4`, 0, true},
+		{`1.5`, 0, true},
+		{`true`, 0, true},
+	}
+
+	for _, tt := range tests {
+		var p Presence
+		err := json.Unmarshal([]byte(tt.data), &p)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && p != tt.want) {
+			t.Errorf("Unmarshal(%s) = %v, %v, want %v, wantErr %v", tt.data, p, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPresenceRulesConfig(t *testing.T) {
+	tests := []struct {
+		data string
+		err  error
+	}{
+		{`{"presence_rules": {"040": {"90": "M"}}}`, ErrInvalidMTI},
+		{`{"presence_rules": {"04y0": {"90": "M"}}}`, ErrInvalidMTI},
+		{`{"presence_rules": {"04x0": {"0": "M"}}}`, ErrInvalidField},
+		{`{"presence_rules": {"04x0": {"193": "M"}}}`, ErrInvalidField},
+		{`{"presence_rules": {"04x0": {"90": "M"}, "x8xx": {"3": "O", "70": "mandatory"}}}`, nil},
+	}
+
+	for _, tt := range tests {
+		_, err := LoadPackagerFromByte([]byte(tt.data))
+		if (tt.err == nil && err != nil) || (tt.err != nil && !errors.Is(err, tt.err)) {
+			t.Errorf("LoadPackagerFromByte(%s) = %v, want %v", tt.data, err, tt.err)
+		}
+	}
+
+	if _, err := LoadPackagerFromByte([]byte(`{"presence_rules": {"04x0": {"90": "Z"}}}`)); err == nil {
+		t.Error("LoadPackagerFromByte accepted an invalid presence")
+	}
+}
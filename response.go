@@ -0,0 +1,165 @@
+package iso8583
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResponseTemplate describes how CreateResponse builds the response to a
+// request. Templates are keyed in PackagerConfig.ResponseTemplates by
+// request MTI or MTI pattern (e.g., "0100", "04x0", "x8xx").
+type ResponseTemplate struct {
+	Echo     []int          `json:"echo,omitempty"`     // Fields copied from the request (empty means every field not dropped)
+	Drop     []int          `json:"drop,omitempty"`     // Fields never copied from the request
+	Required []int          `json:"required,omitempty"` // Fields the response must carry (e.g., 38, 39)
+	Defaults map[int]string `json:"defaults,omitempty"` // Values set when the field is still absent
+}
+
+// DefaultResponseTemplate is used when no configured template matches the
+// request MTI. It echoes the request but never returns cardholder
+// secrets (track data, PIN block, EMV request data) or the request's MACs.
+var DefaultResponseTemplate = ResponseTemplate{
+	Drop:     []int{35, 36, 45, 52, 53, 55, 64, 128},
+	Required: []int{39},
+}
+
+// responseTemplateEntry is a compiled template with its MTI pattern.
+type responseTemplateEntry struct {
+	pattern  string
+	template ResponseTemplate
+}
+
+// validateResponseTemplates checks that every pattern is an MTI pattern
+// (four digits or 'x') and every field number is in range.
+func validateResponseTemplates(templates map[string]ResponseTemplate) error {
+	for pattern, template := range templates {
+		if len(pattern) != 4 || strings.Trim(pattern, "0123456789xX") != "" {
+			return fmt.Errorf("%w: response template pattern %q must be four digits or x", ErrInvalidMTI, pattern)
+		}
+		fields := append(append(append([]int(nil), template.Echo...), template.Drop...), template.Required...)
+		for fieldNum := range template.Defaults {
+			fields = append(fields, fieldNum)
+		}
+		for _, fieldNum := range fields {
+			if fieldNum < 1 || fieldNum > MaxFieldNumber {
+				return fmt.Errorf("%w: response template field %d in %s", ErrInvalidField, fieldNum, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// compileResponseTemplates orders templates from most to least specific
+// pattern (fewest wildcards first), so the first match wins. Invalid
+// patterns are skipped; see validateResponseTemplates.
+func compileResponseTemplates(templates map[string]ResponseTemplate) []responseTemplateEntry {
+	entries := make([]responseTemplateEntry, 0, len(templates))
+	for pattern, template := range templates {
+		if len(pattern) != 4 {
+			continue
+		}
+		entries = append(entries, responseTemplateEntry{pattern: pattern, template: template})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		wi, wj := mtiWildcards(entries[i].pattern), mtiWildcards(entries[j].pattern)
+		if wi != wj {
+			return wi < wj
+		}
+		return entries[i].pattern < entries[j].pattern
+	})
+	return entries
+}
+
+// mtiWildcards counts the 'x' positions in an MTI pattern.
+func mtiWildcards(pattern string) int {
+	n := 0
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == 'x' || pattern[i] == 'X' {
+			n++
+		}
+	}
+	return n
+}
+
+// GetResponseTemplate returns the most specific template matching the
+// request MTI, or DefaultResponseTemplate if none does.
+func (cp *CompiledPackager) GetResponseTemplate(mti MTI) ResponseTemplate {
+	if cp != nil {
+		for _, entry := range cp.responseTemplates {
+			if mti.Matches(entry.pattern) {
+				return entry.template
+			}
+		}
+	}
+	return DefaultResponseTemplate
+}
+
+// CreateResponseWith generates a response like CreateResponse and then sets
+// the given fields (e.g., DE 38 approval code). It returns a FieldError
+// wrapping ErrResponseFieldMissing if a field required by the template is
+// still absent.
+func (m *Message) CreateResponseWith(responseCode string, fields map[int]interface{}) (*Message, error) {
+	resMTI, err := m.GetMTI().Response()
+	if err != nil {
+		return nil, err
+	}
+
+	resMsg := m.Clone()
+	resMsg.mti = resMTI
+	template := resMsg.packager.GetResponseTemplate(MTI(m.mti))
+
+	// Keep only echoed fields that are not dropped
+	echo := make(map[int]bool, len(template.Echo))
+	for _, fieldNum := range template.Echo {
+		echo[fieldNum] = true
+	}
+	drop := make(map[int]bool, len(template.Drop))
+	for _, fieldNum := range template.Drop {
+		drop[fieldNum] = true
+	}
+	for _, fieldNum := range resMsg.GetPresentFields() {
+		if drop[fieldNum] || (len(echo) > 0 && !echo[fieldNum]) {
+			resMsg.ClearField(fieldNum)
+		}
+	}
+
+	// Swap source/destination in structured headers
+	if codec := resMsg.headerCodec(); codec != nil && len(resMsg.header) > 0 {
+		resMsg.header = codec.Reverse(resMsg.header)
+	}
+
+	// Set Response Code and caller-supplied fields
+	if responseCode != "" {
+		if err := resMsg.SetField(39, responseCode); err != nil {
+			resMsg.Release()
+			return nil, err
+		}
+	}
+	for fieldNum, value := range fields {
+		if err := resMsg.SetField(fieldNum, value); err != nil {
+			resMsg.Release()
+			return nil, err
+		}
+	}
+
+	for fieldNum, value := range template.Defaults {
+		if resMsg.HasField(fieldNum) {
+			continue
+		}
+		if err := resMsg.SetField(fieldNum, value); err != nil {
+			resMsg.Release()
+			return nil, err
+		}
+	}
+
+	for _, fieldNum := range template.Required {
+		if !resMsg.HasField(fieldNum) {
+			resMsg.Release()
+			return nil, &FieldError{Field: fieldNum, Err: ErrResponseFieldMissing}
+		}
+	}
+
+	return resMsg, nil
+}
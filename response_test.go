@@ -0,0 +1,113 @@
+package iso8583
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func newResponseRequest(t *testing.T, pkg *CompiledPackager, mti string, fields map[int]string) *Message {
+	t.Helper()
+	m := NewMessage(WithPackager(pkg))
+	if err := m.SetMTI([]byte(mti)); err != nil {
+		t.Fatal(err)
+	}
+	for fieldNum, value := range fields {
+		if err := m.SetField(fieldNum, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestCreateResponseDefaultTemplate(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig())
+	req := newResponseRequest(t, pkg, "0200", map[int]string{
+		3: "000000", 11: "000123", 35: "4111111111111111=2512101", 41: "TERM0001",
+	})
+
+	res, err := req.CreateResponse(RC_APPROVED)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.GetMTI().String(); got != "0210" {
+		t.Errorf("MTI = %s, want 0210", got)
+	}
+	if got, want := res.GetPresentFields(), []int{3, 11, 39, 41}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+	if got, _ := res.GetString(39); got != RC_APPROVED {
+		t.Errorf("DE 39 = %q", got)
+	}
+}
+
+func TestCreateResponseTemplates(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig(
+		WithResponseTemplate("01x0", ResponseTemplate{Echo: []int{11, 41}, Required: []int{38, 39}}),
+		WithResponseTemplate("0100", ResponseTemplate{Echo: []int{11}, Defaults: map[int]string{38: "000000"}, Required: []int{38, 39}}),
+		WithResponseTemplate("08xx", ResponseTemplate{Drop: []int{70}}),
+	))
+	fields := map[int]string{3: "000000", 11: "000123", 41: "TERM0001", 70: "301"}
+
+	tests := []struct {
+		name       string
+		mti        string
+		extra      map[int]interface{}
+		wantMTI    string
+		wantFields []int
+		wantErr    bool
+	}{
+		{"most specific pattern", "0100", nil, "0110", []int{11, 38, 39}, false},
+		{"wildcard pattern", "0120", map[int]interface{}{38: "A1B2C3"}, "0130", []int{11, 38, 39, 41}, false},
+		{"required field missing", "0120", nil, "", nil, true},
+		{"drop list", "0800", nil, "0810", []int{3, 11, 39, 41}, false},
+		{"repeat origin", "0101", nil, "0110", []int{3, 11, 39, 41, 70}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newResponseRequest(t, pkg, tt.mti, fields)
+			res, err := req.CreateResponseWith(RC_APPROVED, tt.extra)
+			if tt.wantErr {
+				var fe *FieldError
+				if !errors.As(err, &fe) || !errors.Is(err, ErrResponseFieldMissing) || fe.Field != 38 {
+					t.Fatalf("CreateResponseWith = %v, want DE 38 ErrResponseFieldMissing", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.GetMTI().String(); got != tt.wantMTI {
+				t.Errorf("MTI = %s, want %s", got, tt.wantMTI)
+			}
+			if got := res.GetPresentFields(); !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("fields = %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+
+	if _, err := newResponseRequest(t, pkg, "0110", nil).CreateResponse(RC_APPROVED); !errors.Is(err, ErrNoResponseMTI) {
+		t.Errorf("CreateResponse of a response = %v, want ErrNoResponseMTI", err)
+	}
+}
+
+func TestResponseTemplateConfig(t *testing.T) {
+	tests := []struct {
+		data string
+		err  error
+	}{
+		{`{"response_templates": {"010": {"required": [39]}}}`, ErrInvalidMTI},
+		{`{"response_templates": {"01y0": {"required": [39]}}}`, ErrInvalidMTI},
+		{`{"response_templates": {"0100": {"echo": [0]}}}`, ErrInvalidField},
+		{`{"response_templates": {"0100": {"defaults": {"193": "00"}}}}`, ErrInvalidField},
+		{`{"response_templates": {"01x0": {"echo": [11], "required": [39]}}}`, nil},
+	}
+
+	for _, tt := range tests {
+		_, err := LoadPackagerFromByte([]byte(tt.data))
+		if (tt.err == nil && err != nil) || (tt.err != nil && !errors.Is(err, tt.err)) {
+			t.Errorf("LoadPackagerFromByte(%s) = %v, want %v", tt.data, err, tt.err)
+		}
+	}
+}
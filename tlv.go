@@ -1,8 +1,10 @@
 package iso8583
 
 import (
+	"encoding/hex"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 )
 
@@ -45,9 +47,10 @@ func (t TLV) BCD() int64 {
 // A TLVParser instance is stateful (it contains a buffer) but is safe
 // for concurrent use due to its internal mutex.
 type TLVParser struct {
-	tlvType TLVType
-	buffer  []TLV // Internal buffer to reduce allocations during parsing
-	mu      sync.Mutex
+	tlvType  TLVType
+	buffer   []TLV // Internal buffer to reduce allocations during parsing
+	maxDepth int   // Nesting levels parsed for constructed EMV tags (<= 1 means flat)
+	mu       sync.Mutex
 
 	asciiTagLen     int // e.g., 2 for "AL"
 	asciiLenLen     int // e.g., 2 for "04"
@@ -76,6 +79,15 @@ func NewASCIITLVParser(tagLen, lenLen, base int) *TLVParser {
 	}
 }
 
+// SetMaxDepth sets how many levels of constructed EMV tags are parsed into
+// TLV.Children. The top level counts as depth 1, so a depth of 1 or less
+// keeps the result flat. Constructed tags below the limit are left as raw values.
+func (tp *TLVParser) SetMaxDepth(depth int) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.maxDepth = depth
+}
+
 // reset clears the parser's internal buffer for reuse.
 func (tp *TLVParser) reset() {
 	tp.buffer = tp.buffer[:0] // Reset buffer slice length
@@ -204,92 +216,150 @@ func (tp *TLVParser) parseStandardTLV(data []byte) ([]TLV, error) {
 }
 
 // parseEMVTLV parses EMV TLV format with variable tag and length encoding.
+// Constructed tags are parsed recursively into TLV.Children up to tp.maxDepth.
 func (tp *TLVParser) parseEMVTLV(data []byte) ([]TLV, error) {
 	offset := 0
 	for offset < len(data) {
-		// Parse Tag (variable length)
-		tagStart := offset
-		if offset >= len(data) {
-			break
+		tlv, next, err := readEMVTLV(data, offset)
+		if err != nil {
+			return nil, err
 		}
+		offset = next
 
-		// First byte of tag
-		firstByte := data[offset]
-		offset++
-
-		// Check if tag continues: bits 5-1 of first byte are 11111
-		if (firstByte & 0x1F) == 0x1F {
-			// --- FIX IS HERE ---
-			// Keep reading as long as the MSB of the *current* byte is 1
-			// This means more tag bytes follow.
-			for offset < len(data) && (data[offset]&0x80) != 0 {
-				offset++ // Consume this byte (e.g., 9F -> 80)
-			}
-			// Now we are at the last byte (MSB is 0). Consume it.
-			if offset >= len(data) {
-				return nil, ErrInvalidTLV // Truncated tag
+		if isConstructedTag(tlv.Tag) && tp.maxDepth > 1 {
+			children, err := tp.parseEMVChildren(tlv.Value, 2)
+			if err != nil {
+				return nil, &TLVError{Tag: tlv.Tag, Err: err}
 			}
-			offset++ // Consume the final byte of the tag (e.g., 33 in 9F33)
-			// --- END FIX ---
+			tlv.Children = children
 		}
 
-		if offset > len(data) {
-			return nil, ErrInvalidTLV
+		tp.buffer = append(tp.buffer, tlv)
+	}
+
+	// Return copy of buffer
+	result := make([]TLV, len(tp.buffer))
+	copy(result, tp.buffer)
+	return result, nil
+}
+
+// parseEMVChildren parses the value of a constructed tag at the given depth.
+func (tp *TLVParser) parseEMVChildren(data []byte, depth int) ([]TLV, error) {
+	children := make([]TLV, 0, 4)
+	offset := 0
+	for offset < len(data) {
+		tlv, next, err := readEMVTLV(data, offset)
+		if err != nil {
+			return nil, err
 		}
-		tag := data[tagStart:offset]
+		offset = next
 
-		// Parse Length (variable length)
-		if offset >= len(data) {
-			return nil, ErrInvalidTLV
+		if isConstructedTag(tlv.Tag) && depth < tp.maxDepth {
+			grandchildren, err := tp.parseEMVChildren(tlv.Value, depth+1)
+			if err != nil {
+				return nil, &TLVError{Tag: tlv.Tag, Err: err}
+			}
+			tlv.Children = grandchildren
 		}
 
-		lengthByte := data[offset]
-		offset++
-		var length int
+		children = append(children, tlv)
+	}
+	return children, nil
+}
 
-		if (lengthByte & 0x80) == 0 {
-			// Short form - length is in this single byte (0-127)
-			length = int(lengthByte)
-		} else {
-			// Long form - first byte's lower 7 bits indicate
-			// number of subsequent length bytes.
-			numLengthBytes := int(lengthByte & 0x7F)
-			if numLengthBytes == 0 || numLengthBytes > 4 {
-				return nil, ErrInvalidTLV // Invalid number of length bytes
-			}
+// parseChildren parses the value of a top-level constructed EMV tag into its
+// children, as ParseTLV would. It returns nil for primitive tags, non-EMV
+// parsers and values that are not valid TLV data.
+func (tp *TLVParser) parseChildren(tag, value []byte) []TLV {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
 
-			if offset+numLengthBytes > len(data) {
-				return nil, ErrInvalidTLV // Truncated length
-			}
+	if tp.tlvType != TLVEMV || !isConstructedTag(tag) || tp.maxDepth <= 1 {
+		return nil
+	}
+	children, err := tp.parseEMVChildren(value, 2)
+	if err != nil {
+		return nil
+	}
+	return children
+}
 
-			// Read the N-byte length
-			length = 0
-			for i := 0; i < numLengthBytes; i++ {
-				length = (length << 8) | int(data[offset])
-				offset++
-			}
+// readEMVTLV reads a single EMV TLV entry starting at offset.
+// Returns the entry and the offset just past its value.
+func readEMVTLV(data []byte, offset int) (TLV, int, error) {
+	// Parse Tag (variable length)
+	tagStart := offset
+
+	// First byte of tag
+	firstByte := data[offset]
+	offset++
+
+	// Check if tag continues: bits 5-1 of first byte are 11111
+	if (firstByte & 0x1F) == 0x1F {
+		// Keep reading as long as the MSB of the *current* byte is 1
+		// This means more tag bytes follow.
+		for offset < len(data) && (data[offset]&0x80) != 0 {
+			offset++ // Consume this byte (e.g., 9F -> 80)
+		}
+		// Now we are at the last byte (MSB is 0). Consume it.
+		if offset >= len(data) {
+			return TLV{}, offset, ErrInvalidTLV // Truncated tag
 		}
+		offset++ // Consume the final byte of the tag (e.g., 33 in 9F33)
+	}
 
-		// Parse Value
-		if offset+length > len(data) {
-			return nil, ErrInvalidTLV // Truncated value
+	tag := data[tagStart:offset]
+
+	// Parse Length (variable length)
+	if offset >= len(data) {
+		return TLV{}, offset, ErrInvalidTLV
+	}
+
+	lengthByte := data[offset]
+	offset++
+	var length int
+
+	if (lengthByte & 0x80) == 0 {
+		// Short form - length is in this single byte (0-127)
+		length = int(lengthByte)
+	} else {
+		// Long form - first byte's lower 7 bits indicate
+		// number of subsequent length bytes.
+		numLengthBytes := int(lengthByte & 0x7F)
+		if numLengthBytes == 0 || numLengthBytes > 4 {
+			return TLV{}, offset, ErrInvalidTLV // Invalid number of length bytes
 		}
-		value := data[offset : offset+length]
-		offset += length
 
-		tlv := TLV{
-			Tag:    tag,
-			Length: length,
-			Value:  value,
+		if offset+numLengthBytes > len(data) {
+			return TLV{}, offset, ErrInvalidTLV // Truncated length
 		}
 
-		tp.buffer = append(tp.buffer, tlv)
+		// Read the N-byte length
+		length = 0
+		for i := 0; i < numLengthBytes; i++ {
+			length = (length << 8) | int(data[offset])
+			offset++
+		}
 	}
 
-	// Return copy of buffer
-	result := make([]TLV, len(tp.buffer))
-	copy(result, tp.buffer)
-	return result, nil
+	// Parse Value
+	if length < 0 || offset+length > len(data) {
+		return TLV{}, offset, ErrInvalidTLV // Truncated value
+	}
+	value := data[offset : offset+length]
+	offset += length
+
+	return TLV{
+		Tag:    tag,
+		Length: length,
+		Value:  value,
+	}, offset, nil
+}
+
+// isConstructedTag reports whether an EMV tag is constructed
+// (bit 6 of the first tag byte set), i.e. its value is itself TLV-encoded.
+func isConstructedTag(tag []byte) bool {
+	return len(tag) > 0 && tag[0]&0x20 != 0
 }
 
 // PackTLV packs a slice of TLV structs into a byte buffer.
@@ -403,6 +473,17 @@ func (tp *TLVParser) packEMVTLV(tlvs []TLV, buf []byte) (int, error) {
 	offset := 0
 
 	for _, tlv := range tlvs {
+		// Constructed tags with children are re-encoded from the children
+		value := tlv.Value
+		if len(tlv.Children) > 0 {
+			childBuf := make([]byte, tlvListEncodedSize(tlv.Children))
+			n, err := tp.packEMVTLV(tlv.Children, childBuf)
+			if err != nil {
+				return 0, &TLVError{Tag: tlv.Tag, Err: err}
+			}
+			value = childBuf[:n]
+		}
+
 		// Pack Tag
 		if offset+len(tlv.Tag) > len(buf) {
 			return 0, ErrBufferTooSmall
@@ -411,7 +492,7 @@ func (tp *TLVParser) packEMVTLV(tlvs []TLV, buf []byte) (int, error) {
 		offset += len(tlv.Tag)
 
 		// Pack Length
-		valueLen := len(tlv.Value)
+		valueLen := len(value)
 		if valueLen < 0x80 {
 			// Short form (0-127)
 			if offset+1 > len(buf) {
@@ -443,11 +524,11 @@ func (tp *TLVParser) packEMVTLV(tlvs []TLV, buf []byte) (int, error) {
 		}
 
 		// Pack Value
-		if offset+len(tlv.Value) > len(buf) {
+		if offset+len(value) > len(buf) {
 			return 0, ErrBufferTooSmall
 		}
-		copy(buf[offset:], tlv.Value)
-		offset += len(tlv.Value)
+		copy(buf[offset:], value)
+		offset += len(value)
 	}
 
 	return offset, nil
@@ -473,6 +554,25 @@ func FindTLV(tlvs []TLV, tag []byte) (*TLV, bool) {
 	return nil, false
 }
 
+// FindTLVPath finds a nested TLV entry by a slash-separated path of hex tags,
+// e.g. "72/9F18" for the issuer script identifier inside an issuer script template.
+func FindTLVPath(tlvs []TLV, path string) (*TLV, bool) {
+	var current *TLV
+	for _, segment := range strings.Split(path, "/") {
+		tag, err := hex.DecodeString(segment)
+		if err != nil || len(tag) == 0 {
+			return nil, false
+		}
+		tlv, found := FindTLV(tlvs, tag)
+		if !found {
+			return nil, false
+		}
+		current = tlv
+		tlvs = tlv.Children
+	}
+	return current, current != nil
+}
+
 // FilterTLVsByTag finds all TLV entries matching the given tag prefix.
 func FilterTLVsByTag(tlvs []TLV, tagPrefix []byte) []TLV {
 	var result []TLV
@@ -594,3 +694,218 @@ func TLVToMapString(tlvs []TLV, tlvType TLVType) map[string]string {
 
 	return result
 }
+
+// newFieldTLVParser creates a parser from a field's TLV configuration.
+// maxDepth bounds nested parsing of constructed EMV tags.
+func newFieldTLVParser(config *FieldTLVConfig, maxDepth int) *TLVParser {
+	if config.Type == TLVASCII {
+		base := config.LengthBase
+		if base == 0 {
+			base = 10
+		}
+		return NewASCIITLVParser(config.TagLength, config.LengthLength, base)
+	}
+	parser := NewTLVParser(config.Type)
+	parser.maxDepth = maxDepth
+	return parser
+}
+
+// encodeTLVs packs tlvs into a newly allocated byte slice.
+func encodeTLVs(parser *TLVParser, tlvs []TLV) ([]byte, error) {
+	buf := make([]byte, tlvListEncodedSize(tlvs))
+	n, err := parser.PackTLV(tlvs, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// tlvEncodedSize returns an upper bound for the encoded size of a TLV entry.
+func tlvEncodedSize(tlv TLV) int {
+	// Tag + up to 5 EMV length bytes (or ASCII length digits) + value
+	if len(tlv.Children) > 0 {
+		return len(tlv.Tag) + 8 + tlvListEncodedSize(tlv.Children)
+	}
+	return len(tlv.Tag) + 8 + len(tlv.Value)
+}
+
+// tlvListEncodedSize returns an upper bound for the encoded size of tlvs.
+func tlvListEncodedSize(tlvs []TLV) int {
+	size := 0
+	for _, tlv := range tlvs {
+		size += tlvEncodedSize(tlv)
+	}
+	return size
+}
+
+// --- Message TLV accessors ---
+
+// decodeTLVFields parses every present field marked as TLV into m.tlvData.
+// It's called by Unpack.
+func (m *Message) decodeTLVFields() error {
+	if m.packager == nil {
+		return nil
+	}
+	for fieldNum, parser := range m.packager.tlvParsers {
+		if !m.isFieldPresent(fieldNum) {
+			continue
+		}
+		tlvs, err := parser.ParseTLV(m.fields[fieldNum-1].Bytes())
+		if err != nil {
+			if m.validationLevel == ValidationNone {
+				continue
+			}
+			if m.validationMode == ValidationCollectAll {
+				m.report.add(fieldNum, err)
+				continue
+			}
+			m.lastError.Field = fieldNum
+			m.lastError.Err = err
+			return &m.lastError
+		}
+		if m.tlvData == nil {
+			m.tlvData = make(map[int][]TLV)
+		}
+		m.tlvData[fieldNum] = tlvs
+	}
+	return nil
+}
+
+// GetTLVs returns a copy of the decoded TLV entries of a field marked as
+// TLV. Later SetTag and DeleteTag calls do not change the returned slice.
+func (m *Message) GetTLVs(fieldNum int) ([]TLV, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tlvs, exists := m.tlvData[fieldNum]
+	if !exists {
+		return nil, ErrFieldNotFound
+	}
+	return append([]TLV(nil), tlvs...), nil
+}
+
+// GetTagPath returns a nested TLV entry in a TLV field by a slash-separated
+// path of hex tags (see FindTLVPath).
+func (m *Message) GetTagPath(fieldNum int, path string) (TLV, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tlvs, exists := m.tlvData[fieldNum]
+	if !exists {
+		return TLV{}, ErrFieldNotFound
+	}
+	tlv, found := FindTLVPath(tlvs, path)
+	if !found {
+		return TLV{}, fmt.Errorf("%w: %s", ErrTagNotFound, path)
+	}
+	return *tlv, nil
+}
+
+// GetTag returns the first TLV entry with the given tag in a TLV field.
+func (m *Message) GetTag(fieldNum int, tag []byte) (TLV, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tlvs, exists := m.tlvData[fieldNum]
+	if !exists {
+		return TLV{}, ErrFieldNotFound
+	}
+	tlv, found := FindTLV(tlvs, tag)
+	if !found {
+		return TLV{}, &TLVError{Tag: tag, Err: ErrTagNotFound}
+	}
+	return *tlv, nil
+}
+
+// SetTag sets the value of a tag in a TLV field, replacing the first entry
+// with the same tag or appending a new one. The field is marked present and
+// its raw value is re-encoded from the TLV entries on the next Pack.
+// The tag and value are copied, so the caller may reuse them. The children
+// of a constructed EMV tag are re-parsed from the new value; if it is not
+// valid TLV data the tag is kept as a raw value.
+func (m *Message) SetTag(fieldNum int, tag, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.packager == nil {
+		return ErrNoPackagerConfigured
+	}
+	parser, exists := m.packager.tlvParsers[fieldNum]
+	if !exists {
+		return &FieldError{Field: fieldNum, Err: ErrFieldNotTLV}
+	}
+
+	if m.tlvData == nil {
+		m.tlvData = make(map[int][]TLV)
+	}
+	tag = append([]byte(nil), tag...)
+	value = append([]byte(nil), value...)
+	children := parser.parseChildren(tag, value)
+	tlvs := m.tlvData[fieldNum]
+	if tlv, found := FindTLV(tlvs, tag); found {
+		tlv.Value = value
+		tlv.Length = len(value)
+		tlv.Children = children
+	} else {
+		tlvs = append(tlvs, TLV{Tag: tag, Length: len(value), Value: value, Children: children})
+	}
+	m.tlvData[fieldNum] = tlvs
+	m.markTLVModified(fieldNum)
+	return nil
+}
+
+// DeleteTag removes every entry with the given tag from a TLV field.
+func (m *Message) DeleteTag(fieldNum int, tag []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tlvs, exists := m.tlvData[fieldNum]
+	if !exists {
+		return ErrFieldNotFound
+	}
+	kept := make([]TLV, 0, len(tlvs))
+	for _, tlv := range tlvs {
+		if string(tlv.Tag) != string(tag) {
+			kept = append(kept, tlv)
+		}
+	}
+	m.tlvData[fieldNum] = kept
+	m.markTLVModified(fieldNum)
+	return nil
+}
+
+// markTLVModified flags a TLV field for re-encoding and marks it present.
+func (m *Message) markTLVModified(fieldNum int) {
+	if m.tlvModified == nil {
+		m.tlvModified = make(map[int]bool)
+	}
+	m.tlvModified[fieldNum] = true
+
+	field := &m.fields[fieldNum-1]
+	if !field.parsed {
+		field.fieldType = FieldTypeB
+		field.parsed = true
+	}
+	m.setFieldPresent(fieldNum)
+	m.bitmap.SetField(fieldNum)
+}
+
+// dropTLV discards decoded and modified TLV entries for a field.
+func (m *Message) dropTLV(fieldNum int) {
+	delete(m.tlvData, fieldNum)
+	delete(m.tlvModified, fieldNum)
+}
+
+// encodedTLVField returns the re-encoded raw value of a modified TLV field.
+// ok is false if the field has not been modified through SetTag/DeleteTag.
+func (m *Message) encodedTLVField(packager *CompiledPackager, fieldNum int) ([]byte, bool, error) {
+	if !m.tlvModified[fieldNum] {
+		return nil, false, nil
+	}
+	parser, exists := packager.tlvParsers[fieldNum]
+	if !exists {
+		return nil, false, &FieldError{Field: fieldNum, Err: ErrFieldNotTLV}
+	}
+	data, err := encodeTLVs(parser, m.tlvData[fieldNum])
+	return data, true, err
+}
@@ -0,0 +1,268 @@
+package iso8583
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func newTLVPackager(maxDepth int) *CompiledPackager {
+	return NewCompiledPackager(newTestConfig(
+		WithTLVConfig(TLVConfig{Type: TLVEMV, Enabled: true, MaxDepth: maxDepth}),
+		WithFieldConfig(48, FieldConfig{Type: FieldTypeANS, Length: LengthLLLVAR, MaxLength: 999,
+			TLV: &FieldTLVConfig{Type: TLVASCII, TagLength: 2, LengthLength: 2}}),
+		WithFieldConfig(55, FieldConfig{Type: FieldTypeB, Length: LengthLLLVAR, MaxLength: 999,
+			TLV: &FieldTLVConfig{Type: TLVEMV}}),
+	))
+}
+
+func TestTLVParserRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser *TLVParser
+		data   []byte
+		tags   []string
+	}{
+		{"standard", NewTLVParser(TLVStandard), []byte{0x01, 0x02, 'h', 'i', 0x02, 0x00}, []string{"\x01", "\x02"}},
+		{"EMV", NewTLVParser(TLVEMV), mustHex(t, "9F02060000000012345F2A020840"), []string{"\x9f\x02", "\x5f\x2a"}},
+		{"EMV long length", NewTLVParser(TLVEMV), append(mustHex(t, "DF018180"), make([]byte, 128)...), []string{"\xdf\x01"}},
+		{"ASCII", NewASCIITLVParser(2, 2, 10), []byte("AL04TEST01021Z"), []string{"AL", "01"}},
+		{"ASCII hex lengths", NewASCIITLVParser(2, 2, 16), []byte("AL0C123456789012"), []string{"AL"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlvs, err := tt.parser.ParseTLV(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tags []string
+			for _, tlv := range tlvs {
+				tags = append(tags, string(tlv.Tag))
+			}
+			if !reflect.DeepEqual(tags, tt.tags) {
+				t.Errorf("tags = %q, want %q", tags, tt.tags)
+			}
+
+			buf := make([]byte, len(tt.data)+16)
+			n, err := tt.parser.PackTLV(tlvs, buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf[:n], tt.data) {
+				t.Errorf("PackTLV = %X, want %X", buf[:n], tt.data)
+			}
+		})
+	}
+}
+
+func TestTLVParserErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser *TLVParser
+		data   []byte
+	}{
+		{"standard truncated value", NewTLVParser(TLVStandard), []byte{0x01, 0x05, 'h'}},
+		{"standard missing length", NewTLVParser(TLVStandard), []byte{0x01}},
+		{"EMV truncated value", NewTLVParser(TLVEMV), mustHex(t, "9F020600000000")},
+		{"EMV truncated tag", NewTLVParser(TLVEMV), mustHex(t, "9F")},
+		{"ASCII truncated value", NewASCIITLVParser(2, 2, 10), []byte("AL10TEST")},
+		{"ASCII bad length", NewASCIITLVParser(2, 2, 10), []byte("ALxxTEST")},
+	}
+
+	for _, tt := range tests {
+		if tlvs, err := tt.parser.ParseTLV(tt.data); err == nil {
+			t.Errorf("%s: ParseTLV = %v, want an error", tt.name, tlvs)
+		}
+	}
+}
+
+func TestMessageTLVUnpackAndPack(t *testing.T) {
+	pkg := newTLVPackager(1)
+	m := NewMessage(WithPackager(pkg))
+	if err := m.SetMTI([]byte("0200")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetField(55, mustHex(t, "9F02060000000012349F360200015F2A020840")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetField(48, "AL04TEST"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := repack(t, m)
+	tlv, err := got.GetTag(55, []byte{0x9f, 0x02})
+	if err != nil || tlv.BCD() != 1234 {
+		t.Errorf("GetTag(9F02) = %v, %v", tlv, err)
+	}
+	if tlv, err := got.GetTag(48, []byte("AL")); err != nil || tlv.String() != "TEST" {
+		t.Errorf("GetTag(AL) = %v, %v", tlv, err)
+	}
+	if _, err := got.GetTag(55, []byte{0x9f, 0x03}); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("GetTag(9F03) = %v, want ErrTagNotFound", err)
+	}
+	if _, err := got.GetTLVs(4); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("GetTLVs(4) = %v, want ErrFieldNotFound", err)
+	}
+
+	// Modified tags are re-encoded on Pack
+	if err := got.SetTag(55, []byte{0x9f, 0x02}, mustHex(t, "000000009999")); err != nil {
+		t.Fatal(err)
+	}
+	if err := got.DeleteTag(55, []byte{0x9f, 0x36}); err != nil {
+		t.Fatal(err)
+	}
+	if err := got.SetTag(55, []byte{0x95}, mustHex(t, "0000008000")); err != nil {
+		t.Fatal(err)
+	}
+	again := repack(t, got)
+	raw, _ := again.GetBytes(55)
+	if want := mustHex(t, "9F02060000000099995F2A02084095050000008000"); !bytes.Equal(raw, want) {
+		t.Errorf("DE 55 = %X, want %X", raw, want)
+	}
+
+	// SetField replaces the decoded entries
+	if err := again.SetField(55, mustHex(t, "5A0841111111111111")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := again.GetTLVs(55); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("GetTLVs after SetField = %v, want ErrFieldNotFound", err)
+	}
+}
+
+func TestSetTagErrors(t *testing.T) {
+	m := NewMessage(WithPackager(newTLVPackager(1)))
+	if err := m.SetTag(4, []byte{0x9f, 0x02}, nil); !errors.Is(err, ErrFieldNotTLV) {
+		t.Errorf("SetTag on a non-TLV field = %v, want ErrFieldNotTLV", err)
+	}
+	if err := m.DeleteTag(55, []byte{0x9f, 0x02}); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("DeleteTag on an absent field = %v, want ErrFieldNotFound", err)
+	}
+	if err := NewMessage().SetTag(55, []byte{0x9f, 0x02}, nil); !errors.Is(err, ErrNoPackagerConfigured) {
+		t.Errorf("SetTag without a packager = %v, want ErrNoPackagerConfigured", err)
+	}
+}
+
+func TestSetTagCopies(t *testing.T) {
+	m := NewMessage(WithPackager(newTLVPackager(1)))
+	tag, value := []byte{0x9f, 0x02}, mustHex(t, "000000001234")
+	if err := m.SetTag(55, tag, value); err != nil {
+		t.Fatal(err)
+	}
+	tag[0], value[0] = 0, 0xff
+
+	tlv, err := m.GetTag(55, []byte{0x9f, 0x02})
+	if err != nil || !bytes.Equal(tlv.Value, mustHex(t, "000000001234")) {
+		t.Errorf("GetTag = %X, %v after the caller reused its buffers", tlv.Value, err)
+	}
+}
+
+func TestGetTLVsReturnsCopy(t *testing.T) {
+	m := NewMessage(WithPackager(newTLVPackager(1)))
+	for _, tag := range [][]byte{{0x9f, 0x02}, {0x9f, 0x36}, {0x95}} {
+		if err := m.SetTag(55, tag, []byte{0x01}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before, err := m.GetTLVs(55)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DeleteTag(55, []byte{0x9f, 0x02}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetTag(55, []byte{0x9f, 0x36}, []byte{0x02}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(before) != 3 || !bytes.Equal(before[0].Tag, []byte{0x9f, 0x02}) || before[1].Value[0] != 0x01 {
+		t.Errorf("earlier GetTLVs result changed: %v", before)
+	}
+	after, _ := m.GetTLVs(55)
+	if len(after) != 2 || !bytes.Equal(after[0].Tag, []byte{0x9f, 0x36}) || after[0].Value[0] != 0x02 {
+		t.Errorf("GetTLVs = %v", after)
+	}
+}
+
+func TestConstructedTLVDepth(t *testing.T) {
+	data := mustHex(t, "700E720C9F180400000001860301020395050000008000")
+	tests := []struct {
+		maxDepth int
+		path     string
+		found    bool
+	}{
+		{1, "70", true},
+		{1, "70/72", false},
+		{2, "70/72", true},
+		{2, "70/72/9F18", false},
+		{3, "70/72/9F18", true},
+		{3, "70/72/86", true},
+		{3, "95", true},
+	}
+
+	for _, tt := range tests {
+		parser := NewTLVParser(TLVEMV)
+		parser.SetMaxDepth(tt.maxDepth)
+		tlvs, err := parser.ParseTLV(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, found := FindTLVPath(tlvs, tt.path); found != tt.found {
+			t.Errorf("depth %d: FindTLVPath(%s) found = %v, want %v", tt.maxDepth, tt.path, found, tt.found)
+		}
+
+		// Children are re-encoded from the raw value, so packing is lossless at every depth
+		buf := make([]byte, len(data))
+		if n, err := parser.PackTLV(tlvs, buf); err != nil || !bytes.Equal(buf[:n], data) {
+			t.Errorf("depth %d: PackTLV = %X, %v", tt.maxDepth, buf[:n], err)
+		}
+	}
+}
+
+func TestConstructedTLVErrors(t *testing.T) {
+	parser := NewTLVParser(TLVEMV)
+	parser.SetMaxDepth(2)
+	// 72 claims a 9F18 child of 8 bytes but holds only 4
+	var tlvErr *TLVError
+	if _, err := parser.ParseTLV(mustHex(t, "72079F180800000001")); !errors.As(err, &tlvErr) || !bytes.Equal(tlvErr.Tag, []byte{0x72}) {
+		t.Errorf("ParseTLV = %v, want a TLVError for tag 72", err)
+	}
+
+	// At depth 1 the value is not parsed, so it is accepted
+	parser.SetMaxDepth(1)
+	if _, err := parser.ParseTLV(mustHex(t, "72079F180800000001")); err != nil {
+		t.Errorf("flat ParseTLV = %v", err)
+	}
+}
+
+func TestMessageConstructedTLV(t *testing.T) {
+	m := NewMessage(WithPackager(newTLVPackager(3)))
+	m.SetMTI([]byte("0110"))
+	m.SetField(55, mustHex(t, "910A0102030405060708090A720C9F1804000000018603010203"))
+
+	got := repack(t, m)
+	tlv, err := got.GetTagPath(55, "72/9F18")
+	if err != nil || !bytes.Equal(tlv.Value, []byte{0, 0, 0, 1}) {
+		t.Errorf("GetTagPath(72/9F18) = %X, %v", tlv.Value, err)
+	}
+	if _, err := got.GetTagPath(55, "72/9F19"); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("GetTagPath(72/9F19) = %v, want ErrTagNotFound", err)
+	}
+
+	// SetTag re-parses the children of a constructed tag
+	if err := got.SetTag(55, []byte{0x72}, mustHex(t, "9F180400000002")); err != nil {
+		t.Fatal(err)
+	}
+	if tlv, err := got.GetTagPath(55, "72/9F18"); err != nil || tlv.Value[3] != 2 {
+		t.Errorf("GetTagPath after SetTag = %X, %v", tlv.Value, err)
+	}
+	// A value that is not TLV data is kept as is, without children
+	if err := got.SetTag(55, []byte{0x72}, []byte{0xff}); err != nil {
+		t.Fatal(err)
+	}
+	if tlv, _ := got.GetTag(55, []byte{0x72}); tlv.Children != nil || !bytes.Equal(tlv.Value, []byte{0xff}) {
+		t.Errorf("GetTag(72) = %+v", tlv)
+	}
+}
@@ -0,0 +1,232 @@
+package iso8583
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mkadit/iso8583/pan"
+)
+
+// Track2 is decoded track 2 data (DE 35):
+// [;]PAN=YYMM SSS discretionary[?[LRC]]. The separator may also be 'D',
+// as in packed (BCD) track 2 data.
+type Track2 struct {
+	PAN               string
+	Expiry            string // YYMM, empty if absent
+	ServiceCode       string // Empty if absent
+	DiscretionaryData string
+}
+
+// Track1 is decoded track 1 data (DE 45):
+// [%]B PAN ^NAME^ YYMM SSS discretionary[?[LRC]].
+type Track1 struct {
+	FormatCode        byte // 'B' for bank cards
+	PAN               string
+	Name              string
+	Expiry            string // YYMM, empty if absent
+	ServiceCode       string // Empty if absent
+	DiscretionaryData string
+}
+
+// ParseTrack2 decodes track 2 data. Start/end sentinels are optional;
+// when an LRC follows the end sentinel it is verified.
+func ParseTrack2(data string) (*Track2, error) {
+	body, err := stripSentinels(data, ';', 0x30, 0x0F)
+	if err != nil {
+		return nil, err
+	}
+
+	sep := strings.IndexAny(body, "=D")
+	if sep < 0 {
+		return nil, fmt.Errorf("%w: missing field separator", ErrInvalidTrackData)
+	}
+	track := &Track2{PAN: body[:sep]}
+	if !pan.IsDigits(track.PAN) || len(track.PAN) > pan.MaxLength {
+		return nil, fmt.Errorf("%w: invalid PAN", ErrInvalidTrackData)
+	}
+
+	rest := body[sep+1:]
+	if track.Expiry, rest, err = trackSubfield(rest, 4, body[sep]); err != nil {
+		return nil, err
+	}
+	if track.ServiceCode, rest, err = trackSubfield(rest, 3, body[sep]); err != nil {
+		return nil, err
+	}
+	track.DiscretionaryData = rest
+	return track, nil
+}
+
+// ParseTrack1 decodes track 1 data. Start/end sentinels are optional;
+// when an LRC follows the end sentinel it is verified.
+func ParseTrack1(data string) (*Track1, error) {
+	body, err := stripSentinels(data, '%', 0x20, 0x3F)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 || body[0] < 'A' || body[0] > 'Z' {
+		return nil, fmt.Errorf("%w: missing format code", ErrInvalidTrackData)
+	}
+
+	parts := strings.SplitN(body[1:], "^", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: missing field separator", ErrInvalidTrackData)
+	}
+	track := &Track1{
+		FormatCode: body[0],
+		PAN:        strings.TrimRight(parts[0], " "),
+		Name:       strings.TrimRight(parts[1], " "),
+	}
+	if !pan.IsDigits(track.PAN) || len(track.PAN) > pan.MaxLength {
+		return nil, fmt.Errorf("%w: invalid PAN", ErrInvalidTrackData)
+	}
+	if len(parts[1]) < 2 || len(parts[1]) > 26 {
+		return nil, fmt.Errorf("%w: name must be 2-26 characters", ErrInvalidTrackData)
+	}
+
+	rest := parts[2]
+	if track.Expiry, rest, err = trackSubfield(rest, 4, '^'); err != nil {
+		return nil, err
+	}
+	if track.ServiceCode, rest, err = trackSubfield(rest, 3, '^'); err != nil {
+		return nil, err
+	}
+	track.DiscretionaryData = rest
+	return track, nil
+}
+
+// stripSentinels removes the start sentinel, end sentinel and LRC from a
+// track, verifying the LRC if present. For the LRC, characters are offset
+// and masked to their data bits (4 for track 2, 6 for track 1).
+func stripSentinels(data string, start byte, offset, mask byte) (string, error) {
+	body := data
+	if len(body) > 0 && body[0] == start {
+		body = body[1:]
+	}
+
+	end := strings.IndexByte(body, '?')
+	if end < 0 {
+		return body, nil
+	}
+	switch trailer := body[end+1:]; len(trailer) {
+	case 0:
+	case 1:
+		if len(data) == 0 || data[0] != start {
+			return "", fmt.Errorf("%w: LRC without start sentinel", ErrInvalidTrackData)
+		}
+		var lrc byte
+		for i := 0; i < len(data)-1; i++ {
+			lrc ^= (data[i] - offset) & mask
+		}
+		if lrc != (trailer[0]-offset)&mask {
+			return "", fmt.Errorf("%w: LRC mismatch", ErrInvalidTrackData)
+		}
+	default:
+		return "", fmt.Errorf("%w: data after end sentinel", ErrInvalidTrackData)
+	}
+	return body[:end], nil
+}
+
+// trackSubfield splits a fixed-width subfield off a track; a leading
+// separator marks the subfield as absent.
+func trackSubfield(rest string, width int, sep byte) (string, string, error) {
+	if len(rest) > 0 && rest[0] == sep {
+		return "", rest[1:], nil
+	}
+	if len(rest) < width || !pan.IsDigits(rest[:width]) {
+		return "", "", fmt.Errorf("%w: invalid %d-digit subfield", ErrInvalidTrackData, width)
+	}
+	return rest[:width], rest[width:], nil
+}
+
+// parseTrack decodes track 1 data (leading '%' or format code letter) or
+// track 2 data and returns its PAN and expiry.
+func parseTrack(data string) (number, expiry string, err error) {
+	if strings.HasPrefix(data, "%") || (len(data) > 0 && data[0] >= 'A' && data[0] <= 'Z') {
+		track, err := ParseTrack1(data)
+		if err != nil {
+			return "", "", err
+		}
+		return track.PAN, track.Expiry, nil
+	}
+	track, err := ParseTrack2(data)
+	if err != nil {
+		return "", "", err
+	}
+	return track.PAN, track.Expiry, nil
+}
+
+// GetTrack2 decodes DE 35.
+func (m *Message) GetTrack2() (*Track2, error) {
+	data, err := m.GetString(35)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTrack2(data)
+}
+
+// GetTrack1 decodes DE 45.
+func (m *Message) GetTrack1() (*Track1, error) {
+	data, err := m.GetString(45)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTrack1(data)
+}
+
+// TrackConsistencyRule checks that the PAN and expiry date in track data
+// match the PAN and expiry fields (usually DE 2 and DE 14) when those are
+// present. A mismatch is reported against the track field and the field it
+// disagrees with only. It is only checked when set in PackagerConfig.TrackConsistency.
+type TrackConsistencyRule struct {
+	TrackFields []int `json:"track_fields,omitempty"` // Track data fields; empty means every field with a track1/track2 format or a track rule
+	PANField    int   `json:"pan_field,omitempty"`    // Usually 2; 0 skips the PAN comparison
+	ExpiryField int   `json:"expiry_field,omitempty"` // Usually 14; 0 skips the expiry comparison
+}
+
+// Name returns the rule name.
+func (r *TrackConsistencyRule) Name() string {
+	return "track_consistency"
+}
+
+// Fields returns the track, PAN and expiry fields.
+func (r *TrackConsistencyRule) Fields() []int {
+	fields := append([]int(nil), r.TrackFields...)
+	if r.PANField > 0 {
+		fields = append(fields, r.PANField)
+	}
+	if r.ExpiryField > 0 {
+		fields = append(fields, r.ExpiryField)
+	}
+	return fields
+}
+
+// Validate compares each present track with the PAN and expiry fields.
+func (r *TrackConsistencyRule) Validate(msg *Message) error {
+	for _, fieldNum := range r.TrackFields {
+		data, err := msg.GetString(fieldNum)
+		if err != nil {
+			continue // Track not present
+		}
+		number, expiry, err := parseTrack(data)
+		if err != nil {
+			continue // Reported by the track field's own rules
+		}
+		if want, err := msg.GetString(r.PANField); r.PANField > 0 && err == nil && want != number {
+			return r.mismatch(fieldNum, r.PANField, "PAN")
+		}
+		if want, err := msg.GetString(r.ExpiryField); r.ExpiryField > 0 && err == nil && expiry != "" && want != expiry {
+			return r.mismatch(fieldNum, r.ExpiryField, "expiry date")
+		}
+	}
+	return nil
+}
+
+// mismatch reports a disagreement between a track field and another field.
+func (r *TrackConsistencyRule) mismatch(trackField, otherField int, what string) error {
+	return &ValidationError{
+		Field:   trackField,
+		Fields:  []int{trackField, otherField},
+		Rule:    r.Name(),
+		Message: fmt.Sprintf("%s in field %d does not match field %d", what, trackField, otherField),
+	}
+}
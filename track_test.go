@@ -0,0 +1,229 @@
+package iso8583
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseTrack2(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want *Track2 // nil if an error is expected
+	}{
+		{"bare", "4111111111111111=25121010000000000",
+			&Track2{PAN: "4111111111111111", Expiry: "2512", ServiceCode: "101", DiscretionaryData: "0000000000"}},
+		{"sentinels", ";4111111111111111=25121010000000000?",
+			&Track2{PAN: "4111111111111111", Expiry: "2512", ServiceCode: "101", DiscretionaryData: "0000000000"}},
+		{"sentinels and LRC", ";4111111111111111=25121010000000000?8",
+			&Track2{PAN: "4111111111111111", Expiry: "2512", ServiceCode: "101", DiscretionaryData: "0000000000"}},
+		{"packed separator", "4111111111111111D2512101",
+			&Track2{PAN: "4111111111111111", Expiry: "2512", ServiceCode: "101"}},
+		{"no expiry", "4111111111111111==101",
+			&Track2{PAN: "4111111111111111", ServiceCode: "101"}},
+		{"no expiry or service code", "4111111111111111===12",
+			&Track2{PAN: "4111111111111111", DiscretionaryData: "12"}},
+
+		{"LRC mismatch", ";4111111111111111=25121010000000000?9", nil},
+		{"LRC without start sentinel", "4111111111111111=25121010000000000?8", nil},
+		{"data after LRC", ";4111111111111111=2512101?88", nil},
+		{"missing separator", "4111111111111111", nil},
+		{"non-numeric PAN", "41111111A1111111=2512101", nil},
+		{"PAN too long", "41111111111111111111=2512101", nil},
+		{"short expiry", "4111111111111111=251", nil},
+		{"missing service code", "4111111111111111=2512", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTrack2(tt.data)
+			if tt.want == nil {
+				if !errors.Is(err, ErrInvalidTrackData) {
+					t.Fatalf("ParseTrack2(%q) = %+v, %v; want ErrInvalidTrackData", tt.data, got, err)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTrack2(%q) = %+v, %v; want %+v", tt.data, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrack1(t *testing.T) {
+	want := &Track1{
+		FormatCode:        'B',
+		PAN:               "4111111111111111",
+		Name:              "DOE/JOHN",
+		Expiry:            "2512",
+		ServiceCode:       "101",
+		DiscretionaryData: "000000000",
+	}
+	for _, data := range []string{
+		"B4111111111111111^DOE/JOHN^2512101000000000",
+		"%B4111111111111111^DOE/JOHN^2512101000000000?",
+		"%B4111111111111111^DOE/JOHN^2512101000000000?;",
+		"%B4111111111111111^DOE/JOHN                  ^2512101000000000?",
+	} {
+		got, err := ParseTrack1(data)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseTrack1(%q) = %+v, %v; want %+v", data, got, err, want)
+		}
+	}
+
+	for _, data := range []string{
+		"%B4111111111111111^DOE/JOHN^2512101000000000?:", // LRC mismatch
+		"4111111111111111^DOE/JOHN^2512101",              // Missing format code
+		"B4111111111111111^DOE/JOHN",                     // Missing second separator
+		"B4111111111111111^D^2512101",                    // Name too short
+		"B4111111111111111^DOE/JOHN^25",                  // Short expiry
+	} {
+		if got, err := ParseTrack1(data); !errors.Is(err, ErrInvalidTrackData) {
+			t.Errorf("ParseTrack1(%q) = %+v, %v; want ErrInvalidTrackData", data, got, err)
+		}
+	}
+}
+
+func TestTrackDataRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    TrackDataRule
+		data    string
+		wantErr bool
+	}{
+		{"valid track 2", TrackDataRule{Track: 2}, "4111111111111111=2512101", false},
+		{"valid track 1", TrackDataRule{Track: 1}, "%B4111111111111111^DOE/JOHN^2512101?", false},
+		{"detected track 1", TrackDataRule{}, "%B4111111111111111^DOE/JOHN^2512101?", false},
+		{"Luhn failure", TrackDataRule{Track: 2}, "6000000000000001=2512101", true},
+		{"Luhn skipped", TrackDataRule{Track: 2, SkipLuhn: true}, "6000000000000001=2512101", false},
+		{"structure still checked", TrackDataRule{Track: 2, SkipLuhn: true}, "6000000000000001", true},
+		{"empty", TrackDataRule{Track: 2}, "", true},
+		{"empty allowed", TrackDataRule{Track: 2, AllowEmpty: true}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := &Field{}
+			field.SetString(tt.data, FieldTypeANS)
+			if err := tt.rule.Validate(field); (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q) = %v, wantErr %v", tt.data, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrackConsistencyRule(t *testing.T) {
+	rule := &TrackConsistencyRule{TrackFields: []int{35}, PANField: 2, ExpiryField: 14}
+
+	tests := []struct {
+		name       string
+		fields     map[int]string
+		wantFields []int // nil if the rule should pass
+	}{
+		{"consistent", map[int]string{2: "4111111111111111", 14: "2512", 35: "4111111111111111=2512101"}, nil},
+		{"no track", map[int]string{2: "4111111111111111", 14: "2512"}, nil},
+		{"no PAN or expiry", map[int]string{35: "4111111111111111=2512101"}, nil},
+		{"track without expiry", map[int]string{14: "2601", 35: "4111111111111111==101"}, nil},
+		{"PAN mismatch", map[int]string{2: "4111111111111112", 35: "4111111111111111=2512101"}, []int{35, 2}},
+		{"expiry mismatch", map[int]string{2: "4111111111111111", 14: "2601", 35: "4111111111111111=2512101"}, []int{35, 14}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMessage(WithPackager(NewCompiledPackager(NewPackagerConfig())))
+			for fieldNum, value := range tt.fields {
+				if err := m.SetField(fieldNum, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			errs := validateMessageRules(m, []MessageRule{rule}, nil, true)
+			if tt.wantFields == nil {
+				if len(errs) != 0 {
+					t.Fatalf("unexpected error: %v", errs[0])
+				}
+				return
+			}
+			if len(errs) != 1 {
+				t.Fatalf("got %d errors, want 1", len(errs))
+			}
+			if errs[0].Field != tt.wantFields[0] || !reflect.DeepEqual(errs[0].Fields, tt.wantFields) {
+				t.Errorf("error fields = %d %v, want %v", errs[0].Field, errs[0].Fields, tt.wantFields)
+			}
+		})
+	}
+
+	if got, want := (&TrackConsistencyRule{TrackFields: []int{35, 45}, PANField: 2}).Fields(), []int{35, 45, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields() = %v, want %v", got, want)
+	}
+}
+
+func TestTrackRulesOptIn(t *testing.T) {
+	// DE 35 fails the Luhn check and disagrees with DE 2
+	validate := func(pkg *CompiledPackager) []*ValidationError {
+		m := NewMessage(WithPackager(pkg))
+		for fieldNum, value := range map[int]string{2: "4111111111111111", 35: "6000000000000001=2512101"} {
+			if err := m.SetField(fieldNum, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+		report, _ := pkg.GetValidator().ValidateMessageMode(m, ValidationStrict, ValidationCollectAll).(*ValidationReport)
+		if report == nil {
+			return nil
+		}
+		return report.FieldErrors(35)
+	}
+
+	if errs := validate(NewCompiledPackager(newTestConfig())); len(errs) != 0 {
+		t.Errorf("default layout checked DE 35: %v", errs)
+	}
+
+	errs := validate(NewCompiledPackager(newTestConfig(WithTrackRule(35, TrackDataRule{Track: 2}))))
+	if len(errs) != 1 || errs[0].Rule != "track_data" {
+		t.Errorf("TrackRules errors = %v, want a track_data error", errs)
+	}
+
+	errs = validate(NewCompiledPackager(newTestConfig(
+		WithTrackRule(35, TrackDataRule{Track: 2, SkipLuhn: true}),
+		WithTrackConsistency(TrackConsistencyRule{PANField: 2, ExpiryField: 14}),
+	)))
+	if len(errs) != 1 || errs[0].Rule != "track_consistency" || !reflect.DeepEqual(errs[0].Fields, []int{35, 2}) {
+		t.Errorf("TrackConsistency errors = %v, want a track_consistency error on [35 2]", errs)
+	}
+}
+
+func TestTrackRuleConfig(t *testing.T) {
+	for _, data := range []string{
+		`{"track_rules": {"35": {"skip_luhn": true}}}`,
+		`{"fields": {"35": {"type": "z", "length": "llvar", "max_length": 37}}, "track_rules": {"35": {}}}`,
+		`{"track_rules": {"35": {"track": 3}}}`,
+	} {
+		if _, err := LoadPackagerFromByte([]byte(data)); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("LoadPackagerFromByte(%s) = %v, want ErrInvalidRule", data, err)
+		}
+	}
+
+	for _, data := range []string{
+		`{"track_rules": {"35": {"track": 2}}}`,
+		`{"fields": {"35": {"type": "z", "length": "llvar", "max_length": 37, "format": "track2"}}, "track_rules": {"35": {"skip_luhn": true}}}`,
+	} {
+		if _, err := LoadPackagerFromByte([]byte(data)); err != nil {
+			t.Errorf("LoadPackagerFromByte(%s) = %v", data, err)
+		}
+	}
+
+	// Rules set in code are reported on validation instead
+	pkg := NewCompiledPackager(newTestConfig(WithTrackRule(35, TrackDataRule{})))
+	m := NewMessage(WithPackager(pkg))
+	if err := m.SetField(35, "4111111111111111=2512101"); err != nil {
+		t.Fatal(err)
+	}
+	report, ok := pkg.GetValidator().ValidateMessageMode(m, ValidationStrict, ValidationCollectAll).(*ValidationReport)
+	if !ok {
+		t.Fatal("expected a ValidationReport")
+	}
+	if errs := report.FieldErrors(35); len(errs) != 1 || !errors.Is(errs[0].Err, ErrInvalidRule) {
+		t.Errorf("FieldErrors(35) = %v, want an invalid rule error", errs)
+	}
+}
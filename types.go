@@ -24,6 +24,13 @@ const (
 	BitmapEncodingHex
 )
 
+type BitmapExtension int
+
+const (
+	BitmapExtensionChained BitmapExtension = iota // Bit 1 of each bitmap flags the next one (DE 1, DE 65)
+	BitmapExtensionFixed                          // Every configured bitmap is always sent
+)
+
 type LengthType int
 
 const (
@@ -52,6 +59,26 @@ const (
 	HeaderCustom
 )
 
+type Encoding int
+
+const (
+	EncodingASCII   Encoding = iota // Raw ASCII text (default)
+	EncodingEBCDIC                  // EBCDIC code page 037
+	EncodingBCD                     // Packed BCD, right-justified (leading pad nibble)
+	EncodingBCDLeft                 // Packed BCD, left-justified (trailing pad nibble)
+	EncodingBinary                  // Raw bytes
+	EncodingHex                     // Raw bytes carried as ASCII hex characters
+	EncodingCustom                  // First value available to RegisterEncoding
+)
+
+type SubfieldFormat int
+
+const (
+	SubfieldFixed  SubfieldFormat = iota // Positional subfields in subfield-number order (e.g., DE 90, DE 95)
+	SubfieldBitmap                       // 8-byte sub-bitmap followed by the present subfields
+	SubfieldTLV                          // ASCII TLV with numeric tags (e.g., DE 48 subelements)
+)
+
 type TLVType int
 
 const (
@@ -63,10 +90,18 @@ const (
 type ValidationLevel int
 
 const (
-	ValidationNone ValidationLevel = iota
-	ValidationBasic
-	ValidationStrict
-	ValidationCustom
+	ValidationNone   ValidationLevel = iota // No validation; Unpack skips undecodable fields
+	ValidationBasic                         // Mandatory field presence and field lengths
+	ValidationStrict                        // Basic plus charset, format and cross-field rules, and registered rules
+	ValidationCustom                        // Only registered rules
+)
+
+// ValidationMode selects whether validation stops at the first failure.
+type ValidationMode int
+
+const (
+	ValidationFailFast   ValidationMode = iota // Return the first failure
+	ValidationCollectAll                       // Return a ValidationReport with every failure
 )
 
 type Field struct {
@@ -78,25 +113,68 @@ type Field struct {
 }
 
 type TLV struct {
-	Tag    []byte
-	Length int
-	Value  []byte
+	Tag      []byte
+	Length   int
+	Value    []byte
+	Children []TLV // Nested entries of a constructed EMV tag (e.g., 70, 77, 71/72)
 }
 
 type FieldConfig struct {
-	Type      FieldType  `json:"type"`
-	Length    LengthType `json:"length"`
-	MaxLength int        `json:"max_length"`
-	MinLength int        `json:"min_length"`
-	Mandatory bool       `json:"mandatory"`
-	Format    string     `json:"format,omitempty"`
+	Type           FieldType           `json:"type"`
+	Length         LengthType          `json:"length"`
+	MaxLength      int                 `json:"max_length"`
+	MinLength      int                 `json:"min_length"`
+	Mandatory      bool                `json:"mandatory"`
+	Format         string              `json:"format,omitempty"`
+	Encoding       Encoding            `json:"encoding"`
+	LengthEncoding Encoding            `json:"length_encoding"`
+	TLV            *FieldTLVConfig     `json:"tlv,omitempty"`
+	Subfields      map[int]FieldConfig `json:"subfields,omitempty"`
+	SubfieldFormat SubfieldFormat      `json:"subfield_format"`
+	Name           string              `json:"name,omitempty"`        // Short label shown by Dump (e.g., "Primary account number")
+	Description    string              `json:"description,omitempty"` // Longer explanation shown by Dump
+	Alias          string              `json:"alias,omitempty"`       // JSON key used instead of the field number
+}
+
+// FieldTLVConfig marks a field as TLV-encoded (e.g., DE 55 EMV data, DE 48 ASCII TLV).
+type FieldTLVConfig struct {
+	Type         TLVType `json:"type"`
+	TagLength    int     `json:"tag_length,omitempty"`    // ASCII TLV only: characters per tag
+	LengthLength int     `json:"length_length,omitempty"` // ASCII TLV only: characters per length
+	LengthBase   int     `json:"length_base,omitempty"`   // ASCII TLV only: 10 or 16
+}
+
+func (tc *FieldTLVConfig) UnmarshalJSON(data []byte) error {
+	type Alias FieldTLVConfig
+	aux := &struct {
+		Type interface{} `json:"type"`
+		*Alias
+	}{
+		Alias: (*Alias)(tc),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	switch v := aux.Type.(type) {
+	case float64:
+		tc.Type = TLVType(v)
+	case string:
+		tc.Type = parseTLVTypeString(v)
+	}
+
+	return nil
 }
 
 func (fc *FieldConfig) UnmarshalJSON(data []byte) error {
 	type Alias FieldConfig
 	aux := &struct {
-		Type   interface{} `json:"type"`
-		Length interface{} `json:"length"`
+		Type           interface{} `json:"type"`
+		Length         interface{} `json:"length"`
+		Encoding       interface{} `json:"encoding"`
+		LengthEncoding interface{} `json:"length_encoding"`
+		SubfieldFormat interface{} `json:"subfield_format"`
 		*Alias
 	}{
 		Alias: (*Alias)(fc),
@@ -120,6 +198,27 @@ func (fc *FieldConfig) UnmarshalJSON(data []byte) error {
 		fc.Length = parseLengthTypeString(v)
 	}
 
+	switch v := aux.Encoding.(type) {
+	case float64:
+		fc.Encoding = Encoding(v)
+	case string:
+		fc.Encoding = parseEncodingString(v)
+	}
+
+	switch v := aux.LengthEncoding.(type) {
+	case float64:
+		fc.LengthEncoding = Encoding(v)
+	case string:
+		fc.LengthEncoding = parseEncodingString(v)
+	}
+
+	switch v := aux.SubfieldFormat.(type) {
+	case float64:
+		fc.SubfieldFormat = SubfieldFormat(v)
+	case string:
+		fc.SubfieldFormat = parseSubfieldFormatString(v)
+	}
+
 	return nil
 }
 
@@ -155,6 +254,47 @@ func parseLengthTypeString(s string) LengthType {
 	}
 }
 
+func parseEncodingString(s string) Encoding {
+	switch strings.ToUpper(s) {
+	case "ASCII":
+		return EncodingASCII
+	case "EBCDIC":
+		return EncodingEBCDIC
+	case "BCD", "BCD_RIGHT":
+		return EncodingBCD
+	case "BCD_LEFT":
+		return EncodingBCDLeft
+	case "BINARY", "B":
+		return EncodingBinary
+	case "HEX":
+		return EncodingHex
+	default:
+		return EncodingASCII // safe fallback
+	}
+}
+
+func parseSubfieldFormatString(s string) SubfieldFormat {
+	switch strings.ToUpper(s) {
+	case "BITMAP":
+		return SubfieldBitmap
+	case "TLV":
+		return SubfieldTLV
+	default:
+		return SubfieldFixed
+	}
+}
+
+func parseTLVTypeString(s string) TLVType {
+	switch strings.ToUpper(s) {
+	case "EMV", "BER":
+		return TLVEMV
+	case "ASCII":
+		return TLVASCII
+	default:
+		return TLVStandard
+	}
+}
+
 type LengthIndicatorConfig struct {
 	Type   LengthIndicatorType `json:"type"`
 	Length int                 `json:"length"`
@@ -173,16 +313,34 @@ type TLVConfig struct {
 }
 
 type PackagerConfig struct {
-	Fields          map[int]FieldConfig   `json:"fields"`
-	BitmapEncoding  BitmapEncoding        `json:"bitmap_encoding"`
-	LengthIndicator LengthIndicatorConfig `json:"length_indicator"`
-	Header          HeaderConfig          `json:"header"`
-	TLV             TLVConfig             `json:"tlv"`
+	Fields            map[int]FieldConfig             `json:"fields"`
+	BitmapEncoding    BitmapEncoding                  `json:"bitmap_encoding"`
+	Bitmaps           int                             `json:"bitmaps,omitempty"` // Maximum number of bitmaps (1-3, default 2)
+	BitmapExtension   BitmapExtension                 `json:"bitmap_extension"`
+	LengthIndicator   LengthIndicatorConfig           `json:"length_indicator"`
+	Header            HeaderConfig                    `json:"header"`
+	TLV               TLVConfig                       `json:"tlv"`
+	Version           Version                         `json:"version,omitempty"`            // Field layout edition (default 1987)
+	DetectVersion     bool                            `json:"detect_version"`               // Pick the profile from the MTI version digit on Unpack
+	VersionFields     map[Version]map[int]FieldConfig `json:"version_fields,omitempty"`     // Per-version field overrides for DetectVersion
+	ResponseTemplates map[string]ResponseTemplate     `json:"response_templates,omitempty"` // Keyed by request MTI or pattern (e.g., "04x0")
+	Masking           map[int]MaskMode                `json:"masking,omitempty"`            // Per-field log masking, layered over DefaultMaskingPolicy
+	PresenceRules     map[string]map[int]Presence     `json:"presence_rules,omitempty"`     // Per-MTI presence rules, layered over DefaultPresenceRules if enabled
+	DefaultPresence   bool                            `json:"default_presence_rules"`       // Apply DefaultPresenceRules
+	CrossFieldRules   []CrossFieldRuleConfig          `json:"cross_field_rules,omitempty"`  // Declarative rules over several fields, checked at ValidationStrict
+	PANRules          map[int]*PANRule                `json:"pan_rules,omitempty"`          // PAN checks per field (e.g. BIN lists for DE 2), checked at ValidationStrict
+	TrackRules        map[int]*TrackDataRule          `json:"track_rules,omitempty"`        // Track data checks per field (e.g. SkipLuhn for DE 35), checked at ValidationStrict
+	TrackConsistency  *TrackConsistencyRule           `json:"track_consistency,omitempty"`  // Check track data against other fields (e.g. DE 2 and DE 14), at ValidationStrict
+	MaskingKey        []byte                          `json:"-"`                            // HMAC key for MaskHash; never loaded from JSON
+	MaskingKeyEnv     string                          `json:"masking_key_env,omitempty"`    // Environment variable holding the MaskHash key if MaskingKey is unset
+	LogRawMessage     bool                            `json:"log_raw_message"`              // Include the unmasked raw message in LogValue
 }
 
 const (
 	DefaultBufferSize   = 8192
-	MaxFieldNumber      = 128
+	MaxFieldNumber      = 192
+	MaxBitmaps          = 3
+	DefaultBitmaps      = 2
 	BitmapSize          = 8
 	SecondaryBitmapSize = 8
 )
@@ -1,9 +1,14 @@
 package iso8583
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/mkadit/iso8583/pan"
 )
 
 // ValidationRule defines the interface for a single validation rule.
@@ -12,31 +17,68 @@ type ValidationRule interface {
 	Name() string // Returns the name of the rule (e.g., "length")
 }
 
+// MessageRule defines the interface for a rule over the whole message,
+// e.g. "DE 14 required if DE 22 indicates manual entry". Validate may
+// return a *ValidationError to report only some of the rule's fields.
+type MessageRule interface {
+	Validate(msg *Message) error
+	Name() string
+	Fields() []int // Fields the rule involves, reported in its ValidationError
+}
+
 // CompiledValidator holds a pre-compiled set of validation rules
 // derived from a PackagerConfig. It is safe for concurrent use.
+//
+// What is checked depends on the ValidationLevel:
+//   - ValidationBasic: field presence (FieldConfig.Mandatory refined by the
+//     per-MTI presence rules) and field lengths.
+//   - ValidationStrict: Basic plus charset rules of the field types,
+//     FieldConfig.Format rules, the configured cross-field rules and every
+//     registered rule.
+//   - ValidationCustom: only registered rules (AddFieldRule, AddGlobalRule,
+//     AddMessageRule).
 type CompiledValidator struct {
 	mandatoryFields map[int]bool              // Fast lookup for mandatory fields
-	fieldRules      map[int][]ValidationRule  // Rules specific to a field number
+	presenceRules   []presenceRuleEntry       // Per-MTI presence rules, most specific pattern first
+	lengthRules     map[int][]ValidationRule  // Length rules derived from the field configs
+	fieldRules      map[int][]ValidationRule  // Charset rules derived from the field configs
+	formatRules     map[int][]ValidationRule  // Date/time and amount rules derived from FieldConfig.Format
+	customRules     map[int][]ValidationRule  // Rules registered for a single field
 	globalRules     []ValidationRule          // Rules applied to all fields
+	crossFieldRules []MessageRule             // Rules compiled from PackagerConfig.CrossFieldRules
+	messageRules    []MessageRule             // Message-level rules registered with AddMessageRule
 	regexCache      map[string]*regexp.Regexp // Cache for compiled regex rules
-	charsetEnabled  bool                      // (Not currently used)
-	lengthEnabled   bool                      // (Not currently used)
-	presenceEnabled bool                      // (Not currently used)
-	validationLevel ValidationLevel           // (Not currently used)
+	charsetEnabled  bool                      // Run charset rules at ValidationStrict
+	lengthEnabled   bool                      // Run length rules at ValidationBasic and ValidationStrict
+	presenceEnabled bool                      // Check mandatory fields at ValidationBasic and ValidationStrict
+	validationLevel ValidationLevel           // Level used by ValidateField
 	mu              sync.RWMutex
 }
 
+// validationChecks lists the groups of rules run at a validation level.
+type validationChecks struct {
+	presence   bool
+	length     bool
+	charset    bool
+	format     bool
+	crossField bool
+	registered bool
+}
+
 // NewCompiledValidator creates a new, empty validator.
 func NewCompiledValidator() *CompiledValidator {
 	return &CompiledValidator{
 		mandatoryFields: make(map[int]bool),
+		lengthRules:     make(map[int][]ValidationRule),
 		fieldRules:      make(map[int][]ValidationRule),
+		formatRules:     make(map[int][]ValidationRule),
+		customRules:     make(map[int][]ValidationRule),
 		globalRules:     make([]ValidationRule, 0),
 		regexCache:      make(map[string]*regexp.Regexp),
 		charsetEnabled:  true,
 		lengthEnabled:   true,
 		presenceEnabled: true,
-		validationLevel: ValidationBasic,
+		validationLevel: ValidationStrict,
 	}
 }
 
@@ -47,9 +89,74 @@ func (cv *CompiledValidator) AddGlobalRule(rule ValidationRule) {
 	cv.globalRules = append(cv.globalRules, rule)
 }
 
+// AddFieldRule adds a rule that will be applied to a single field.
+func (cv *CompiledValidator) AddFieldRule(fieldNum int, rule ValidationRule) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.customRules[fieldNum] = append(cv.customRules[fieldNum], rule)
+}
+
+// AddMessageRule adds a rule that will be applied to the whole message.
+func (cv *CompiledValidator) AddMessageRule(rule MessageRule) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.messageRules = append(cv.messageRules, rule)
+}
+
+// SetCharsetValidation enables or disables the charset rules of ValidationStrict.
+func (cv *CompiledValidator) SetCharsetValidation(enabled bool) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.charsetEnabled = enabled
+}
+
+// SetLengthValidation enables or disables the length rules of
+// ValidationBasic and ValidationStrict.
+func (cv *CompiledValidator) SetLengthValidation(enabled bool) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.lengthEnabled = enabled
+}
+
+// SetPresenceValidation enables or disables the mandatory field checks of
+// ValidationBasic and ValidationStrict.
+func (cv *CompiledValidator) SetPresenceValidation(enabled bool) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.presenceEnabled = enabled
+}
+
+// SetValidationLevel sets the level used by ValidateField (ValidationStrict by default).
+func (cv *CompiledValidator) SetValidationLevel(level ValidationLevel) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.validationLevel = level
+}
+
+// checks returns the groups of rules run at a level.
+func (cv *CompiledValidator) checks(level ValidationLevel) validationChecks {
+	switch level {
+	case ValidationBasic:
+		return validationChecks{presence: cv.presenceEnabled, length: cv.lengthEnabled}
+	case ValidationStrict:
+		return validationChecks{presence: cv.presenceEnabled, length: cv.lengthEnabled, charset: cv.charsetEnabled, format: true, crossField: true, registered: true}
+	case ValidationCustom:
+		return validationChecks{registered: true}
+	default:
+		return validationChecks{}
+	}
+}
+
 // ValidateMessage validates an entire Message.
-// It checks for mandatory field presence and then validates all present fields.
+// It checks for mandatory field presence and then validates all present
+// fields, returning the first failure.
 func (cv *CompiledValidator) ValidateMessage(msg *Message, level ValidationLevel) error {
+	return cv.ValidateMessageMode(msg, level, ValidationFailFast)
+}
+
+// ValidateMessageMode validates an entire Message. In ValidationCollectAll
+// mode every failing field and rule is returned as a *ValidationReport.
+func (cv *CompiledValidator) ValidateMessageMode(msg *Message, level ValidationLevel, mode ValidationMode) error {
 	cv.mu.RLock()
 	defer cv.mu.RUnlock()
 
@@ -57,58 +164,145 @@ func (cv *CompiledValidator) ValidateMessage(msg *Message, level ValidationLevel
 		return nil
 	}
 
-	// Check for mandatory fields
-	for fieldNum := 1; fieldNum <= 128; fieldNum++ {
-		if cv.mandatoryFields[fieldNum] && !msg.HasField(fieldNum) {
-			return &ValidationError{
-				Field:   fieldNum,
-				Rule:    "mandatory",
-				Message: "mandatory field missing",
+	checks := cv.checks(level)
+	report := &ValidationReport{}
+	failFast := mode == ValidationFailFast
+	mti := msg.GetMTI()
+	matched := cv.matchPresenceRules(mti)
+	for fieldNum := 1; fieldNum <= MaxFieldNumber; fieldNum++ {
+		present := msg.HasField(fieldNum)
+
+		// Check the field's presence rule for this MTI
+		if checks.presence {
+			switch cv.presenceFor(matched, fieldNum) {
+			case PresenceMandatory:
+				if !present {
+					report.Errors = append(report.Errors, &ValidationError{
+						Field:   fieldNum,
+						Rule:    "mandatory",
+						Message: "mandatory field missing",
+					})
+				}
+			case PresenceForbidden:
+				if present {
+					report.Errors = append(report.Errors, &ValidationError{
+						Field:   fieldNum,
+						Rule:    "forbidden",
+						Message: "field not allowed in MTI " + mti.String(),
+					})
+				}
+			}
+			if failFast && len(report.Errors) > 0 {
+				return report.Errors[0]
 			}
 		}
 
 		// Validate the field if it's present
-		if msg.HasField(fieldNum) {
+		if present {
 			field, _ := msg.GetField(fieldNum) // Error check not needed, HasField was true
-			if err := cv.ValidateField(fieldNum, field); err != nil {
-				return err // Return the first error found
+			report.Errors = cv.validateField(fieldNum, field, checks, report.Errors, failFast)
+			if failFast && len(report.Errors) > 0 {
+				return report.Errors[0]
 			}
 		}
 	}
 
+	// Run message-level rules
+	if checks.crossField {
+		report.Errors = validateMessageRules(msg, cv.crossFieldRules, report.Errors, failFast)
+	}
+	if checks.registered && !(failFast && len(report.Errors) > 0) {
+		report.Errors = validateMessageRules(msg, cv.messageRules, report.Errors, failFast)
+	}
+	if failFast && len(report.Errors) > 0 {
+		return report.Errors[0]
+	}
+
+	if len(report.Errors) > 0 {
+		return report
+	}
 	return nil
 }
 
-// ValidateField validates a single field against all applicable rules.
+// validateMessageRules appends the failures of message-level rules to errs,
+// stopping at the first one if failFast is set.
+func validateMessageRules(msg *Message, rules []MessageRule, errs []*ValidationError, failFast bool) []*ValidationError {
+	for _, rule := range rules {
+		err := rule.Validate(msg)
+		if err == nil {
+			continue
+		}
+		ve := &ValidationError{
+			Fields:  rule.Fields(),
+			Rule:    rule.Name(),
+			Message: err.Error(),
+			Err:     err,
+		}
+		var ruleErr *ValidationError
+		if errors.As(err, &ruleErr) {
+			ve.Message, ve.Err = ruleErr.Message, ruleErr.Err
+			if len(ruleErr.Fields) > 0 {
+				ve.Fields = ruleErr.Fields
+			} else if ruleErr.Field > 0 {
+				ve.Fields = []int{ruleErr.Field}
+			}
+		}
+		if len(ve.Fields) > 0 {
+			ve.Field = ve.Fields[0]
+		}
+		errs = append(errs, ve)
+		if failFast {
+			return errs
+		}
+	}
+	return errs
+}
+
+// ValidateField validates a single field against the rules of the
+// validator's level (see SetValidationLevel).
 func (cv *CompiledValidator) ValidateField(fieldNum int, field *Field) error {
 	cv.mu.RLock()
 	defer cv.mu.RUnlock()
 
-	// Run field-specific rules
-	if rules, exists := cv.fieldRules[fieldNum]; exists {
+	if errs := cv.validateField(fieldNum, field, cv.checks(cv.validationLevel), nil, true); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// validateField appends the failures of a field to errs, stopping at the
+// first one if failFast is set.
+func (cv *CompiledValidator) validateField(fieldNum int, field *Field, checks validationChecks, errs []*ValidationError, failFast bool) []*ValidationError {
+	var groups [5][]ValidationRule
+	if checks.length {
+		groups[0] = cv.lengthRules[fieldNum]
+	}
+	if checks.charset {
+		groups[1] = cv.fieldRules[fieldNum]
+	}
+	if checks.format {
+		groups[2] = cv.formatRules[fieldNum]
+	}
+	if checks.registered {
+		groups[3] = cv.customRules[fieldNum]
+		groups[4] = cv.globalRules
+	}
+
+	for _, rules := range groups {
 		for _, rule := range rules {
 			if err := rule.Validate(field); err != nil {
-				return &ValidationError{
+				errs = append(errs, &ValidationError{
 					Field:   fieldNum,
 					Rule:    rule.Name(),
 					Message: err.Error(),
+				})
+				if failFast {
+					return errs
 				}
 			}
 		}
 	}
-
-	// Run global rules
-	for _, rule := range cv.globalRules {
-		if err := rule.Validate(field); err != nil {
-			return &ValidationError{
-				Field:   fieldNum,
-				Rule:    rule.Name(),
-				Message: err.Error(),
-			}
-		}
-	}
-
-	return nil
+	return errs
 }
 
 // Clone creates a deep copy of the CompiledValidator.
@@ -121,14 +315,17 @@ func (cv *CompiledValidator) Clone() *CompiledValidator {
 	for k, v := range cv.mandatoryFields {
 		clone.mandatoryFields[k] = v
 	}
+	clone.presenceRules = cv.presenceRules // Compiled entries are never modified
 
-	for k, v := range cv.fieldRules {
-		clone.fieldRules[k] = make([]ValidationRule, len(v))
-		copy(clone.fieldRules[k], v)
-	}
+	copyRules(clone.lengthRules, cv.lengthRules)
+	copyRules(clone.fieldRules, cv.fieldRules)
+	copyRules(clone.formatRules, cv.formatRules)
+	copyRules(clone.customRules, cv.customRules)
 
 	clone.globalRules = make([]ValidationRule, len(cv.globalRules))
 	copy(clone.globalRules, cv.globalRules)
+	clone.crossFieldRules = cv.crossFieldRules // Compiled from config, never modified
+	clone.messageRules = append([]MessageRule(nil), cv.messageRules...)
 
 	clone.charsetEnabled = cv.charsetEnabled
 	clone.lengthEnabled = cv.lengthEnabled
@@ -140,6 +337,14 @@ func (cv *CompiledValidator) Clone() *CompiledValidator {
 	return clone
 }
 
+// copyRules copies the rule slices of src into dst.
+func copyRules(dst, src map[int][]ValidationRule) {
+	for k, v := range src {
+		dst[k] = make([]ValidationRule, len(v))
+		copy(dst[k], v)
+	}
+}
+
 // --- Validation Rule Implementations ---
 
 // LengthRule validates the field's length.
@@ -372,6 +577,28 @@ func (r *CustomRule) Validate(field *Field) error {
 	return r.ValidateFunc(field)
 }
 
+// CustomMessageRule allows defining an arbitrary message-level validation function.
+type CustomMessageRule struct {
+	ValidateFunc func(*Message) error
+	RuleName     string
+	RuleFields   []int
+}
+
+// Name returns the custom rule name.
+func (r *CustomMessageRule) Name() string {
+	return r.RuleName
+}
+
+// Fields returns the fields the rule involves.
+func (r *CustomMessageRule) Fields() []int {
+	return r.RuleFields
+}
+
+// Validate executes the custom validation function.
+func (r *CustomMessageRule) Validate(msg *Message) error {
+	return r.ValidateFunc(msg)
+}
+
 // PresenceRule validates that a field is present.
 type PresenceRule struct {
 	Required bool
@@ -390,17 +617,85 @@ func (r *PresenceRule) Validate(field *Field) error {
 	return nil
 }
 
-// TrackDataRule provides basic validation for track data (e.g., from a magnetic stripe).
-type TrackDataRule struct {
+// FormatRule validates a field against a FieldConfig.Format: a date/time
+// format such as "MMDDhhmmss" or "YYMM", or an amount ("amount", "amount:2",
+// "signed_amount"), DE 54 "additional_amounts" blocks, "track1"/"track2"
+// magnetic stripe data or a "pan" (see PANRule).
+type FormatRule struct {
+	Format     string
 	AllowEmpty bool
 }
 
+// Name returns the rule name.
+func (r *FormatRule) Name() string {
+	return "format"
+}
+
+// Validate checks that the value is a valid date/time or amount.
+func (r *FormatRule) Validate(field *Field) error {
+	data := field.String()
+
+	if len(data) == 0 && r.AllowEmpty {
+		return nil
+	}
+
+	if layout, ok := timeLayout(r.Format); ok {
+		if _, err := time.Parse(layout.layout, data); err != nil {
+			return fmt.Errorf("invalid %s value %q", r.Format, data)
+		}
+		return nil
+	}
+
+	if _, _, signed, ok := amountFormat(r.Format); ok {
+		start := 0
+		if signed {
+			if len(data) == 0 || (data[0] != 'C' && data[0] != 'D') {
+				return fmt.Errorf("amount must start with C or D")
+			}
+			start = 1
+		}
+		if len(data) == start {
+			return fmt.Errorf("empty amount")
+		}
+		for i := start; i < len(data); i++ {
+			if data[i] < '0' || data[i] > '9' {
+				return fmt.Errorf("non-numeric amount character at position %d", i)
+			}
+		}
+		return nil
+	}
+
+	if r.Format == "pan" {
+		return (&PANRule{}).Validate(field)
+	}
+
+	if track, ok := trackFormats[r.Format]; ok {
+		return (&TrackDataRule{Track: track}).Validate(field)
+	}
+
+	if r.Format == "additional_amounts" {
+		_, err := ParseAdditionalAmounts(field.Bytes())
+		return err
+	}
+
+	return fmt.Errorf("unknown format %q", r.Format)
+}
+
+// TrackDataRule validates magnetic stripe data: structure, sentinels and
+// LRC (when present), and the Luhn check digit of the PAN unless SkipLuhn
+// is set (e.g. for private-label cards).
+type TrackDataRule struct {
+	AllowEmpty bool `json:"allow_empty"`
+	Track      int  `json:"track,omitempty"` // 1 or 2; 0 detects track 1 by its '%' sentinel or format code
+	SkipLuhn   bool `json:"skip_luhn"`
+}
+
 // Name returns the rule name.
 func (r *TrackDataRule) Name() string {
 	return "track_data"
 }
 
-// Validate performs a very basic check on track data.
+// Validate parses the track data and checks the PAN.
 func (r *TrackDataRule) Validate(field *Field) error {
 	data := field.String()
 
@@ -408,12 +703,101 @@ func (r *TrackDataRule) Validate(field *Field) error {
 		return nil
 	}
 
-	// Basic sanity check
-	if len(data) < 10 {
-		return fmt.Errorf("track data too short")
+	var number string
+	switch r.Track {
+	case 1:
+		track, err := ParseTrack1(data)
+		if err != nil {
+			return err
+		}
+		number = track.PAN
+	case 2:
+		track, err := ParseTrack2(data)
+		if err != nil {
+			return err
+		}
+		number = track.PAN
+	default:
+		var err error
+		if number, _, err = parseTrack(data); err != nil {
+			return err
+		}
 	}
 
-	// More complex track data validation (e.g., format, LRC) could go here.
+	if !r.SkipLuhn && !pan.Luhn(number) {
+		return fmt.Errorf("PAN in track data fails Luhn check")
+	}
+	return nil
+}
+
+// PANRule validates a primary account number: length, Luhn check digit
+// and optional BIN allow/deny lists. BIN patterns are prefixes ("4") or
+// ranges of equal-length prefixes ("222100-272099").
+type PANRule struct {
+	MinLength  int      `json:"min_length,omitempty"` // Default 12
+	MaxLength  int      `json:"max_length,omitempty"` // Default 19
+	SkipLuhn   bool     `json:"skip_luhn"`
+	AllowBINs  []string `json:"allow_bins,omitempty"` // If set, the PAN must match one of these
+	DenyBINs   []string `json:"deny_bins,omitempty"`  // The PAN must match none of these
+	AllowEmpty bool     `json:"allow_empty"`
+}
+
+// Name returns the rule name.
+func (r *PANRule) Name() string {
+	return "pan"
+}
+
+// Check reports invalid settings: malformed BIN patterns or inverted lengths.
+func (r *PANRule) Check() error {
+	if r.MinLength < 0 || r.MaxLength < 0 || (r.MaxLength > 0 && r.MinLength > r.MaxLength) {
+		return fmt.Errorf("%w: PAN lengths %d-%d", ErrInvalidRule, r.MinLength, r.MaxLength)
+	}
+	for _, patterns := range [][]string{r.AllowBINs, r.DenyBINs} {
+		for _, pattern := range patterns {
+			if err := pan.CheckBINPattern(pattern); err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidRule, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks the PAN.
+func (r *PANRule) Validate(field *Field) error {
+	number := field.String()
+
+	if len(number) == 0 && r.AllowEmpty {
+		return nil
+	}
+
+	minLength, maxLength := r.MinLength, r.MaxLength
+	if minLength == 0 {
+		minLength = pan.MinLength
+	}
+	if maxLength == 0 {
+		maxLength = pan.MaxLength
+	}
+	if len(number) < minLength || len(number) > maxLength {
+		return fmt.Errorf("PAN length %d outside %d-%d", len(number), minLength, maxLength)
+	}
+
+	if !r.SkipLuhn && !pan.Luhn(number) {
+		return fmt.Errorf("PAN fails Luhn check")
+	}
+
+	for _, pattern := range r.DenyBINs {
+		if pan.MatchBIN(number, pattern) {
+			return fmt.Errorf("BIN %s is not allowed", pan.BIN(number, 6))
+		}
+	}
+	if len(r.AllowBINs) > 0 {
+		for _, pattern := range r.AllowBINs {
+			if pan.MatchBIN(number, pattern) {
+				return nil
+			}
+		}
+		return fmt.Errorf("BIN %s is not allowed", pan.BIN(number, 6))
+	}
 
 	return nil
 }
@@ -422,6 +806,11 @@ func (r *TrackDataRule) Validate(field *Field) error {
 // defined in a PackagerConfig.
 func compileValidator(config *PackagerConfig) *CompiledValidator {
 	validator := NewCompiledValidator()
+	validator.presenceRules = compilePresenceRules(config.PresenceRules, config.DefaultPresence)
+	validator.crossFieldRules = compileCrossFieldRules(config)
+	if err := validatePresenceRules(config.PresenceRules); err != nil {
+		validator.crossFieldRules = append(validator.crossFieldRules, &invalidRule{name: "presence", err: err})
+	}
 
 	for fieldNum, fieldConfig := range config.Fields {
 		// Add mandatory presence rule
@@ -429,16 +818,16 @@ func compileValidator(config *PackagerConfig) *CompiledValidator {
 			validator.mandatoryFields[fieldNum] = true
 		}
 
-		var rules []ValidationRule
-
 		// Add length rule
 		if fieldConfig.MinLength > 0 || fieldConfig.MaxLength > 0 {
-			rules = append(rules, &LengthRule{
+			validator.lengthRules[fieldNum] = []ValidationRule{&LengthRule{
 				MinLength: fieldConfig.MinLength,
 				MaxLength: fieldConfig.MaxLength,
-			})
+			}}
 		}
 
+		var rules []ValidationRule
+
 		// Add content type rule
 		switch fieldConfig.Type {
 		case FieldTypeN:
@@ -465,7 +854,88 @@ func compileValidator(config *PackagerConfig) *CompiledValidator {
 		if len(rules) > 0 {
 			validator.fieldRules[fieldNum] = rules
 		}
+
+		// Add format rule
+		if track, ok := trackFormats[fieldConfig.Format]; ok {
+			validator.formatRules[fieldNum] = []ValidationRule{&TrackDataRule{Track: track}}
+		} else if fieldConfig.Format == "pan" {
+			validator.formatRules[fieldNum] = []ValidationRule{&PANRule{}}
+		} else if knownFormat(fieldConfig.Format) {
+			validator.formatRules[fieldNum] = []ValidationRule{&FormatRule{Format: fieldConfig.Format}}
+		}
+	}
+
+	// Configured PAN and track rules replace the default format rule
+	for fieldNum, rule := range config.PANRules {
+		if rule == nil {
+			continue
+		}
+		if err := rule.Check(); err != nil {
+			validator.crossFieldRules = append(validator.crossFieldRules, &invalidRule{
+				name:   rule.Name(),
+				err:    err,
+				fields: []int{fieldNum},
+			})
+			continue
+		}
+		validator.formatRules[fieldNum] = []ValidationRule{rule}
+	}
+	for fieldNum, rule := range config.TrackRules {
+		if rule == nil {
+			continue
+		}
+		trackRule, err := trackRuleFor(rule, config.Fields[fieldNum])
+		if err != nil {
+			validator.crossFieldRules = append(validator.crossFieldRules, &invalidRule{
+				name:   rule.Name(),
+				err:    err,
+				fields: []int{fieldNum},
+			})
+			continue
+		}
+		validator.formatRules[fieldNum] = []ValidationRule{trackRule}
+	}
+
+	// Check track data against other fields only when configured
+	if config.TrackConsistency != nil {
+		rule := *config.TrackConsistency
+		if len(rule.TrackFields) == 0 {
+			rule.TrackFields = trackFields(config)
+		}
+		if len(rule.TrackFields) > 0 {
+			validator.crossFieldRules = append(validator.crossFieldRules, &rule)
+		}
 	}
 
 	return validator
 }
+
+// trackRuleFor returns the rule for a field, taking the track number from
+// the field's track1/track2 format when the rule does not set it.
+func trackRuleFor(rule *TrackDataRule, fieldConfig FieldConfig) (*TrackDataRule, error) {
+	resolved := *rule
+	if resolved.Track == 0 {
+		resolved.Track = trackFormats[fieldConfig.Format]
+	}
+	if resolved.Track != 1 && resolved.Track != 2 {
+		return nil, fmt.Errorf("%w: track rule needs track 1 or 2 or a track1/track2 field format", ErrInvalidRule)
+	}
+	return &resolved, nil
+}
+
+// trackFields returns the fields with a track1/track2 format or a track rule.
+func trackFields(config *PackagerConfig) []int {
+	var fields []int
+	for fieldNum, fieldConfig := range config.Fields {
+		if _, ok := trackFormats[fieldConfig.Format]; ok || config.TrackRules[fieldNum] != nil {
+			fields = append(fields, fieldNum)
+		}
+	}
+	for fieldNum, rule := range config.TrackRules {
+		if _, ok := config.Fields[fieldNum]; !ok && rule != nil {
+			fields = append(fields, fieldNum)
+		}
+	}
+	sort.Ints(fields)
+	return fields
+}
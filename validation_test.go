@@ -0,0 +1,390 @@
+package iso8583
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mkadit/iso8583/pan"
+)
+
+func TestPANRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    PANRule
+		number  string
+		wantErr bool
+	}{
+		{"valid", PANRule{}, "4111111111111111", false},
+		{"Luhn failure", PANRule{}, "4111111111111112", true},
+		{"Luhn skipped", PANRule{SkipLuhn: true}, "4111111111111112", false},
+		{"too short", PANRule{}, "41111111111", true},
+		{"custom length", PANRule{MinLength: 16, MaxLength: 16}, "378282246310005", true},
+		{"empty", PANRule{}, "", true},
+		{"empty allowed", PANRule{AllowEmpty: true}, "", false},
+		{"allowed prefix", PANRule{AllowBINs: []string{"5", "4"}}, "4111111111111111", false},
+		{"allowed range", PANRule{AllowBINs: []string{"222100-272099"}}, "2223000048400011", false},
+		{"not allowed", PANRule{AllowBINs: []string{"5"}}, "4111111111111111", true},
+		{"denied", PANRule{DenyBINs: []string{"411111"}}, "4111111111111111", true},
+		{"deny wins over allow", PANRule{AllowBINs: []string{"4"}, DenyBINs: []string{"4111"}}, "4111111111111111", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := &Field{}
+			field.SetString(tt.number, FieldTypeN)
+			if err := tt.rule.Validate(field); (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q) = %v, wantErr %v", tt.number, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPANRuleCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		rule PANRule
+		err  error // nil if the rule is valid
+	}{
+		{"defaults", PANRule{}, nil},
+		{"patterns", PANRule{AllowBINs: []string{"4", "222100-272099"}, DenyBINs: []string{"411111"}}, nil},
+		{"wildcard", PANRule{AllowBINs: []string{"41xxxx"}}, pan.ErrInvalidBINPattern},
+		{"trailing space", PANRule{DenyBINs: []string{"411111 "}}, pan.ErrInvalidBINPattern},
+		{"inverted range", PANRule{AllowBINs: []string{"55-51"}}, pan.ErrInvalidBINPattern},
+		{"inverted lengths", PANRule{MinLength: 19, MaxLength: 12}, ErrInvalidRule},
+		{"negative length", PANRule{MinLength: -1}, ErrInvalidRule},
+	}
+
+	for _, tt := range tests {
+		err := tt.rule.Check()
+		if tt.err == nil {
+			if err != nil {
+				t.Errorf("%s: Check() = %v", tt.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, tt.err) || !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("%s: Check() = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestPANRuleConfig(t *testing.T) {
+	_, err := LoadPackagerFromByte([]byte(`{"pan_rules": {"2": {"allow_bins": ["41xxxx"]}}}`))
+	if !errors.Is(err, pan.ErrInvalidBINPattern) {
+		t.Errorf("LoadPackagerFromByte = %v, want ErrInvalidBINPattern", err)
+	}
+
+	// Rules set in code are reported on validation instead
+	pkg := NewCompiledPackager(NewPackagerConfig(WithPANRule(2, PANRule{DenyBINs: []string{"411111 "}})))
+	m := NewMessage(WithPackager(pkg))
+	if err := m.SetField(2, "4111111111111111"); err != nil {
+		t.Fatal(err)
+	}
+	report, ok := pkg.GetValidator().ValidateMessageMode(m, ValidationStrict, ValidationCollectAll).(*ValidationReport)
+	if !ok {
+		t.Fatal("expected a ValidationReport")
+	}
+	errs := report.FieldErrors(2)
+	found := false
+	for _, ve := range errs {
+		if ve.Rule == "pan" && errors.Is(ve.Err, pan.ErrInvalidBINPattern) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("FieldErrors(2) = %v, want an invalid BIN pattern error", errs)
+	}
+}
+
+func TestPANRuleOptIn(t *testing.T) {
+	validate := func(pkg *CompiledPackager) error {
+		m := NewMessage(WithPackager(pkg))
+		if err := m.SetField(2, "4111111111111112"); err != nil {
+			t.Fatal(err)
+		}
+		report, _ := pkg.GetValidator().ValidateMessageMode(m, ValidationStrict, ValidationCollectAll).(*ValidationReport)
+		if report == nil {
+			return nil
+		}
+		if errs := report.FieldErrors(2); len(errs) > 0 {
+			return errs[0]
+		}
+		return nil
+	}
+
+	if err := validate(NewCompiledPackager(NewPackagerConfig())); err != nil {
+		t.Errorf("default layout checked DE 2: %v", err)
+	}
+
+	fieldConfig := DefaultConfigField[2]
+	fieldConfig.Format = "pan"
+	if err := validate(NewCompiledPackager(newTestConfig(WithFieldConfig(2, fieldConfig)))); err == nil {
+		t.Error("Format \"pan\" did not check DE 2")
+	}
+
+	if err := validate(NewCompiledPackager(newTestConfig(WithPANRule(2, PANRule{})))); err == nil {
+		t.Error("PANRules did not check DE 2")
+	}
+}
+
+// newReportMessage returns an 0200 missing DE 7 and DE 49, with an overlong DE 11,
+// and a message rule over DE 4 and DE 49 that always fails.
+func newReportMessage(t *testing.T, opts ...MessageOption) *Message {
+	t.Helper()
+	pkg := NewCompiledPackager(newTestConfig())
+	pkg.GetValidator().AddMessageRule(&CustomMessageRule{
+		RuleName:     "currency_required",
+		RuleFields:   []int{4, 49},
+		ValidateFunc: func(*Message) error { return errors.New("amount without currency") },
+	})
+	m := newTestMessage(t, pkg, "0200", map[int]string{
+		3: "000000", 4: "000000001000", 11: "1234567", 12: "123000", 13: "1016", 22: "051", 25: "00",
+	})
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func TestValidateCollectAll(t *testing.T) {
+	err := newReportMessage(t, WithStrictValidation(), WithCollectAllErrors()).Validate()
+
+	var report *ValidationReport
+	if !errors.As(err, &report) {
+		t.Fatalf("Validate = %v, want a *ValidationReport", err)
+	}
+	if len(report.Errors) != 4 {
+		t.Fatalf("report has %d errors, want 4: %v", len(report.Errors), err)
+	}
+	if got, want := report.Fields(), []int{4, 7, 11, 49}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields = %v, want %v", got, want)
+	}
+	if got := report.FieldErrors(49); len(got) != 2 || got[0].Rule != "mandatory" || got[1].Rule != "currency_required" {
+		t.Errorf("FieldErrors(49) = %v, want mandatory and currency_required", got)
+	}
+	if got := report.FieldErrors(11); len(got) != 1 || got[0].Rule != "length" {
+		t.Errorf("FieldErrors(11) = %v, want length", got)
+	}
+	if got := report.FieldErrors(3); got != nil {
+		t.Errorf("FieldErrors(3) = %v, want none", got)
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, "4 validation errors; ") || !strings.Contains(msg, "fields 4, 49 (currency_required)") {
+		t.Errorf("Error = %q", msg)
+	}
+
+	if !errors.Is(err, ErrValidationFailed) {
+		t.Error("report does not match ErrValidationFailed")
+	}
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Field != 7 {
+		t.Errorf("errors.As = %v, want the DE 7 failure", ve)
+	}
+}
+
+func TestValidateFailFast(t *testing.T) {
+	err := newReportMessage(t, WithStrictValidation()).Validate()
+
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Field != 7 || ve.Rule != "mandatory" {
+		t.Fatalf("Validate = %v, want the DE 7 mandatory failure", err)
+	}
+	var report *ValidationReport
+	if errors.As(err, &report) {
+		t.Error("fail-fast Validate returned a report")
+	}
+}
+
+func TestValidationReportSingleError(t *testing.T) {
+	ve := &ValidationError{Field: 39, Rule: "mandatory", Message: "mandatory field missing"}
+	report := &ValidationReport{Errors: []*ValidationError{ve}}
+	if report.Error() != ve.Error() {
+		t.Errorf("Error = %q, want %q", report.Error(), ve.Error())
+	}
+}
+
+// newDecodePackager configures DE 52 and DE 53 as hex-encoded binary fields,
+// so that a non-hex digit on the wire is a decode error with a known field
+// boundary.
+func newDecodePackager() *CompiledPackager {
+	hexField := FieldConfig{Type: FieldTypeB, Length: LengthFixed, MaxLength: 8, Encoding: EncodingHex}
+	return NewCompiledPackager(newTestConfig(WithFieldConfig(52, hexField), WithFieldConfig(53, hexField)))
+}
+
+// undecodableMessage returns a packed 0200 whose DE 52 and DE 53 carry a
+// non-hex digit.
+func undecodableMessage(t *testing.T, pkg *CompiledPackager) []byte {
+	t.Helper()
+	m := newTestMessage(t, pkg, "0200", map[int]string{11: "000123", 41: "TERM0001"})
+	if err := m.SetField(52, bytes.Repeat([]byte{0x99}, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetField(53, bytes.Repeat([]byte{0x77}, 8)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 512)
+	n, err := m.Pack(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buf[:n]
+	for _, wire := range []string{"9999999999999999", "7777777777777777"} {
+		i := bytes.Index(data, []byte(wire))
+		if i < 0 {
+			t.Fatalf("packed message does not contain %s", wire)
+		}
+		data[i] = 'G'
+	}
+	return data
+}
+
+func TestUnpackCollectAll(t *testing.T) {
+	pkg := newDecodePackager()
+	data := undecodableMessage(t, pkg)
+
+	m := NewMessage(WithPackager(pkg), WithStrictValidation(), WithCollectAllErrors())
+	err := m.Unpack(data)
+	var report *ValidationReport
+	if !errors.As(err, &report) {
+		t.Fatalf("Unpack = %v, want a *ValidationReport", err)
+	}
+	if got, want := report.Fields(), []int{52, 53}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields = %v, want %v", got, want)
+	}
+	for _, ve := range report.Errors {
+		if ve.Rule != "decode" || !errors.Is(ve, ErrInvalidEncoding) {
+			t.Errorf("DE %d: %v, want a decode ErrInvalidEncoding", ve.Field, ve)
+		}
+	}
+	if !errors.Is(err, ErrInvalidEncoding) {
+		t.Error("report does not match the decode error")
+	}
+	if got, _ := m.GetString(41); got != "TERM0001" {
+		t.Errorf("DE 41 = %q, want the fields around the failures decoded", got)
+	}
+
+	m = NewMessage(WithPackager(pkg), WithStrictValidation())
+	var fe *FieldError
+	if err := m.Unpack(data); !errors.As(err, &fe) || fe.Field != 52 || !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("fail-fast Unpack = %v, want the DE 52 decode error", err)
+	}
+}
+
+// newLevelMessage returns an 0200 with one failure for every group of
+// checks: DE 49 is missing, DE 11 is too long, DE 3 is not numeric, DE 13
+// is not a date, DE 14 is required by a cross-field rule and DE 41 fails
+// a rule registered with WithCustomValidation.
+func newLevelMessage(t *testing.T, opts ...MessageOption) *Message {
+	t.Helper()
+	pkg := NewCompiledPackager(newTestConfig(WithCrossFieldRule(CrossFieldRuleConfig{
+		Name:    "chip_expiry",
+		When:    `DE22[0:2] == "05"`,
+		Require: "present(DE14)",
+	})))
+	m := NewMessage(append([]MessageOption{WithPackager(pkg), WithCollectAllErrors()}, opts...)...)
+	if err := m.SetMTI([]byte("0200")); err != nil {
+		t.Fatal(err)
+	}
+	fields := map[int]string{
+		3: "00A000", 4: "000000001000", 7: "1016120000", 11: "1234567",
+		12: "120000", 13: "1399", 22: "051", 25: "00", 41: "TERM0001",
+	}
+	for fieldNum, value := range fields {
+		if err := m.SetField(fieldNum, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+// failedRules returns the failing "field:rule" pairs of a validation error.
+func failedRules(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var report *ValidationReport
+	if !errors.As(err, &report) {
+		t.Fatalf("Validate = %v, want a *ValidationReport", err)
+	}
+	rules := make([]string, len(report.Errors))
+	for i, ve := range report.Errors {
+		rules[i] = fmt.Sprintf("%d:%s", ve.Field, ve.Rule)
+	}
+	return rules
+}
+
+func TestValidationLevels(t *testing.T) {
+	terminalRule := &CustomRule{
+		RuleName: "terminal",
+		ValidateFunc: func(f *Field) error {
+			if f.String() == "TERM0001" {
+				return errors.New("terminal blocked")
+			}
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name string
+		opt  MessageOption
+		want []string
+	}{
+		{"none", WithValidationLevel(ValidationNone), nil},
+		{"basic", WithBasicValidation(), []string{"11:length", "49:mandatory"}},
+		{"strict", WithStrictValidation(), []string{"3:numeric", "11:length", "13:format", "49:mandatory", "14:chip_expiry"}},
+		{"custom", WithCustomValidation(terminalRule), []string{"41:terminal"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := failedRules(t, newLevelMessage(t, tt.opt).Validate())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("failures = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationSwitches(t *testing.T) {
+	m := newLevelMessage(t, WithStrictValidation())
+	validator := m.packager.GetValidator()
+	validator.SetCharsetValidation(false)
+	validator.SetLengthValidation(false)
+	validator.SetPresenceValidation(false)
+
+	if got, want := failedRules(t, m.Validate()), []string{"13:format", "14:chip_expiry"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("failures = %v, want %v", got, want)
+	}
+}
+
+func TestValidateFieldLevel(t *testing.T) {
+	validator := NewCompiledPackager(newTestConfig()).GetValidator()
+	field := &Field{}
+	field.SetString("00A000", FieldTypeN)
+
+	if err := validator.ValidateField(3, field); err == nil {
+		t.Error("ValidateField at ValidationStrict accepted a non-numeric DE 3")
+	}
+	validator.SetValidationLevel(ValidationBasic)
+	if err := validator.ValidateField(3, field); err != nil {
+		t.Errorf("ValidateField at ValidationBasic = %v, want charset not checked", err)
+	}
+}
+
+func TestUnpackValidationNone(t *testing.T) {
+	pkg := newDecodePackager()
+	m := NewMessage(WithPackager(pkg))
+	if err := m.Unpack(undecodableMessage(t, pkg)); err != nil {
+		t.Fatalf("Unpack = %v, want undecodable fields skipped", err)
+	}
+	if m.HasField(52) || m.HasField(53) {
+		t.Error("undecodable fields are present")
+	}
+	if got, _ := m.GetString(41); got != "TERM0001" {
+		t.Errorf("DE 41 = %q", got)
+	}
+}
@@ -0,0 +1,191 @@
+package iso8583
+
+import "fmt"
+
+// Version identifies the edition of ISO 8583 a message follows.
+// It matches the first digit of the MTI (0 = 1987, 1 = 1993, 2 = 2003).
+type Version int
+
+const (
+	Version1987 Version = 1987
+	Version1993 Version = 1993
+	Version2003 Version = 2003
+)
+
+// String returns the edition year, e.g. "1993".
+func (v Version) String() string {
+	return fmt.Sprintf("%d", int(v))
+}
+
+// DetectVersion returns the version encoded in the first MTI digit.
+// It returns false for private-use or malformed MTIs.
+func DetectVersion(mti []byte) (Version, bool) {
+	if len(mti) < 1 {
+		return 0, false
+	}
+	switch mti[0] {
+	case '0':
+		return Version1987, true
+	case '1':
+		return Version1993, true
+	case '2':
+		return Version2003, true
+	default:
+		return 0, false
+	}
+}
+
+// DefaultConfigField1993 holds the field layout of ISO 8583:1993.
+// Fields not listed in the overrides keep their 1987 definition.
+var DefaultConfigField1993 = overrideFields(DefaultConfigField, map[int]FieldConfig{
+	12: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 12, Mandatory: true, Format: "YYMMDDhhmmss"},
+	13: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false, Format: "YYMM"}, // Date, effective
+	22: {Type: FieldTypeAN, Length: LengthFixed, MaxLength: 12, Mandatory: true},
+	24: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 3, Mandatory: false}, // Function code
+	25: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false}, // Message reason code
+	26: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false}, // Card acceptor business code
+	27: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 1, Mandatory: false},
+	28: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 6, Mandatory: false, Format: "YYMMDD"}, // Date, reconciliation
+	29: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 3, Mandatory: false},
+	30: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 24, Mandatory: false}, // Amounts, original
+	31: {Type: FieldTypeANS, Length: LengthLLVAR, MaxLength: 99, Mandatory: false},
+	36: {Type: FieldTypeZ, Length: LengthLLLVAR, MaxLength: 104, Mandatory: false},
+	39: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 3, Mandatory: false}, // Action code
+	40: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 3, Mandatory: false},
+	43: {Type: FieldTypeANS, Length: LengthLLVAR, MaxLength: 99, Mandatory: false},
+	44: {Type: FieldTypeANS, Length: LengthLLVAR, MaxLength: 99, Mandatory: false},
+	46: {Type: FieldTypeANS, Length: LengthLLLVAR, MaxLength: 204, Mandatory: false},
+	49: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 3, Mandatory: true},
+	50: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 3, Mandatory: false},
+	51: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 3, Mandatory: false},
+	53: {Type: FieldTypeB, Length: LengthLLVAR, MaxLength: 48, Mandatory: false},
+	55: {Type: FieldTypeB, Length: LengthLLLVAR, MaxLength: 255, Mandatory: false},
+	56: {Type: FieldTypeN, Length: LengthLLVAR, MaxLength: 35, Mandatory: false}, // Original data elements
+	57: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 3, Mandatory: false},
+	58: {Type: FieldTypeN, Length: LengthLLVAR, MaxLength: 11, Mandatory: false},
+	66: {Type: FieldTypeANS, Length: LengthLLLVAR, MaxLength: 204, Mandatory: false},
+	71: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 8, Mandatory: false},
+	72: {Type: FieldTypeANS, Length: LengthLLLVAR, MaxLength: 999, Mandatory: false},
+	73: {Type: FieldTypeN, Length: LengthFixed, MaxLength: 6, Mandatory: false},
+})
+
+// DefaultConfigField2003 holds the field layout of ISO 8583:2003.
+// It builds on the 1993 layout with a century in DE 12, a 4-digit action
+// code and LLLLVAR private/national use fields.
+var DefaultConfigField2003 = overrideFields(DefaultConfigField1993, map[int]FieldConfig{
+	12:  {Type: FieldTypeN, Length: LengthFixed, MaxLength: 14, Mandatory: true, Format: "CCYYMMDDhhmmss"},
+	39:  {Type: FieldTypeN, Length: LengthFixed, MaxLength: 4, Mandatory: false},
+	46:  {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	47:  {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	48:  {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	55:  {Type: FieldTypeB, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	59:  {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	60:  {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	61:  {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	62:  {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	63:  {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	72:  {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	111: {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	123: {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	124: {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	125: {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	126: {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+	127: {Type: FieldTypeANS, Length: LengthLLLLVAR, MaxLength: 9999, Mandatory: false},
+})
+
+// overrideFields returns a copy of base with the given fields replaced.
+func overrideFields(base, overrides map[int]FieldConfig) map[int]FieldConfig {
+	fields := make(map[int]FieldConfig, len(base)+len(overrides))
+	for fieldNum, config := range base {
+		fields[fieldNum] = config
+	}
+	for fieldNum, config := range overrides {
+		fields[fieldNum] = config
+	}
+	return fields
+}
+
+// DefaultConfigFieldForVersion returns the built-in field layout for a version.
+func DefaultConfigFieldForVersion(v Version) (map[int]FieldConfig, error) {
+	switch v {
+	case Version1987, 0:
+		return DefaultConfigField, nil
+	case Version1993:
+		return DefaultConfigField1993, nil
+	case Version2003:
+		return DefaultConfigField2003, nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, int(v))
+	}
+}
+
+// DefaultPackagerConfigForVersion returns the default packager configuration
+// with the field layout of the given version.
+func DefaultPackagerConfigForVersion(v Version) (*PackagerConfig, error) {
+	fields, err := DefaultConfigFieldForVersion(v)
+	if err != nil {
+		return nil, err
+	}
+	config := DefaultPackagerConfig()
+	config.Fields = fields
+	config.Version = v
+	return config, nil
+}
+
+// NewPackagerForVersion compiles a packager for the given version,
+// applying opts on top of the version's default configuration.
+func NewPackagerForVersion(v Version, opts ...PackagerOption) (*CompiledPackager, error) {
+	config, err := DefaultPackagerConfigForVersion(v)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return NewCompiledPackager(config), nil
+}
+
+// compileVersionProfiles builds one packager per supported version for MTI
+// version detection. Each profile shares cp's framing, header, bitmap and TLV
+// settings and uses the version's built-in fields unless overridden in
+// config.VersionFields. cp itself serves its own version.
+func (cp *CompiledPackager) compileVersionProfiles(config *PackagerConfig) {
+	profiles := map[Version]*CompiledPackager{cp.version: cp}
+
+	for _, v := range []Version{Version1987, Version1993, Version2003} {
+		if v == cp.version {
+			continue
+		}
+		fields, ok := config.VersionFields[v]
+		if !ok {
+			fields, _ = DefaultConfigFieldForVersion(v)
+		}
+
+		profileConfig := *config
+		profileConfig.Fields = fields
+		profileConfig.Version = v
+		profileConfig.DetectVersion = false
+		profiles[v] = NewCompiledPackager(&profileConfig)
+	}
+
+	// Share the map so a message switched to another profile can switch back
+	for _, profile := range profiles {
+		profile.versionProfiles = profiles
+	}
+}
+
+// profileFor returns the packager to use for an MTI, or cp itself if
+// version detection is disabled or the MTI's version has no profile.
+func (cp *CompiledPackager) profileFor(mti []byte) *CompiledPackager {
+	if cp.versionProfiles == nil {
+		return cp
+	}
+	v, ok := DetectVersion(mti)
+	if !ok {
+		return cp
+	}
+	if profile, exists := cp.versionProfiles[v]; exists {
+		return profile
+	}
+	return cp
+}
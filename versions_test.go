@@ -0,0 +1,108 @@
+package iso8583
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDetectVersion(t *testing.T) {
+	tests := []struct {
+		mti  string
+		want Version
+		ok   bool
+	}{
+		{"0100", Version1987, true},
+		{"1100", Version1993, true},
+		{"2100", Version2003, true},
+		{"9100", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := DetectVersion([]byte(tt.mti))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("DetectVersion(%q) = %v, %v, want %v, %v", tt.mti, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNewPackagerForVersion(t *testing.T) {
+	if _, err := NewPackagerForVersion(Version(1999)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("NewPackagerForVersion(1999) = %v, want ErrUnsupportedVersion", err)
+	}
+
+	pkg, err := NewPackagerForVersion(Version2003)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config, _ := pkg.GetFieldConfig(12); config.MaxLength != 14 {
+		t.Errorf("DE 12 max length = %d, want 14", config.MaxLength)
+	}
+}
+
+func TestVersionDetectionRoundTrip(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig(WithVersionDetection(true)))
+	tests := []struct {
+		mti  string
+		de12 string
+		de39 string
+	}{
+		{"0110", "120000", "00"},
+		{"1110", "261016120000", "000"},
+		{"2110", "20261016120000", "0000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mti, func(t *testing.T) {
+			m := NewMessage(WithPackager(pkg))
+			if err := m.SetMTI([]byte(tt.mti)); err != nil {
+				t.Fatal(err)
+			}
+			m.SetField(12, tt.de12)
+			m.SetField(39, tt.de39)
+
+			got := repack(t, m)
+			for fieldNum, want := range map[int]string{12: tt.de12, 39: tt.de39} {
+				if s, err := got.GetString(fieldNum); err != nil || s != want {
+					t.Errorf("DE %d = %q, %v, want %q", fieldNum, s, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetMTISelectsVersionProfile(t *testing.T) {
+	pkg := NewCompiledPackager(newTestConfig(WithVersionDetection(true)))
+	tests := []struct {
+		name string
+		opts []MessageOption
+	}{
+		{"SetMTI", nil},
+		{"WithMTI before WithPackager", []MessageOption{WithMTI([]byte("1100")), WithPackager(pkg)}},
+		{"WithMTI after WithPackager", []MessageOption{WithPackager(pkg), WithMTI([]byte("1100"))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMessage(append([]MessageOption{WithPackager(pkg)}, tt.opts...)...)
+			if tt.opts == nil {
+				m.SetMTI([]byte("1100"))
+			}
+			m.SetField(12, "261016120000") // n12 in 1993, n6 in 1987
+
+			report, ok := m.packager.GetValidator().ValidateMessageMode(m, ValidationBasic, ValidationCollectAll).(*ValidationReport)
+			if ok && len(report.FieldErrors(12)) > 0 {
+				t.Errorf("DE 12 validated against the 1987 layout: %v", report.FieldErrors(12))
+			}
+			if config, _ := m.fieldConfig(12); config.MaxLength != 12 {
+				t.Errorf("DE 12 max length = %d, want 12", config.MaxLength)
+			}
+
+			// Switching back to a 1987 MTI selects the base profile again
+			m.SetMTI([]byte("0100"))
+			if m.packager != pkg {
+				t.Error("1987 MTI did not select the base packager")
+			}
+		})
+	}
+}